@@ -27,8 +27,24 @@ var (
 	createBatchCmd = &cobra.Command{
 		Use:   "create",
 		Short: "create - Creates a new batch",
-		Long:  ``,
-		Run:   createBatch,
+		Long: `Creates a new batch.
+
+Pass --spec to read the batch from a YAML file instead of a long command line. The file holds named
+batches under 'batches', plus optional 'defaults' shared by all of them. Keys are the flag names of
+this command, lists may be used for comma-separated flags, and parameters may be given as a map.
+$VAR and ${VAR} are replaced by environment variables. Flags given on the command line override the
+file. Choose a batch with --spec-batch when the file defines more than one:
+
+  batches:
+    nightly:
+      build-id: ${BUILD_ID}
+      experience-tags: [nightly, regression]
+      parameter:
+        planner.max_speed: 12
+      pool-labels: [gpu]
+      metrics-set: default`,
+		PreRun: applyBatchSpecFlags,
+		Run:    createBatch,
 	}
 	getBatchCmd = &cobra.Command{
 		Use:   "get",
@@ -59,7 +75,9 @@ var (
 
 By default the exit code is derived from Batch.Status: 1 = internal error, 0 = SUCCEEDED, 2 = ERROR, 5 = CANCELLED, 6 = timed out. This historical mapping is preserved bit-for-bit.
 
-Pass --fail-on-states=BLOCKER,ERROR,WARNING to derive the exit code from Batch.ConflatedStatus instead. In that mode, BLOCKER produces exit code 7 and WARNING produces exit code 8 (in addition to the existing codes). Conflated states that are not in the filter are treated as success.`,
+Pass --fail-on-states=BLOCKER,ERROR,WARNING to derive the exit code from Batch.ConflatedStatus instead. In that mode, BLOCKER produces exit code 7 and WARNING produces exit code 8 (in addition to the existing codes). Conflated states that are not in the filter are treated as success.
+
+Pass --metric-rules to also check numeric metric rules once the batch finishes. If a rule is violated, or the rules cannot be checked because metrics or the baseline batch cannot be fetched, a batch that would otherwise exit 0 exits with code 9. Pass --allow-missing-baseline to skip the relative rules instead when no baseline batch is found.`,
 		Run: waitBatch,
 	}
 
@@ -93,7 +111,19 @@ Exit codes (in conflated-status mode):
   7 = BLOCKER (unresolved BLOCKER jobs in the fail filter)
   8 = WARNING (unresolved WARNING jobs in the fail filter)
 
-Pass --fail-on-states to override --rerun-on-states for exit-code purposes only (the rerun decisions still use --rerun-on-states).`,
+Pass --fail-on-states to override --rerun-on-states for exit-code purposes only (the rerun decisions still use --rerun-on-states).
+
+Pass --state-file to save rerun attempts as they are submitted. If supervision is interrupted, e.g. because the CI runner was preempted, running the same command again resumes from the latest rerun batch and keeps counting attempts toward --max-rerun-attempts.
+
+Pass --batch-ids or --batches-from instead of --batch-id to supervise several batches in parallel. Each batch is supervised independently with the same rerun settings, and its log lines are prefixed with the start of its ID. All the batches share the --requests-per-second limit on API requests. Once every batch has finished, a summary is printed and the exit code covers all the batches, using the multi-batch priority: 1 > 6 > 7 > 2 > 8 > 5 > 3 > 4 > 0.
+
+Rerun policy flags refine what is rerun and when:
+  --rerun-delay, --rerun-backoff  wait before each rerun, e.g. 1m, then 2m, then 4m
+  --rerun-experience-tags         only rerun tests of experiences with these tags
+  --max-rerun-fraction            skip reruns when too many tests are left to rerun, e.g. after a systemic break
+  --metrics-only-reruns           rerun only metrics when the tests errored in the metrics stage
+
+Each attempt first counts the tests in --rerun-on-states; if they are more than --rerun-max-failure-percent of the batch, nothing is rerun. The remaining tests are then narrowed to those with a --rerun-experience-tags tag, and if they are more than --max-rerun-fraction of the batch, nothing is rerun either. Without --rerun-experience-tags, --max-rerun-fraction only matters when it is lower than --rerun-max-failure-percent / 100.`,
 		Run: superviseBatch,
 	}
 )
@@ -139,6 +169,8 @@ const (
 // Exit codes used by batch wait/supervise/get and workflow runs supervise.
 // Existing codes preserved for backwards compatibility; BLOCKER (7) and WARNING (8)
 // are produced only when ConflatedStatus mode is active (a non-empty fail filter).
+// METRIC_RULES (9) is produced only when --metric-rules is set and a rule is violated or
+// the rules could not be checked.
 const (
 	exitCodeSucceeded   = 0
 	exitCodeInternalErr = 1
@@ -149,6 +181,7 @@ const (
 	exitCodeTimeout     = 6
 	exitCodeBlocker     = 7
 	exitCodeWarning     = 8
+	exitCodeMetricRules = 9
 )
 
 func init() {
@@ -166,7 +199,6 @@ func init() {
 	createBatchCmd.Flags().String(batchExperienceTagsKey, "", "List of experience tag names or list of experience tag IDs to run, comma-separated.")
 	createBatchCmd.Flags().StringSlice(batchParameterKey, []string{}, "(Optional) Parameter overrides to pass to the build. Format: <parameter-name>=<parameter-value> or <parameter-name>:<parameter-value>. The equals sign (=) is recommended, especially if parameter names contain colons. Accepts repeated parameters or comma-separated parameters e.g. 'param1=value1,param2=value2'. If multiple = signs are used, the first one will be used to determine the key, and the rest will be part of as the value.")
 	createBatchCmd.Flags().StringSlice(batchPoolLabelsKey, []string{}, "Pool labels to determine where to run this batch. Pool labels are interpreted as a logical AND. Accepts repeated labels or comma-separated labels.")
-	createBatchCmd.MarkFlagsOneRequired(batchExperienceIDsKey, batchExperiencesKey, batchExperienceTagIDsKey, batchExperienceTagNamesKey, batchExperienceTagsKey)
 	createBatchCmd.Flags().String(batchAccountKey, "", "Specify a username for a CI/CD platform account to associate with this test batch.")
 	createBatchCmd.Flags().String(batchNameKey, "", "An optional name for the batch. If not supplied, ReSim generates a pseudo-unique name e.g rejoicing-aquamarine-starfish. This name need not be unique, but uniqueness is recommended to make it easier to identify batches.")
 	createBatchCmd.Flags().Int(batchAllowableFailurePercentKey, 0, "An optional percentage (0-100) that determines the maximum percentage of tests that can have an execution error and have aggregate metrics be computed and consider the batch successfully completed. If not supplied, ReSim defaults to 0, which means that the batch will only be considered successful if all tests complete successfully.")
@@ -176,6 +208,9 @@ func init() {
 	createBatchCmd.Flags().Bool(batchSyncMetricsConfigKey, false, "If set, run metrics sync before creating the batch")
 	createBatchCmd.Flags().StringSlice(batchMetricsConfigPath, []string{".resim/metrics/config.resim.yml"}, "The path(s) to the metrics config file(s). Supports glob patterns (e.g. \"metrics/*.yml\"). Can be specified multiple times or comma-separated. Files are merged in order. Only used if sync-metrics-config is set to true")
 	createBatchCmd.Flags().String(batchMetricsTemplatesPath, ".resim/metrics/templates", "The path to the metrics templates directory. Default is .resim/metrics/templates. Only used if sync-metrics-config is set to true")
+	createBatchCmd.Flags().Bool(batchDryRunKey, false, "If set, resolve and validate the batch's inputs, print the request and the experiences it would run, and exit without creating the batch")
+	createBatchCmd.Flags().String(batchSpecKey, "", "(Optional) Path to a YAML batch spec file providing values for the other flags. Flags given on the command line take precedence.")
+	createBatchCmd.Flags().String(batchSpecBatchKey, "", "(Optional) The name of the batch to create from the spec file. Required when the file defines more than one batch.")
 	batchCmd.AddCommand(createBatchCmd)
 
 	getBatchCmd.Flags().String(batchProjectKey, "", "The name or ID of the project the batch is associated with")
@@ -187,6 +222,8 @@ func init() {
 	getBatchCmd.Flags().String(batchFailOnStatesKey, "", "(Optional) Comma-separated list of conflated states that should fail the command (WARNING, ERROR, BLOCKER). Only used when --exit-status is set; switches the exit code to be derived from Batch.ConflatedStatus filtered to these states. New exit codes: 7=BLOCKER, 8=WARNING.")
 	getBatchCmd.Flags().Bool(batchSlackOutputKey, false, "If set, output batch summary as a Slack webhook payload")
 	getBatchCmd.Flags().Bool(batchMarkdownOutputKey, false, "If set, output batch summary as markdown (suitable for GitHub Actions summary)")
+	getBatchCmd.Flags().String(batchBaselineKey, "", baselineFlagDescription)
+	getBatchCmd.Flags().String(batchJUnitKey, "", junitFlagDescription)
 	batchCmd.AddCommand(getBatchCmd)
 
 	cancelBatchCmd.Flags().String(batchProjectKey, "", "The name or ID of the project the batch is associated with")
@@ -213,6 +250,10 @@ func init() {
 	waitBatchCmd.Flags().String(batchWaitPollKey, "30s", "Interval between checking batch status, expressed in Golang duration string.")
 	waitBatchCmd.Flags().String(batchFailOnStatesKey, "", "(Optional) Comma-separated list of conflated states that should fail the command (WARNING, ERROR, BLOCKER). When set, the exit code is derived from Batch.ConflatedStatus filtered to these states (new codes: 7=BLOCKER, 8=WARNING). When unset, the legacy Batch.Status-based exit codes are used.")
 	waitBatchCmd.Flags().Bool(batchQuietKey, false, "Suppress informational log lines (conflated status summaries).")
+	waitBatchCmd.Flags().String(batchBaselineKey, "", baselineFlagDescription)
+	waitBatchCmd.Flags().String(batchJUnitKey, "", junitFlagDescription)
+	waitBatchCmd.Flags().String(batchMetricRulesKey, "", metricRulesFlagDescription)
+	waitBatchCmd.Flags().Bool(batchAllowMissingBaselineKey, false, allowMissingBaselineFlagDescription)
 	batchCmd.AddCommand(waitBatchCmd)
 
 	logsBatchCmd.Flags().String(batchProjectKey, "", "The name or ID of the project the batch is associated with")
@@ -239,8 +280,11 @@ func init() {
 	superviseBatchCmd.MarkFlagRequired(batchProjectKey)
 	superviseBatchCmd.Flags().String(batchIDKey, "", "The ID of the batch to supervise.")
 	superviseBatchCmd.Flags().String(batchNameKey, "", "The name of the batch to supervise (e.g. rejoicing-aquamarine-starfish). If the name is not unique, this supervises the most recent batch with that name.")
-	superviseBatchCmd.MarkFlagsMutuallyExclusive(batchIDKey, batchNameKey)
-	superviseBatchCmd.MarkFlagsOneRequired(batchIDKey, batchNameKey)
+	superviseBatchCmd.Flags().String(batchIDsKey, "", "A comma-separated list of IDs of batches to supervise in parallel.")
+	superviseBatchCmd.Flags().String(batchesFromKey, "", "The path to a file of IDs of batches to supervise in parallel, separated by commas or newlines. Lines starting with # are ignored.")
+	superviseBatchCmd.MarkFlagsMutuallyExclusive(batchIDKey, batchNameKey, batchIDsKey, batchesFromKey)
+	superviseBatchCmd.MarkFlagsOneRequired(batchIDKey, batchNameKey, batchIDsKey, batchesFromKey)
+	superviseBatchCmd.Flags().Float64(batchRequestsPerSecondKey, 10, "The maximum number of API requests per second shared by the batches given with --batch-ids or --batches-from. 0 removes the limit.")
 	superviseBatchCmd.Flags().Int(batchMaxRerunAttemptsKey, 0, "Maximum number of rerun attempts for failed tests")
 	superviseBatchCmd.MarkFlagRequired(batchMaxRerunAttemptsKey)
 	superviseBatchCmd.Flags().Float64(batchRerunMaxFailurePercentKey, 50, "Maximum percentage of failed jobs before stopping (1-100)")
@@ -251,6 +295,12 @@ func init() {
 	superviseBatchCmd.Flags().Bool(batchQuietKey, false, "Suppress informational log lines (conflated status summaries and per-attempt rerun breakdowns).")
 	superviseBatchCmd.Flags().String(batchWaitTimeoutKey, "1h", "Amount of time to wait for a batch to finish, expressed in Golang duration string.")
 	superviseBatchCmd.Flags().String(batchWaitPollKey, "30s", "Interval between checking batch status, expressed in Golang duration string.")
+	superviseBatchCmd.Flags().String(batchBaselineKey, "", baselineFlagDescription)
+	superviseBatchCmd.Flags().String(batchJUnitKey, "", junitFlagDescription)
+	superviseBatchCmd.Flags().String(batchMetricRulesKey, "", metricRulesFlagDescription)
+	superviseBatchCmd.Flags().Bool(batchAllowMissingBaselineKey, false, allowMissingBaselineFlagDescription)
+	superviseBatchCmd.Flags().String(batchStateFileKey, "", stateFileFlagDescription)
+	addRerunPolicyFlags(superviseBatchCmd)
 	batchCmd.AddCommand(superviseBatchCmd)
 
 	rootCmd.AddCommand(batchCmd)
@@ -396,15 +446,6 @@ func infoLogln(args ...any) {
 	log.Println(args...)
 }
 
-// logConflatedSummary prints the per-state breakdown of a batch (default on,
-// suppressed by --quiet).
-func logConflatedSummary(batch *api.Batch) {
-	if batch == nil {
-		return
-	}
-	infoLogln(formatConflatedSummary(batch))
-}
-
 // countJobsByConflatedStatus returns counts of jobs in each conflated state of interest.
 func countJobsByConflatedStatus(jobs []api.Job) (blocker, errCount, warning int) {
 	for _, job := range jobs {
@@ -424,6 +465,15 @@ func countJobsByConflatedStatus(jobs []api.Job) (blocker, errCount, warning int)
 }
 
 func getAllJobs(projectID uuid.UUID, batchID uuid.UUID) []api.Job {
+	jobs, err := fetchAllJobs(projectID, batchID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return jobs
+}
+
+// fetchAllJobs is getAllJobs for callers that must not exit on an API error.
+func fetchAllJobs(projectID uuid.UUID, batchID uuid.UUID) ([]api.Job, error) {
 	var allJobs []api.Job
 	var pageToken *string = nil
 
@@ -433,11 +483,13 @@ func getAllJobs(projectID uuid.UUID, batchID uuid.UUID) []api.Job {
 			PageToken: pageToken,
 		})
 		if err != nil {
-			log.Fatal("unable to list jobs:", err)
+			return nil, fmt.Errorf("unable to list jobs: %w", err)
 		}
-		ValidateResponse(http.StatusOK, "unable to list jobs", response.HTTPResponse, response.Body)
-		if response.JSON200.Jobs == nil {
-			log.Fatal("unable to list jobs")
+		if err := ValidateResponseSafe(http.StatusOK, "unable to list jobs", response.HTTPResponse, response.Body); err != nil {
+			return nil, err
+		}
+		if response.JSON200 == nil || response.JSON200.Jobs == nil {
+			return nil, fmt.Errorf("unable to list jobs")
 		}
 		responseJobs := *response.JSON200.Jobs
 		allJobs = append(allJobs, responseJobs...)
@@ -449,7 +501,7 @@ func getAllJobs(projectID uuid.UUID, batchID uuid.UUID) []api.Job {
 		}
 	}
 
-	return allJobs
+	return allJobs, nil
 }
 
 func filterJobsByStatus(jobs []api.Job, conflatedStatuses []api.ConflatedJobStatus) []uuid.UUID {
@@ -488,10 +540,43 @@ type SuperviseParams struct {
 	PollInterval             time.Duration
 	BatchID                  string
 	BatchName                string
+	// StateFile persists rerun attempts so supervision can resume; nil when
+	// --state-file is not set.
+	StateFile *superviseStateFile
+	// LogPrefix is prepended to informational log lines, to tell apart batches
+	// supervised in parallel.
+	LogPrefix string
+	// Limiter spaces out the API requests of batches supervised in parallel;
+	// nil does not limit them.
+	Limiter     *requestLimiter
+	RerunPolicy RerunPolicy
+}
+
+// logf logs an informational message with the params' LogPrefix unless --quiet is set.
+func (p *SuperviseParams) logf(format string, args ...any) {
+	infoLog("%s"+format, append([]any{p.LogPrefix}, args...)...)
 }
 
 func getSuperviseParams(ccmd *cobra.Command, args []string) (*SuperviseParams, error) {
 	projectID := getProjectID(Client, viper.GetString(batchProjectKey))
+	params, err := parseSuperviseFlags(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	params.StateFile, err = loadSuperviseStateFile(viper.GetString(batchStateFileKey), projectID)
+	if err != nil {
+		return nil, err
+	}
+	params.BatchID = viper.GetString(batchIDKey)     // validated in waitForBatchCompletion
+	params.BatchName = viper.GetString(batchNameKey) // validated in waitForBatchCompletion
+	return params, nil
+}
+
+// parseSuperviseFlags reads the rerun, timeout and rerun policy flags shared by
+// `batches supervise` and `test-suites run --builds`, which register them with
+// the same keys.
+func parseSuperviseFlags(projectID uuid.UUID) (*SuperviseParams, error) {
 	maxRerunAttempts := viper.GetInt(batchMaxRerunAttemptsKey)
 	rerunMaxFailurePercent := viper.GetFloat64(batchRerunMaxFailurePercentKey)
 	rerunOnStates := viper.GetString(batchRerunOnStatesKey)
@@ -509,8 +594,19 @@ func getSuperviseParams(ccmd *cobra.Command, args []string) (*SuperviseParams, e
 	conflatedStates := parseRerunStates(rerunOnStates)
 
 	// Parse timeout and poll interval
-	pollInterval, _ := time.ParseDuration(viper.GetString(batchWaitPollKey))
-	timeout, _ := time.ParseDuration(viper.GetString(batchWaitTimeoutKey))
+	pollInterval, err := time.ParseDuration(viper.GetString(batchWaitPollKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse poll interval: %v", err)
+	}
+	timeout, err := time.ParseDuration(viper.GetString(batchWaitTimeoutKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timeout: %v", err)
+	}
+
+	rerunPolicy, err := getRerunPolicy(projectID)
+	if err != nil {
+		return nil, err
+	}
 
 	return &SuperviseParams{
 		ProjectID:                projectID,
@@ -519,30 +615,36 @@ func getSuperviseParams(ccmd *cobra.Command, args []string) (*SuperviseParams, e
 		UndesiredConflatedStates: conflatedStates,
 		Timeout:                  timeout,
 		PollInterval:             pollInterval,
-		BatchID:                  viper.GetString(batchIDKey),   // validated in waitForBatchCompletion
-		BatchName:                viper.GetString(batchNameKey), // validated in waitForBatchCompletion
+		RerunPolicy:              rerunPolicy,
 	}, nil
 }
 
 func getMatchingJobIDs(batch *api.Batch, params *SuperviseParams, attempt int) []uuid.UUID {
+	return planRerun(batch, params, attempt).JobIDs
+}
+
+// planRerun decides which tests of a finished batch to rerun, if any, applying
+// the params' RerunPolicy.
+func planRerun(batch *api.Batch, params *SuperviseParams, attempt int) rerunPlan {
 	// Check if we've reached max attempts first (before any API calls)
 	if attempt >= params.MaxRerunAttempts {
-		infoLog("Reached max rerun attempts (%d); no more reruns will be triggered\n", params.MaxRerunAttempts)
-		return nil
+		params.logf("Reached max rerun attempts (%d); no more reruns will be triggered\n", params.MaxRerunAttempts)
+		return rerunPlan{}
 	}
 
 	// If batch is cancelled, do not rerun
 	if *batch.Status == api.BatchStatusCANCELLED {
-		infoLogln("Batch was cancelled; no rerun")
-		return nil
+		params.logf("Batch was cancelled; no rerun\n")
+		return rerunPlan{}
 	}
 
 	// Get all jobs and filter by status
+	params.Limiter.wait()
 	allJobs := getAllJobs(params.ProjectID, *batch.BatchID)
 	matchingJobIDs := filterJobsByStatus(allJobs, params.UndesiredConflatedStates)
 
 	blocker, errCount, warning := countJobsByConflatedStatus(allJobs)
-	infoLog(
+	params.logf(
 		"Attempt %d/%d: %d jobs in undesired states (BLOCKER: %d, ERROR: %d, WARNING: %d)\n",
 		attempt+1, params.MaxRerunAttempts, len(matchingJobIDs), blocker, errCount, warning,
 	)
@@ -552,36 +654,52 @@ func getMatchingJobIDs(batch *api.Batch, params *SuperviseParams, attempt int) [
 	failedJobs := len(matchingJobIDs)
 	if totalJobs > 0 {
 		failedPercentage := float64(failedJobs*100) / float64(totalJobs)
-		infoLog("Failed job percentage: %.1f%% (%d/%d jobs)\n", failedPercentage, failedJobs, totalJobs)
+		params.logf("Failed job percentage: %.1f%% (%d/%d jobs)\n", failedPercentage, failedJobs, totalJobs)
 		if failedPercentage > params.RerunMaxFailurePercent {
-			infoLog(
+			params.logf(
 				"Failed job percentage %.1f%% exceeds threshold %.1f%%; skipping rerun\n",
 				failedPercentage, params.RerunMaxFailurePercent,
 			)
-			return nil
+			return rerunPlan{}
 		}
 	}
 
-	return matchingJobIDs
+	return applyRerunPolicy(batch, params, allJobs, matchingJobIDs)
 }
 
 func actualSuperviseBatch(ccmd *cobra.Command, args []string) *SuperviseResult {
-
-	// Get parameters
 	params, err := getSuperviseParams(ccmd, args)
 	if err != nil {
 		return &SuperviseResult{
 			Error: err,
 		}
 	}
+	return superviseBatchLoop(params)
+}
+
+// superviseBatchLoop waits for the batch given by params.BatchID or params.BatchName
+// and reruns its failed tests until none need a rerun or the attempts run out. It is
+// the supervise loop of `batches supervise`, of workflow runs and of
+// `test-suites run --builds`, which differ only in their params.
+func superviseBatchLoop(params *SuperviseParams) *SuperviseResult {
+	// Resume from the latest rerun batch if supervision was interrupted
+	stateKey := params.BatchID
+	if stateKey == "" {
+		stateKey = params.BatchName
+	}
+	startAttempt, resumeBatchID := params.StateFile.resume(stateKey, params.BatchID)
+	if startAttempt > 0 {
+		params.BatchID = resumeBatchID
+		params.BatchName = ""
+	}
 
 	// Unified loop for initial batch and reruns
 	var batch *api.Batch
 
-	for attempt := 0; attempt <= params.MaxRerunAttempts; attempt++ {
+	for attempt := startAttempt; attempt <= params.MaxRerunAttempts; attempt++ {
 		var err error
 
-		batch, err = waitForBatchCompletion(params.ProjectID, params.BatchID, params.BatchName, params.Timeout, params.PollInterval)
+		batch, err = waitForBatchCompletionLimited(params.ProjectID, params.BatchID, params.BatchName, params.Timeout, params.PollInterval, params.Limiter)
 
 		// Check timeout
 		if err != nil {
@@ -591,30 +709,36 @@ func actualSuperviseBatch(ccmd *cobra.Command, args []string) *SuperviseResult {
 				}
 			} else {
 				return &SuperviseResult{
-					Error: fmt.Errorf("Error retrieving batch: %v", err),
+					Error: fmt.Errorf("error retrieving batch: %v", err),
 				}
 			}
 		}
 
-		infoLog("Batch completed with status: %s\n", *batch.Status)
-		logConflatedSummary(batch)
+		params.logf("Batch %s completed with status: %s\n", batch.BatchID, *batch.Status)
+		params.logf("%s\n", formatConflatedSummary(batch))
 
 		// Check if rerun is required (includes max attempts check)
-		matchingJobIDs := getMatchingJobIDs(batch, params, attempt)
-		if len(matchingJobIDs) == 0 {
+		plan := planRerun(batch, params, attempt)
+		if len(plan.JobIDs) == 0 {
 			return &SuperviseResult{
 				Batch: batch,
 			}
 		}
 
-		response, err := submitBatchRerun(params.ProjectID, *batch.BatchID, matchingJobIDs, 30*time.Second, false, false)
+		if delay := params.RerunPolicy.delayBefore(attempt); delay > 0 {
+			params.logf("Waiting %s before rerunning\n", delay)
+			time.Sleep(delay)
+		}
+		params.Limiter.wait()
+		response, err := submitBatchRerun(params.ProjectID, *batch.BatchID, plan.JobIDs, 30*time.Second, false, plan.MetricsOnly)
 		if err != nil {
 			return &SuperviseResult{
 				Error: err,
 			}
 		}
 		newBatchID := response.JSON200.BatchID
-		infoLog("Submitted rerun batch: %s\n", newBatchID.String())
+		params.logf("Submitted rerun batch: %s\n", newBatchID.String())
+		params.StateFile.recordRerun(stateKey, attempt, *batch.BatchID, *newBatchID, plan.JobIDs)
 
 		// Update batch ID for next iteration
 		params.BatchID = newBatchID.String()
@@ -622,13 +746,19 @@ func actualSuperviseBatch(ccmd *cobra.Command, args []string) *SuperviseResult {
 	}
 
 	// This should never happen, but we'll return the batch if we get here
-	log.Fatal("Control should never reach here. Returning batch.")
 	return &SuperviseResult{
 		Batch: batch,
 	}
 }
 
 func superviseBatch(ccmd *cobra.Command, args []string) {
+	if viper.IsSet(batchIDsKey) || viper.IsSet(batchesFromKey) {
+		superviseManyBatches()
+		return
+	}
+
+	baselineMode := getBaselineModeFlag()
+	rules := getMetricRulesFlag()
 
 	result := actualSuperviseBatch(ccmd, args)
 
@@ -636,13 +766,19 @@ func superviseBatch(ccmd *cobra.Command, args []string) {
 	if result.Batch != nil && result.Batch.BatchID != nil {
 		viper.Set(batchIDKey, result.Batch.BatchID.String())
 	}
+	metricRules := metricRulesPassed
+	if result.Error == nil && result.Batch != nil && result.Batch.ProjectID != nil {
+		logRegressions(findBatchRegressions(*result.Batch.ProjectID, result.Batch, baselineMode))
+		metricRules = checkMetricRules(*result.Batch.ProjectID, result.Batch, baselineMode, rules)
+	}
 
 	// Exit with appropriate code based on final status. Supervise uses ConflatedStatus mode:
 	// the fail filter defaults to --rerun-on-states (i.e. "if a state is worth rerunning, an
 	// unresolved instance of it should fail the command"). --fail-on-states overrides.
 	failFilter := supervisorFailFilter(batchFailOnStatesKey, batchRerunOnStatesKey)
 	results := []*SuperviseResult{result}
-	exitWithBatchStatus(results, exitCodeOptions{failOnStates: failFilter}, true)
+	writeJUnitReportForResults(viper.GetString(batchJUnitKey), "ReSim", results)
+	exitWithBatchStatus(results, exitCodeOptions{failOnStates: failFilter, metricRules: metricRules}, true)
 }
 
 // supervisorFailFilter returns the fail filter to use for a supervise-style command's exit
@@ -664,7 +800,7 @@ func supervisorFailFilter(failOnStatesKey, rerunOnStatesKey string) []api.Confla
 func createBatch(ccmd *cobra.Command, args []string) {
 	projectID := getProjectID(Client, viper.GetString(batchProjectKey))
 	batchGithub := viper.GetBool(batchGithubKey)
-	if !batchGithub {
+	if !batchGithub && !viper.GetBool(batchDryRunKey) {
 		fmt.Println("Creating a batch...")
 	}
 
@@ -674,20 +810,9 @@ func createBatch(ccmd *cobra.Command, args []string) {
 		log.Fatal("failed to parse build ID: ", err)
 	}
 
-	var allExperienceIDs []uuid.UUID
-	var allExperienceNames []string
-
-	// Parse --experience-ids
-	if viper.IsSet(batchExperienceIDsKey) {
-		experienceIDs := parseUUIDs(viper.GetString(batchExperienceIDsKey))
-		allExperienceIDs = append(allExperienceIDs, experienceIDs...)
-	}
-
-	// Parse --experiences into either IDs or names
-	if viper.IsSet(batchExperiencesKey) {
-		experienceIDs, experienceNames := parseUUIDsAndNames(viper.GetString(batchExperiencesKey))
-		allExperienceIDs = append(allExperienceIDs, experienceIDs...)
-		allExperienceNames = append(allExperienceNames, experienceNames...)
+	selection := parseBatchExperienceFlags()
+	if selection.empty() {
+		log.Fatalf("failed to create batch: one of --%v, --%v, --%v, --%v or --%v is required", batchExperiencesKey, batchExperienceIDsKey, batchExperienceTagsKey, batchExperienceTagIDsKey, batchExperienceTagNamesKey)
 	}
 
 	metricsBuildID := uuid.Nil
@@ -698,35 +823,6 @@ func createBatch(ccmd *cobra.Command, args []string) {
 		}
 	}
 
-	if viper.IsSet(batchExperienceTagIDsKey) && viper.IsSet(batchExperienceTagNamesKey) {
-		log.Fatalf("failed to create batch: %v and %v are mutually exclusive parameters", batchExperienceTagNamesKey, batchExperienceTagIDsKey)
-	}
-
-	var allExperienceTagIDs []uuid.UUID
-	var allExperienceTagNames []string
-
-	// Parse --experience-tag-ids
-	if viper.IsSet(batchExperienceTagIDsKey) {
-		experienceTagIDs := parseUUIDs(viper.GetString(batchExperienceTagIDsKey))
-		allExperienceTagIDs = append(allExperienceTagIDs, experienceTagIDs...)
-	}
-
-	// Parse --experience-tag-names:
-	if viper.IsSet(batchExperienceTagNamesKey) {
-		experienceTagNames := strings.Split(viper.GetString(batchExperienceTagNamesKey), ",")
-		for i := range experienceTagNames {
-			experienceTagNames[i] = strings.TrimSpace(experienceTagNames[i])
-		}
-		allExperienceTagNames = append(allExperienceTagNames, experienceTagNames...)
-	}
-
-	// Parse --experience-tags
-	if viper.IsSet(batchExperienceTagsKey) {
-		experienceTagIDs, experienceTagNames := parseUUIDsAndNames(viper.GetString(batchExperienceTagsKey))
-		allExperienceTagIDs = append(allExperienceTagIDs, experienceTagIDs...)
-		allExperienceTagNames = append(allExperienceTagNames, experienceTagNames...)
-	}
-
 	// Parse --parameter (if any provided)
 	parameters := api.BatchParameters{}
 	if viper.IsSet(batchParameterKey) {
@@ -797,20 +893,20 @@ func createBatch(ccmd *cobra.Command, args []string) {
 		body.Priority = priority
 	}
 
-	if allExperienceIDs != nil {
-		body.ExperienceIDs = &allExperienceIDs
+	if selection.ExperienceIDs != nil {
+		body.ExperienceIDs = &selection.ExperienceIDs
 	}
 
-	if allExperienceNames != nil {
-		body.ExperienceNames = &allExperienceNames
+	if selection.ExperienceNames != nil {
+		body.ExperienceNames = &selection.ExperienceNames
 	}
 
-	if allExperienceTagIDs != nil {
-		body.ExperienceTagIDs = &allExperienceTagIDs
+	if selection.ExperienceTagIDs != nil {
+		body.ExperienceTagIDs = &selection.ExperienceTagIDs
 	}
 
-	if allExperienceTagNames != nil {
-		body.ExperienceTagNames = &allExperienceTagNames
+	if selection.ExperienceTagNames != nil {
+		body.ExperienceTagNames = &selection.ExperienceTagNames
 	}
 
 	if metricsBuildID != uuid.Nil {
@@ -821,6 +917,15 @@ func createBatch(ccmd *cobra.Command, args []string) {
 		body.PoolLabels = &poolLabels
 	}
 
+	if viper.GetBool(batchDryRunKey) {
+		dryRun := dryRunBatch(projectID, body, selection)
+		if viper.GetBool(batchSyncMetricsConfigKey) {
+			dryRun.Notes = append(dryRun.Notes, "Metrics config would be synced before creating the batch; a dry run does not sync it.")
+		}
+		fmt.Print(formatBatchDryRun(dryRun))
+		return
+	}
+
 	// Sync metrics2.0 config
 	if viper.GetBool(batchSyncMetricsConfigKey) {
 		build, err := Client.GetBuildWithResponse(context.Background(), projectID, buildID)
@@ -849,16 +954,79 @@ func createBatch(ccmd *cobra.Command, args []string) {
 	if response.JSON201 == nil {
 		log.Fatal("empty response")
 	}
-	batch := *response.JSON201
+	reportCreatedBatch(*response.JSON201, batchGithub)
+}
+
+// batchExperienceSelection holds the experiences and experience tags chosen with
+// the --experience* flags of the batch creation commands. Names are resolved by
+// the API for full batches and by the CLI for light batches.
+type batchExperienceSelection struct {
+	ExperienceIDs      []uuid.UUID
+	ExperienceNames    []string
+	ExperienceTagIDs   []uuid.UUID
+	ExperienceTagNames []string
+}
+
+func (s batchExperienceSelection) empty() bool {
+	return len(s.ExperienceIDs) == 0 && len(s.ExperienceNames) == 0 && len(s.ExperienceTagIDs) == 0 && len(s.ExperienceTagNames) == 0
+}
+
+func parseBatchExperienceFlags() batchExperienceSelection {
+	var selection batchExperienceSelection
+
+	// Parse --experience-ids
+	if viper.IsSet(batchExperienceIDsKey) {
+		experienceIDs := parseUUIDs(viper.GetString(batchExperienceIDsKey))
+		selection.ExperienceIDs = append(selection.ExperienceIDs, experienceIDs...)
+	}
+
+	// Parse --experiences into either IDs or names
+	if viper.IsSet(batchExperiencesKey) {
+		experienceIDs, experienceNames := parseUUIDsAndNames(viper.GetString(batchExperiencesKey))
+		selection.ExperienceIDs = append(selection.ExperienceIDs, experienceIDs...)
+		selection.ExperienceNames = append(selection.ExperienceNames, experienceNames...)
+	}
+
+	if viper.IsSet(batchExperienceTagIDsKey) && viper.IsSet(batchExperienceTagNamesKey) {
+		log.Fatalf("failed to create batch: %v and %v are mutually exclusive parameters", batchExperienceTagNamesKey, batchExperienceTagIDsKey)
+	}
+
+	// Parse --experience-tag-ids
+	if viper.IsSet(batchExperienceTagIDsKey) {
+		experienceTagIDs := parseUUIDs(viper.GetString(batchExperienceTagIDsKey))
+		selection.ExperienceTagIDs = append(selection.ExperienceTagIDs, experienceTagIDs...)
+	}
+
+	// Parse --experience-tag-names:
+	if viper.IsSet(batchExperienceTagNamesKey) {
+		experienceTagNames := strings.Split(viper.GetString(batchExperienceTagNamesKey), ",")
+		for i := range experienceTagNames {
+			experienceTagNames[i] = strings.TrimSpace(experienceTagNames[i])
+		}
+		selection.ExperienceTagNames = append(selection.ExperienceTagNames, experienceTagNames...)
+	}
 
-	if !batchGithub {
+	// Parse --experience-tags
+	if viper.IsSet(batchExperienceTagsKey) {
+		experienceTagIDs, experienceTagNames := parseUUIDsAndNames(viper.GetString(batchExperienceTagsKey))
+		selection.ExperienceTagIDs = append(selection.ExperienceTagIDs, experienceTagIDs...)
+		selection.ExperienceTagNames = append(selection.ExperienceTagNames, experienceTagNames...)
+	}
+
+	return selection
+}
+
+// reportCreatedBatch prints a newly created batch, or just its batch_id output
+// in GitHub mode.
+func reportCreatedBatch(batch api.Batch, github bool) {
+	if !github {
 		// Report the results back to the user
 		fmt.Println("Created batch successfully!")
 	}
 	if batch.BatchID == nil {
 		log.Fatal("empty ID")
 	}
-	if !batchGithub {
+	if !github {
 		fmt.Println("Batch ID:", batch.BatchID.String())
 	} else {
 		fmt.Printf("batch_id=%s\n", batch.BatchID.String())
@@ -866,13 +1034,13 @@ func createBatch(ccmd *cobra.Command, args []string) {
 	if batch.FriendlyName == nil {
 		log.Fatal("empty name")
 	}
-	if !batchGithub {
+	if !github {
 		fmt.Println("Batch name:", *batch.FriendlyName)
 	}
 	if batch.Status == nil {
 		log.Fatal("empty status")
 	}
-	if !batchGithub {
+	if !github {
 		fmt.Println("Status:", *batch.Status)
 	}
 }
@@ -928,9 +1096,21 @@ func actualGetBatch(projectID uuid.UUID, batchIDRaw string, batchName string) *a
 
 func getBatch(ccmd *cobra.Command, args []string) {
 	projectID := getProjectID(Client, viper.GetString(batchProjectKey))
+	baselineMode := getBaselineModeFlag()
 	batch := actualGetBatch(projectID, viper.GetString(batchIDKey), viper.GetString(batchNameKey))
+	regressions := findBatchRegressions(projectID, batch, baselineMode)
+	if path := viper.GetString(batchJUnitKey); path != "" {
+		if err := writeJUnitReport(path, "ReSim", []*api.Batch{batch}); err != nil {
+			// With --exit-status, the batch status decides the exit code.
+			if !viper.GetBool(batchExitStatusKey) {
+				log.Fatal(err)
+			}
+			log.Printf("Unable to write JUnit report to %s: %v\n", path, err)
+		}
+	}
 
 	if viper.GetBool(batchExitStatusKey) {
+		logRegressions(regressions)
 		// By default, exit code is derived from Batch.Status (preserving the historical
 		// contract of `get --exit-status`). When --fail-on-states is set, switch to
 		// ConflatedStatus mode with the user-supplied filter.
@@ -942,9 +1122,17 @@ func getBatch(ccmd *cobra.Command, args []string) {
 	}
 	if viper.GetBool(batchMarkdownOutputKey) {
 		fmt.Print(batchToMarkdown(batch))
+		if regressions != nil {
+			fmt.Print(regressionsToMarkdown(regressions))
+		}
 	} else if viper.GetBool(batchSlackOutputKey) {
-		OutputJson(batchToSlackWebhookPayload(batch))
+		payload := batchToSlackWebhookPayload(batch)
+		if regressions != nil {
+			payload.Blocks.BlockSet = append(payload.Blocks.BlockSet, regressionsToSlackBlocks(regressions, "")...)
+		}
+		OutputJson(payload)
 	} else {
+		logRegressions(regressions)
 		OutputJson(batch)
 	}
 }
@@ -974,6 +1162,9 @@ type exitCodeOptions struct {
 	// includeExtended controls whether SUBMITTED (3) and RUNNING (4) exit codes are produced.
 	// Used by `get --exit-status`, which may run on a non-terminal batch.
 	includeExtended bool
+	// metricRules turns an otherwise successful result into exitCodeMetricRules when the
+	// rules were violated or could not be checked.
+	metricRules metricRulesOutcome
 }
 
 // internalError is a sentinel returned by computeExitCode when an internal error was
@@ -986,7 +1177,7 @@ const internalError = -1
 //
 // Priority (highest first):
 //
-//	internalError(-1) > timeout(6) > BLOCKER(7) > ERROR(2) > WARNING(8) > CANCELLED(5) > SUBMITTED(3) > RUNNING(4) > SUCCEEDED(0)
+//	internalError(-1) > timeout(6) > BLOCKER(7) > ERROR(2) > WARNING(8) > CANCELLED(5) > SUBMITTED(3) > RUNNING(4) > METRIC_RULES(9) > SUCCEEDED(0)
 //
 // Legacy mode (failOnStates empty): preserves the historical Batch.Status-based mapping
 // 0=SUCCEEDED, 2=ERROR, 5=CANCELLED, with extended 3=SUBMITTED, 4=RUNNING when
@@ -1117,6 +1308,8 @@ func computeExitCode(results []*SuperviseResult, opts exitCodeOptions) int {
 		return exitCodeSubmitted
 	case opts.includeExtended && hasRunning:
 		return exitCodeRunning
+	case allSucceeded && opts.metricRules != metricRulesPassed:
+		return exitCodeMetricRules
 	case allSucceeded:
 		return exitCodeSucceeded
 	}
@@ -1156,6 +1349,12 @@ func exitWithBatchStatus(results []*SuperviseResult, opts exitCodeOptions, shoul
 			} else {
 				log.Println("Batch timed out")
 			}
+		case exitCodeMetricRules:
+			if opts.metricRules == metricRulesUnevaluated {
+				log.Println("Batch completed, but metric rules could not be checked")
+			} else {
+				log.Println("Batch completed, but metric rules were violated")
+			}
 		case exitCodeSucceeded:
 			if isMultiple {
 				log.Println("All batches completed successfully")
@@ -1173,6 +1372,10 @@ func exitWithBatchStatus(results []*SuperviseResult, opts exitCodeOptions, shoul
 		}
 	}
 
+	if shouldLog {
+		logTopBatchErrors(results)
+	}
+
 	if code == internalError {
 		log.Fatal("unknown batch status")
 	}
@@ -1201,9 +1404,16 @@ func exitWithSingleBatchStatus(batch *api.Batch, err error, opts exitCodeOptions
 }
 
 func waitForBatchCompletion(projectID uuid.UUID, batchID string, batchName string, timeout time.Duration, pollInterval time.Duration) (*api.Batch, error) {
+	return waitForBatchCompletionLimited(projectID, batchID, batchName, timeout, pollInterval, nil)
+}
+
+// waitForBatchCompletionLimited is waitForBatchCompletion, with each poll waiting
+// on the limiter first.
+func waitForBatchCompletionLimited(projectID uuid.UUID, batchID string, batchName string, timeout time.Duration, pollInterval time.Duration, limiter *requestLimiter) (*api.Batch, error) {
 	startTime := time.Now()
 
 	for {
+		limiter.wait()
 		batch := actualGetBatch(projectID, batchID, batchName)
 		if batch.Status == nil {
 			return nil, fmt.Errorf("no status returned")
@@ -1233,21 +1443,42 @@ func waitForBatchCompletion(projectID uuid.UUID, batchID string, batchName strin
 }
 
 func waitBatch(ccmd *cobra.Command, args []string) {
+	actualWaitBatch(viper.GetBool(batchLiveKey))
+}
+
+// actualWaitBatch implements wait and watch; live shows per-test progress while waiting.
+func actualWaitBatch(live bool) {
 	projectID := getProjectID(Client, viper.GetString(batchProjectKey))
 	timeout, _ := time.ParseDuration(viper.GetString(batchWaitTimeoutKey))
 	pollWait, _ := time.ParseDuration(viper.GetString(batchWaitPollKey))
+	baselineMode := getBaselineModeFlag()
+	rules := getMetricRulesFlag()
 
-	batch, err := waitForBatchCompletion(projectID, viper.GetString(batchIDKey), viper.GetString(batchNameKey), timeout, pollWait)
+	var batch *api.Batch
+	var err error
+	if live {
+		watcher := newBatchWatcher(os.Stdout, isTerminal(os.Stdout))
+		batch, err = watchForBatchCompletion(projectID, viper.GetString(batchIDKey), viper.GetString(batchNameKey), timeout, pollWait, watcher)
+	} else {
+		batch, err = waitForBatchCompletion(projectID, viper.GetString(batchIDKey), viper.GetString(batchNameKey), timeout, pollWait)
+	}
 
 	// Set the batch ID for future reference
 	if batch != nil && batch.BatchID != nil {
 		viper.Set(batchIDKey, batch.BatchID.String())
 	}
+	metricRules := metricRulesPassed
+	if err == nil {
+		logRegressions(findBatchRegressions(projectID, batch, baselineMode))
+		metricRules = checkMetricRules(projectID, batch, baselineMode, rules)
+	}
+	writeJUnitReportForResults(viper.GetString(batchJUnitKey), "ReSim", []*SuperviseResult{{Batch: batch}})
 
 	// Exit code: by default driven by Batch.Status (preserves historical contract).
 	// When --fail-on-states is set, switch to ConflatedStatus mode with that filter.
 	opts := exitCodeOptions{
 		failOnStates: parseConflatedBatchStates(viper.GetString(batchFailOnStatesKey)),
+		metricRules:  metricRules,
 	}
 	exitWithSingleBatchStatus(batch, err, opts, true)
 }
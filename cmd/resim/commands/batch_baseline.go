@@ -0,0 +1,213 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+)
+
+// baselineMode selects which of the server's batch suggestions is used as the
+// reference batch for a regression check.
+type baselineMode string
+
+const (
+	baselineModeAuto   baselineMode = "auto"
+	baselineModeMain   baselineMode = "main"
+	baselineModeBranch baselineMode = "branch"
+)
+
+// baselineFlagDescription is shared by every command that takes --baseline as a mode.
+const baselineFlagDescription = "(Optional) Compare the batch against a baseline batch chosen from the server's suggestions and report tests newly failing compared with it. One of: main (last passing batch on main, else the latest one), branch (last passing batch on the batch's branch, else the latest one), auto (main, falling back to branch)."
+
+// regressionListLimit caps how many regressed tests are listed in a Slack payload,
+// which limits the size of a single text block.
+const regressionListLimit = 20
+
+// parseBaselineMode parses a --baseline value. It returns ok=false for any value
+// that is not a baseline mode, so callers that also accept batch IDs or names can
+// fall back to those.
+func parseBaselineMode(raw string) (baselineMode, bool) {
+	switch mode := baselineMode(strings.ToLower(strings.TrimSpace(raw))); mode {
+	case baselineModeAuto, baselineModeMain, baselineModeBranch:
+		return mode, true
+	}
+	return "", false
+}
+
+// getBaselineModeFlag returns the --baseline mode, or "" if the flag is unset.
+func getBaselineModeFlag() baselineMode {
+	raw := viper.GetString(batchBaselineKey)
+	if raw == "" {
+		return ""
+	}
+	mode, ok := parseBaselineMode(raw)
+	if !ok {
+		log.Fatalf("Unsupported baseline: %s. Valid baselines are: auto, main, branch", raw)
+	}
+	return mode
+}
+
+func getBatchSuggestions(projectID uuid.UUID, batchID uuid.UUID) (*api.BatchSuggestionsOutput, error) {
+	response, err := Client.GetBatchSuggestionsWithResponse(context.Background(), projectID, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if response.HTTPResponse == nil || response.HTTPResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response getting batch suggestions: %s", response.Status())
+	}
+	if response.JSON200 == nil {
+		return nil, fmt.Errorf("empty response from getBatchSuggestions")
+	}
+	return response.JSON200, nil
+}
+
+// selectBaselineBatch picks the reference batch for mode from the suggestions,
+// preferring the last passing batch over the latest one. Suggestions that are the
+// batch itself are skipped. Returns nil if nothing suitable was suggested.
+func selectBaselineBatch(suggestions *api.BatchSuggestionsOutput, batchID uuid.UUID, mode baselineMode) *api.Batch {
+	var candidates []*api.Batch
+	mainCandidates := []*api.Batch{suggestions.LastPassingOnMain, suggestions.LatestOnMain}
+	branchCandidates := []*api.Batch{suggestions.LastPassingOnBranch, suggestions.LatestOnBranch}
+	switch mode {
+	case baselineModeMain:
+		candidates = mainCandidates
+	case baselineModeBranch:
+		candidates = branchCandidates
+	case baselineModeAuto:
+		candidates = append(mainCandidates, branchCandidates...)
+	}
+	for _, candidate := range candidates {
+		if candidate == nil || candidate.BatchID == nil || *candidate.BatchID == batchID {
+			continue
+		}
+		return candidate
+	}
+	return nil
+}
+
+// resolveBaselineBatch returns the baseline batch for mode, or nil if nothing
+// suitable was suggested.
+func resolveBaselineBatch(projectID uuid.UUID, batch *api.Batch, mode baselineMode) (*api.Batch, error) {
+	suggestions, err := getBatchSuggestions(projectID, *batch.BatchID)
+	if err != nil {
+		return nil, err
+	}
+	return selectBaselineBatch(suggestions, *batch.BatchID, mode), nil
+}
+
+// batchRegressions lists the tests that pass in the baseline and fail in the batch.
+type batchRegressions struct {
+	ProjectID         uuid.UUID             `json:"projectID"`
+	BaselineBatchID   uuid.UUID             `json:"baselineBatchID"`
+	BaselineBatchName string                `json:"baselineBatchName"`
+	BatchID           uuid.UUID             `json:"batchID"`
+	Tests             []batchComparisonTest `json:"tests"`
+}
+
+// findBatchRegressions resolves the baseline for mode and compares the batch
+// against it. Returns nil if mode is empty, no baseline could be found or the
+// lookup failed; the latter two are logged, since a regression check should never
+// fail the command or change its exit code.
+func findBatchRegressions(projectID uuid.UUID, batch *api.Batch, mode baselineMode) *batchRegressions {
+	if mode == "" || batch == nil || batch.BatchID == nil {
+		return nil
+	}
+	baseline, err := resolveBaselineBatch(projectID, batch, mode)
+	if err != nil {
+		log.Printf("Unable to find a %s baseline batch for batch %s; skipping regression check: %v\n", mode, *batch.BatchID, err)
+		return nil
+	}
+	if baseline == nil {
+		log.Printf("No %s baseline batch found for batch %s; skipping regression check\n", mode, *batch.BatchID)
+		return nil
+	}
+	comparison, err := actualCompareBatches(projectID, batch, baseline)
+	if err != nil {
+		log.Printf("Unable to compare batch %s with baseline %s; skipping regression check: %v\n", *batch.BatchID, *baseline.BatchID, err)
+		return nil
+	}
+	regressions := &batchRegressions{
+		ProjectID:         projectID,
+		BaselineBatchID:   comparison.BaselineBatchID,
+		BaselineBatchName: comparison.BaselineBatchName,
+		BatchID:           comparison.BatchID,
+		Tests:             []batchComparisonTest{},
+	}
+	for _, test := range comparison.Tests {
+		if test.Classification == comparisonRegressed {
+			regressions.Tests = append(regressions.Tests, test)
+		}
+	}
+	return regressions
+}
+
+// formatRegressions renders the regression section for log output.
+func formatRegressions(regressions *batchRegressions) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s compared with baseline %s (%s)", pluralize(len(regressions.Tests), "regression"), regressions.BaselineBatchName, regressions.BaselineBatchID)
+	for _, test := range regressions.Tests {
+		fmt.Fprintf(&b, "\n  %s: %s -> %s", test.ExperienceName, jobStatusOrDash(test.BaselineStatus), jobStatusOrDash(test.Status))
+	}
+	return b.String()
+}
+
+// logRegressions prints the regression section, if any (default on, suppressed by --quiet).
+func logRegressions(regressions *batchRegressions) {
+	if regressions == nil {
+		return
+	}
+	infoLogln(formatRegressions(regressions))
+}
+
+// regressionsToMarkdown renders the regression section appended to batchToMarkdown.
+func regressionsToMarkdown(regressions *batchRegressions) string {
+	baselineUrl := buildProjectBaseURL(regressions.ProjectID).JoinPath("batches", regressions.BaselineBatchID.String()).String()
+
+	var markdown strings.Builder
+	markdown.WriteString(fmt.Sprintf("\n**%s** compared with baseline [%s](%s)", pluralize(len(regressions.Tests), "regression"), regressions.BaselineBatchName, baselineUrl))
+	if len(regressions.Tests) == 0 {
+		markdown.WriteString(".\n")
+		return markdown.String()
+	}
+	markdown.WriteString(":\n\n")
+	for _, test := range regressions.Tests {
+		markdown.WriteString(fmt.Sprintf("- [%s](%s): %s → %s\n",
+			test.ExperienceName,
+			buildJobURL(regressions.ProjectID, regressions.BatchID, *test.JobID),
+			jobStatusOrDash(test.BaselineStatus),
+			jobStatusOrDash(test.Status),
+		))
+	}
+	return markdown.String()
+}
+
+// regressionsToSlackBlocks renders the regression section appended to the blocks
+// from batchToSlackBlocks, with an optional ID suffix for uniqueness.
+func regressionsToSlackBlocks(regressions *batchRegressions, idSuffix string) []slack.Block {
+	baselineUrl := buildProjectBaseURL(regressions.ProjectID).JoinPath("batches", regressions.BaselineBatchID.String()).String()
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("*%s* compared with baseline <%s|%s>", pluralize(len(regressions.Tests), "regression"), baselineUrl, regressions.BaselineBatchName))
+	for i, test := range regressions.Tests {
+		if i == regressionListLimit {
+			text.WriteString(fmt.Sprintf("\n• …and %d more", len(regressions.Tests)-regressionListLimit))
+			break
+		}
+		text.WriteString(fmt.Sprintf("\n• <%s|%s>: %s → %s",
+			buildJobURL(regressions.ProjectID, regressions.BatchID, *test.JobID),
+			test.ExperienceName,
+			jobStatusOrDash(test.BaselineStatus),
+			jobStatusOrDash(test.Status),
+		))
+	}
+	sectionBlock := slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text.String(), false, false), nil, nil)
+	sectionBlock.BlockID = "regressions" + idSuffix
+	return []slack.Block{sectionBlock}
+}
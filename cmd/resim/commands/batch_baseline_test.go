@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	"github.com/resim-ai/api-client/auth"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *CommandsSuite) TestParseBaselineMode() {
+	for raw, want := range map[string]baselineMode{
+		"auto":    baselineModeAuto,
+		"MAIN":    baselineModeMain,
+		" branch": baselineModeBranch,
+	} {
+		mode, ok := parseBaselineMode(raw)
+		s.True(ok, raw)
+		s.Equal(want, mode)
+	}
+	_, ok := parseBaselineMode("rejoicing-aquamarine-starfish")
+	s.False(ok)
+	_, ok = parseBaselineMode(uuid.New().String())
+	s.False(ok)
+}
+
+func (s *CommandsSuite) TestSelectBaselineBatch() {
+	batchID := uuid.New()
+	suggested := func() *api.Batch { return &api.Batch{BatchID: Ptr(uuid.New())} }
+	lastPassingOnMain := suggested()
+	latestOnMain := suggested()
+	lastPassingOnBranch := suggested()
+	latestOnBranch := suggested()
+	all := &api.BatchSuggestionsOutput{
+		LastPassingOnMain:   lastPassingOnMain,
+		LatestOnMain:        latestOnMain,
+		LastPassingOnBranch: lastPassingOnBranch,
+		LatestOnBranch:      latestOnBranch,
+	}
+
+	s.Equal(lastPassingOnMain, selectBaselineBatch(all, batchID, baselineModeMain))
+	s.Equal(lastPassingOnBranch, selectBaselineBatch(all, batchID, baselineModeBranch))
+	s.Equal(lastPassingOnMain, selectBaselineBatch(all, batchID, baselineModeAuto))
+
+	// Falls back to the latest batch when nothing has passed.
+	s.Equal(latestOnMain, selectBaselineBatch(&api.BatchSuggestionsOutput{LatestOnMain: latestOnMain}, batchID, baselineModeMain))
+	// Auto falls back to the branch when there is nothing on main.
+	s.Equal(latestOnBranch, selectBaselineBatch(&api.BatchSuggestionsOutput{LatestOnBranch: latestOnBranch}, batchID, baselineModeAuto))
+	// Main never falls back to the branch.
+	s.Nil(selectBaselineBatch(&api.BatchSuggestionsOutput{LatestOnBranch: latestOnBranch}, batchID, baselineModeMain))
+	// The batch is never its own baseline.
+	self := &api.Batch{BatchID: Ptr(batchID)}
+	s.Equal(latestOnBranch, selectBaselineBatch(&api.BatchSuggestionsOutput{LastPassingOnBranch: self, LatestOnBranch: latestOnBranch}, batchID, baselineModeBranch))
+	s.Nil(selectBaselineBatch(&api.BatchSuggestionsOutput{LatestOnMain: self}, batchID, baselineModeAuto))
+}
+
+func (s *CommandsSuite) TestFindBatchRegressions() {
+	projectID := uuid.New()
+	batch := &api.Batch{BatchID: Ptr(uuid.New()), FriendlyName: Ptr("new-batch")}
+	baseline := &api.Batch{BatchID: Ptr(uuid.New()), FriendlyName: Ptr("old-batch")}
+	passed := Ptr(api.ConflatedJobStatusPASSED)
+	blocker := Ptr(api.ConflatedJobStatusBLOCKER)
+
+	s.mockClient.On("GetBatchSuggestionsWithResponse", matchContext, projectID, *batch.BatchID).Return(
+		&api.GetBatchSuggestionsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.BatchSuggestionsOutput{LastPassingOnMain: baseline},
+		}, nil)
+	s.mockClient.On("CompareBatchesWithResponse", matchContext, projectID, *baseline.BatchID, *batch.BatchID, mock.Anything).Return(
+		&api.CompareBatchesResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200: &api.CompareBatchesOutput{
+				Tests: []api.CompareBatchTest{
+					compareTest("lane-change", passed, blocker),
+					compareTest("merge", passed, passed),
+					compareTest("roundabout", blocker, passed),
+				},
+			},
+		}, nil)
+
+	regressions := findBatchRegressions(projectID, batch, baselineModeAuto)
+	s.Require().NotNil(regressions)
+	s.Equal(*baseline.BatchID, regressions.BaselineBatchID)
+	s.Equal("old-batch", regressions.BaselineBatchName)
+	s.Require().Len(regressions.Tests, 1)
+	s.Equal("lane-change", regressions.Tests[0].ExperienceName)
+
+	s.Nil(findBatchRegressions(projectID, batch, ""))
+}
+
+func (s *CommandsSuite) TestFindBatchRegressionsWithoutBaseline() {
+	projectID := uuid.New()
+	batch := &api.Batch{BatchID: Ptr(uuid.New())}
+	s.mockClient.On("GetBatchSuggestionsWithResponse", matchContext, projectID, *batch.BatchID).Return(
+		&api.GetBatchSuggestionsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.BatchSuggestionsOutput{},
+		}, nil)
+
+	s.Nil(findBatchRegressions(projectID, batch, baselineModeBranch))
+	s.mockClient.AssertNotCalled(s.T(), "CompareBatchesWithResponse", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *CommandsSuite) TestFindBatchRegressionsIgnoresAPIErrors() {
+	projectID := uuid.New()
+	batch := &api.Batch{BatchID: Ptr(uuid.New())}
+	baseline := &api.Batch{BatchID: Ptr(uuid.New())}
+	s.mockClient.On("GetBatchSuggestionsWithResponse", matchContext, projectID, *batch.BatchID).Return(
+		&api.GetBatchSuggestionsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusInternalServerError},
+		}, nil).Once()
+
+	// A failed baseline lookup skips the check instead of exiting.
+	s.Nil(findBatchRegressions(projectID, batch, baselineModeMain))
+
+	s.mockClient.On("GetBatchSuggestionsWithResponse", matchContext, projectID, *batch.BatchID).Return(
+		&api.GetBatchSuggestionsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.BatchSuggestionsOutput{LastPassingOnMain: baseline},
+		}, nil).Once()
+	s.mockClient.On("CompareBatchesWithResponse", matchContext, projectID, *baseline.BatchID, *batch.BatchID, mock.Anything).Return(
+		&api.CompareBatchesResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusBadGateway},
+		}, nil).Once()
+
+	// So does a failed comparison.
+	s.Nil(findBatchRegressions(projectID, batch, baselineModeMain))
+}
+
+func testRegressions(projectID uuid.UUID, count int) *batchRegressions {
+	regressions := &batchRegressions{
+		ProjectID:         projectID,
+		BaselineBatchID:   uuid.New(),
+		BaselineBatchName: "old-batch",
+		BatchID:           uuid.New(),
+		Tests:             []batchComparisonTest{},
+	}
+	for i := 0; i < count; i++ {
+		regressions.Tests = append(regressions.Tests, batchComparisonTest{
+			ExperienceID:   uuid.New(),
+			ExperienceName: fmt.Sprintf("exp-%d", i),
+			Classification: comparisonRegressed,
+			JobID:          Ptr(uuid.New()),
+			Status:         Ptr(api.ConflatedJobStatusBLOCKER),
+			BaselineStatus: Ptr(api.ConflatedJobStatusPASSED),
+		})
+	}
+	return regressions
+}
+
+func (s *CommandsSuite) TestFormatRegressions() {
+	regressions := testRegressions(uuid.New(), 2)
+	out := formatRegressions(regressions)
+	s.Contains(out, "2 regressions compared with baseline old-batch ("+regressions.BaselineBatchID.String()+")")
+	s.Contains(out, "\n  exp-0: PASSED -> BLOCKER")
+	s.Contains(out, "\n  exp-1: PASSED -> BLOCKER")
+}
+
+func (s *CommandsSuite) TestRegressionsToMarkdown() {
+	viper.Reset()
+	viper.Set(auth.KeyURL, "https://api.resim.ai/v1/")
+	defer viper.Reset()
+
+	projectID := uuid.New()
+	regressions := testRegressions(projectID, 1)
+	out := regressionsToMarkdown(regressions)
+	projectURL := "https://app.resim.ai/projects/" + projectID.String()
+	s.Contains(out, "**1 regression** compared with baseline [old-batch]("+projectURL+"/batches/"+regressions.BaselineBatchID.String()+"):")
+	s.Contains(out, "- [exp-0]("+projectURL+"/batches/"+regressions.BatchID.String()+"/jobs/"+regressions.Tests[0].JobID.String()+"): PASSED → BLOCKER\n")
+
+	s.Contains(regressionsToMarkdown(testRegressions(projectID, 0)), "**0 regressions** compared with baseline [old-batch]")
+}
+
+func (s *CommandsSuite) TestRegressionsToSlackBlocks() {
+	viper.Reset()
+	viper.Set(auth.KeyURL, "https://api.resim.ai/v1/")
+	defer viper.Reset()
+
+	blocks := regressionsToSlackBlocks(testRegressions(uuid.New(), regressionListLimit+3), "_1")
+	s.Require().Len(blocks, 1)
+	raw, err := json.Marshal(blocks[0])
+	s.Require().NoError(err)
+	out := string(raw)
+	s.Contains(out, `"block_id":"regressions_1"`)
+	s.Contains(out, "*23 regressions* compared with baseline")
+	s.Contains(out, "|exp-19\\u003e: PASSED → BLOCKER")
+	s.NotContains(out, "|exp-20")
+	s.Contains(out, "…and 3 more")
+}
@@ -0,0 +1,374 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var compareBatchCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "compare - Compares the tests in a batch against a baseline batch",
+	Long: `compare - Compares every test in a batch against the same experience in a baseline batch.
+
+Each experience is classified as:
+  REGRESSED  passed in the baseline, fails in the batch
+  FIXED      failed in the baseline, passes in the batch
+  NEW        only present in the batch
+  MISSING    only present in the baseline
+  UNCHANGED  anything else (same outcome in both, or still running)
+
+A test passes when its conflated status is PASSED and fails when it is
+BLOCKER, WARNING or ERROR. --batch and --baseline accept a batch ID or name;
+--baseline also accepts auto, main or branch to pick the baseline from the
+server's batch suggestions.`,
+	Run: compareBatches,
+}
+
+const (
+	batchCompareBatchKey = "batch"
+	batchBaselineKey     = "baseline"
+)
+
+// batchOutputMarkdown renders the comparison as markdown (suitable for GitHub Actions summary).
+const batchOutputMarkdown = "markdown"
+
+func init() {
+	compareBatchCmd.Flags().String(batchProjectKey, "", "The name or ID of the project the batches are associated with")
+	compareBatchCmd.MarkFlagRequired(batchProjectKey)
+	compareBatchCmd.Flags().String(batchCompareBatchKey, "", "The ID or name of the batch to compare.")
+	compareBatchCmd.MarkFlagRequired(batchCompareBatchKey)
+	compareBatchCmd.Flags().String(batchBaselineKey, "", "The ID or name of the baseline batch to compare against, or one of auto, main or branch to use the server's suggested baseline.")
+	compareBatchCmd.MarkFlagRequired(batchBaselineKey)
+	compareBatchCmd.Flags().String(batchOutputKey, batchOutputTable, "Output format: table, json or markdown (suitable for GitHub Actions summary)")
+	batchCmd.AddCommand(compareBatchCmd)
+}
+
+// comparisonClass is the outcome of comparing one experience across two batches.
+type comparisonClass string
+
+const (
+	comparisonRegressed comparisonClass = "REGRESSED"
+	comparisonFixed     comparisonClass = "FIXED"
+	comparisonNew       comparisonClass = "NEW"
+	comparisonMissing   comparisonClass = "MISSING"
+	comparisonUnchanged comparisonClass = "UNCHANGED"
+)
+
+// comparisonClassOrder is the order classes are reported in: the ones a reviewer
+// has to act on first.
+var comparisonClassOrder = []comparisonClass{
+	comparisonRegressed,
+	comparisonFixed,
+	comparisonNew,
+	comparisonMissing,
+	comparisonUnchanged,
+}
+
+// comparisonClassHeadings are the markdown section headings for each class.
+var comparisonClassHeadings = map[comparisonClass]string{
+	comparisonRegressed: "Regressed",
+	comparisonFixed:     "Fixed",
+	comparisonNew:       "New",
+	comparisonMissing:   "Missing",
+	comparisonUnchanged: "Unchanged",
+}
+
+// batchComparisonTest is one experience's outcome in the batch and in the baseline.
+// The job fields are nil when the experience did not run in that batch.
+type batchComparisonTest struct {
+	ExperienceID   uuid.UUID               `json:"experienceID"`
+	ExperienceName string                  `json:"experienceName"`
+	Classification comparisonClass         `json:"classification"`
+	JobID          *uuid.UUID              `json:"jobID,omitempty"`
+	Status         *api.ConflatedJobStatus `json:"status,omitempty"`
+	BaselineJobID  *uuid.UUID              `json:"baselineJobID,omitempty"`
+	BaselineStatus *api.ConflatedJobStatus `json:"baselineStatus,omitempty"`
+}
+
+// batchComparison is the classified result of comparing a batch against a baseline.
+type batchComparison struct {
+	ProjectID         uuid.UUID               `json:"projectID"`
+	BatchID           uuid.UUID               `json:"batchID"`
+	BatchName         string                  `json:"batchName"`
+	BaselineBatchID   uuid.UUID               `json:"baselineBatchID"`
+	BaselineBatchName string                  `json:"baselineBatchName"`
+	Counts            map[comparisonClass]int `json:"counts"`
+	Tests             []batchComparisonTest   `json:"tests"`
+}
+
+// getBatchByIDOrName resolves a batch from a value that may be either a batch ID or
+// a batch name.
+func getBatchByIDOrName(projectID uuid.UUID, batchKeyRaw string) *api.Batch {
+	if _, err := uuid.Parse(batchKeyRaw); err == nil {
+		return actualGetBatch(projectID, batchKeyRaw, "")
+	}
+	return actualGetBatch(projectID, "", batchKeyRaw)
+}
+
+// listBatchComparisonTests pages through the CompareBatches endpoint. The baseline
+// is passed as the "from" batch, so FromTest is the baseline's job and ToTest is
+// the batch's job.
+func listBatchComparisonTests(projectID uuid.UUID, batchID uuid.UUID, baselineBatchID uuid.UUID) ([]api.CompareBatchTest, error) {
+	var tests []api.CompareBatchTest
+	params := &api.CompareBatchesParams{PageSize: Ptr(100)}
+	for {
+		response, err := Client.CompareBatchesWithResponse(context.Background(), projectID, baselineBatchID, batchID, params)
+		if err != nil {
+			return nil, err
+		}
+		if response.HTTPResponse == nil || response.HTTPResponse.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected response comparing batches: %s", response.Status())
+		}
+		if response.JSON200 == nil {
+			return nil, fmt.Errorf("empty response from compareBatches")
+		}
+		tests = append(tests, response.JSON200.Tests...)
+		if response.JSON200.NextPageToken == "" {
+			break
+		}
+		params.PageToken = Ptr(response.JSON200.NextPageToken)
+	}
+	return tests, nil
+}
+
+// isPassingJobStatus and isFailingJobStatus split the terminal conflated statuses
+// into outcomes. QUEUED, RUNNING and CANCELLED are neither.
+func isPassingJobStatus(status api.ConflatedJobStatus) bool {
+	return status == api.ConflatedJobStatusPASSED
+}
+
+func isFailingJobStatus(status api.ConflatedJobStatus) bool {
+	switch status {
+	case api.ConflatedJobStatusBLOCKER, api.ConflatedJobStatusWARNING, api.ConflatedJobStatusERROR:
+		return true
+	}
+	return false
+}
+
+// classifyComparisonTest classifies one CompareBatches entry, where FromTest is the
+// baseline's job and ToTest is the batch's job.
+func classifyComparisonTest(test api.CompareBatchTest) comparisonClass {
+	switch {
+	case test.FromTest == nil && test.ToTest == nil:
+		return comparisonUnchanged
+	case test.FromTest == nil:
+		return comparisonNew
+	case test.ToTest == nil:
+		return comparisonMissing
+	case isPassingJobStatus(test.FromTest.Status) && isFailingJobStatus(test.ToTest.Status):
+		return comparisonRegressed
+	case isFailingJobStatus(test.FromTest.Status) && isPassingJobStatus(test.ToTest.Status):
+		return comparisonFixed
+	}
+	return comparisonUnchanged
+}
+
+// buildBatchComparison classifies every test and sorts them by class (see
+// comparisonClassOrder) and then by experience name.
+func buildBatchComparison(projectID uuid.UUID, batch *api.Batch, baseline *api.Batch, tests []api.CompareBatchTest) *batchComparison {
+	comparison := &batchComparison{
+		ProjectID:       projectID,
+		BatchID:         *batch.BatchID,
+		BaselineBatchID: *baseline.BatchID,
+		Counts:          map[comparisonClass]int{},
+		Tests:           make([]batchComparisonTest, 0, len(tests)),
+	}
+	if batch.FriendlyName != nil {
+		comparison.BatchName = *batch.FriendlyName
+	}
+	if baseline.FriendlyName != nil {
+		comparison.BaselineBatchName = *baseline.FriendlyName
+	}
+	for _, class := range comparisonClassOrder {
+		comparison.Counts[class] = 0
+	}
+
+	for _, test := range tests {
+		out := batchComparisonTest{
+			ExperienceID:   test.ExperienceID,
+			ExperienceName: test.ExperienceName,
+			Classification: classifyComparisonTest(test),
+		}
+		if test.ToTest != nil {
+			out.JobID = Ptr(test.ToTest.JobID)
+			out.Status = Ptr(test.ToTest.Status)
+		}
+		if test.FromTest != nil {
+			out.BaselineJobID = Ptr(test.FromTest.JobID)
+			out.BaselineStatus = Ptr(test.FromTest.Status)
+		}
+		comparison.Counts[out.Classification]++
+		comparison.Tests = append(comparison.Tests, out)
+	}
+
+	rank := map[comparisonClass]int{}
+	for i, class := range comparisonClassOrder {
+		rank[class] = i
+	}
+	sort.SliceStable(comparison.Tests, func(i, j int) bool {
+		a, b := comparison.Tests[i], comparison.Tests[j]
+		if a.Classification != b.Classification {
+			return rank[a.Classification] < rank[b.Classification]
+		}
+		return a.ExperienceName < b.ExperienceName
+	})
+	return comparison
+}
+
+func actualCompareBatches(projectID uuid.UUID, batch *api.Batch, baseline *api.Batch) (*batchComparison, error) {
+	tests, err := listBatchComparisonTests(projectID, *batch.BatchID, *baseline.BatchID)
+	if err != nil {
+		return nil, err
+	}
+	return buildBatchComparison(projectID, batch, baseline, tests), nil
+}
+
+// jobStatusOrDash renders an optional conflated status, using "-" when the
+// experience did not run in that batch.
+func jobStatusOrDash(status *api.ConflatedJobStatus) string {
+	if status == nil {
+		return "-"
+	}
+	return string(*status)
+}
+
+// formatComparisonCounts renders the per-class counts on one line, e.g.
+// "2 regressed, 1 fixed, 0 new, 0 missing, 40 unchanged".
+func formatComparisonCounts(counts map[comparisonClass]int) string {
+	parts := make([]string, 0, len(comparisonClassOrder))
+	for _, class := range comparisonClassOrder {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[class], strings.ToLower(string(class))))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatBatchComparison renders the comparison as a header followed by a
+// column-aligned table with one row per experience.
+func formatBatchComparison(comparison *batchComparison) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Batch:     %s (%s)\n", comparison.BatchName, comparison.BatchID)
+	fmt.Fprintf(&b, "Baseline:  %s (%s)\n", comparison.BaselineBatchName, comparison.BaselineBatchID)
+	fmt.Fprintf(&b, "Summary:   %s\n", formatComparisonCounts(comparison.Counts))
+	if len(comparison.Tests) == 0 {
+		fmt.Fprintln(&b, "No tests to compare.")
+		return b.String()
+	}
+	b.WriteByte('\n')
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "CLASSIFICATION\tEXPERIENCE\tBASELINE\tBATCH\n")
+	for _, test := range comparison.Tests {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			test.Classification,
+			test.ExperienceName,
+			jobStatusOrDash(test.BaselineStatus),
+			jobStatusOrDash(test.Status),
+		)
+	}
+	tw.Flush()
+	return b.String()
+}
+
+// buildJobURL returns the web app URL of a single test in a batch.
+func buildJobURL(projectID uuid.UUID, batchID uuid.UUID, jobID uuid.UUID) string {
+	return buildProjectBaseURL(projectID).JoinPath("batches", batchID.String(), "jobs", jobID.String()).String()
+}
+
+// batchComparisonToMarkdown renders the comparison in the style of batchToMarkdown:
+// an intro line, a breakdown list, and then the regressed, fixed, new and missing
+// experiences with links to their tests. Unchanged experiences are only counted.
+func batchComparisonToMarkdown(comparison *batchComparison) string {
+	baseUrl := buildProjectBaseURL(comparison.ProjectID)
+	batchUrl := baseUrl.JoinPath("batches", comparison.BatchID.String()).String()
+	baselineUrl := baseUrl.JoinPath("batches", comparison.BaselineBatchID.String()).String()
+
+	var markdown strings.Builder
+	markdown.WriteString(fmt.Sprintf("Comparing **[%s](%s)** against baseline [%s](%s):\n\n", comparison.BatchName, batchUrl, comparison.BaselineBatchName, baselineUrl))
+	markdown.WriteString(fmt.Sprintf("- %d total tests\n", len(comparison.Tests)))
+	// Always show Regressed and Fixed regardless of count
+	markdown.WriteString(fmt.Sprintf("- %d **Regressed**\n", comparison.Counts[comparisonRegressed]))
+	markdown.WriteString(fmt.Sprintf("- %d **Fixed**\n", comparison.Counts[comparisonFixed]))
+	if comparison.Counts[comparisonNew] > 0 {
+		markdown.WriteString(fmt.Sprintf("- %d **New**\n", comparison.Counts[comparisonNew]))
+	}
+	if comparison.Counts[comparisonMissing] > 0 {
+		markdown.WriteString(fmt.Sprintf("- %d **Missing**\n", comparison.Counts[comparisonMissing]))
+	}
+	markdown.WriteString(fmt.Sprintf("- %d Unchanged\n", comparison.Counts[comparisonUnchanged]))
+
+	for _, class := range comparisonClassOrder {
+		if class == comparisonUnchanged || comparison.Counts[class] == 0 {
+			continue
+		}
+		markdown.WriteString(fmt.Sprintf("\n#### %s\n\n", comparisonClassHeadings[class]))
+		for _, test := range comparison.Tests {
+			if test.Classification != class {
+				continue
+			}
+			markdown.WriteString(fmt.Sprintf("- %s: %s → %s\n",
+				comparisonTestMarkdownName(comparison, test),
+				jobStatusOrDash(test.BaselineStatus),
+				jobStatusOrDash(test.Status),
+			))
+		}
+	}
+	return markdown.String()
+}
+
+// comparisonTestMarkdownName links the experience name to the batch's test, or to
+// the baseline's test when the experience did not run in the batch.
+func comparisonTestMarkdownName(comparison *batchComparison, test batchComparisonTest) string {
+	switch {
+	case test.JobID != nil:
+		return fmt.Sprintf("[%s](%s)", test.ExperienceName, buildJobURL(comparison.ProjectID, comparison.BatchID, *test.JobID))
+	case test.BaselineJobID != nil:
+		return fmt.Sprintf("[%s](%s)", test.ExperienceName, buildJobURL(comparison.ProjectID, comparison.BaselineBatchID, *test.BaselineJobID))
+	}
+	return test.ExperienceName
+}
+
+func compareBatches(ccmd *cobra.Command, args []string) {
+	output := viper.GetString(batchOutputKey)
+	if output != batchOutputTable && output != batchOutputJSON && output != batchOutputMarkdown {
+		log.Fatalf("Unsupported output: %s. Valid outputs are: %s, %s, %s", output, batchOutputTable, batchOutputJSON, batchOutputMarkdown)
+	}
+	projectID := getProjectID(Client, viper.GetString(batchProjectKey))
+	batch := getBatchByIDOrName(projectID, viper.GetString(batchCompareBatchKey))
+	var baseline *api.Batch
+	if mode, ok := parseBaselineMode(viper.GetString(batchBaselineKey)); ok {
+		var err error
+		baseline, err = resolveBaselineBatch(projectID, batch, mode)
+		if err != nil {
+			log.Fatal("unable to get batch suggestions:", err)
+		}
+		if baseline == nil {
+			log.Fatalf("no %s baseline batch found for batch %s", mode, *batch.BatchID)
+		}
+	} else {
+		baseline = getBatchByIDOrName(projectID, viper.GetString(batchBaselineKey))
+	}
+
+	comparison, err := actualCompareBatches(projectID, batch, baseline)
+	if err != nil {
+		log.Fatal("unable to compare batches:", err)
+	}
+
+	switch output {
+	case batchOutputJSON:
+		OutputJson(comparison)
+	case batchOutputMarkdown:
+		fmt.Print(batchComparisonToMarkdown(comparison))
+	default:
+		fmt.Print(formatBatchComparison(comparison))
+	}
+}
@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	"github.com/resim-ai/api-client/auth"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+)
+
+func compareTest(name string, from, to *api.ConflatedJobStatus) api.CompareBatchTest {
+	test := api.CompareBatchTest{
+		ExperienceID:   uuid.New(),
+		ExperienceName: name,
+	}
+	if from != nil {
+		test.FromTest = &api.CompareBatchTestDetails{JobID: uuid.New(), Status: *from}
+	}
+	if to != nil {
+		test.ToTest = &api.CompareBatchTestDetails{JobID: uuid.New(), Status: *to}
+	}
+	return test
+}
+
+func (s *CommandsSuite) TestClassifyComparisonTest() {
+	passed := Ptr(api.ConflatedJobStatusPASSED)
+	blocker := Ptr(api.ConflatedJobStatusBLOCKER)
+	warning := Ptr(api.ConflatedJobStatusWARNING)
+	errored := Ptr(api.ConflatedJobStatusERROR)
+	running := Ptr(api.ConflatedJobStatusRUNNING)
+
+	cases := []struct {
+		name     string
+		from, to *api.ConflatedJobStatus
+		want     comparisonClass
+	}{
+		{"passed to blocker", passed, blocker, comparisonRegressed},
+		{"passed to warning", passed, warning, comparisonRegressed},
+		{"passed to error", passed, errored, comparisonRegressed},
+		{"error to passed", errored, passed, comparisonFixed},
+		{"blocker to passed", blocker, passed, comparisonFixed},
+		{"passed to passed", passed, passed, comparisonUnchanged},
+		{"blocker to error", blocker, errored, comparisonUnchanged},
+		{"passed to running", passed, running, comparisonUnchanged},
+		{"only in batch", nil, passed, comparisonNew},
+		{"only in baseline", blocker, nil, comparisonMissing},
+	}
+	for _, c := range cases {
+		s.Run(c.name, func() {
+			s.Equal(c.want, classifyComparisonTest(compareTest("exp", c.from, c.to)))
+		})
+	}
+}
+
+func (s *CommandsSuite) TestBuildBatchComparisonSortsAndCounts() {
+	projectID := uuid.New()
+	batch := &api.Batch{BatchID: Ptr(uuid.New()), FriendlyName: Ptr("new-batch")}
+	baseline := &api.Batch{BatchID: Ptr(uuid.New()), FriendlyName: Ptr("old-batch")}
+	passed := Ptr(api.ConflatedJobStatusPASSED)
+	blocker := Ptr(api.ConflatedJobStatusBLOCKER)
+
+	comparison := buildBatchComparison(projectID, batch, baseline, []api.CompareBatchTest{
+		compareTest("zeta", passed, passed),
+		compareTest("beta", passed, blocker),
+		compareTest("alpha", passed, blocker),
+		compareTest("gamma", nil, passed),
+		compareTest("delta", blocker, passed),
+	})
+
+	s.Equal(projectID, comparison.ProjectID)
+	s.Equal("new-batch", comparison.BatchName)
+	s.Equal("old-batch", comparison.BaselineBatchName)
+	s.Equal(2, comparison.Counts[comparisonRegressed])
+	s.Equal(1, comparison.Counts[comparisonFixed])
+	s.Equal(1, comparison.Counts[comparisonNew])
+	s.Equal(0, comparison.Counts[comparisonMissing])
+	s.Equal(1, comparison.Counts[comparisonUnchanged])
+
+	var names []string
+	for _, test := range comparison.Tests {
+		names = append(names, test.ExperienceName)
+	}
+	s.Equal([]string{"alpha", "beta", "delta", "gamma", "zeta"}, names)
+
+	// The batch's job and status come from ToTest, the baseline's from FromTest.
+	s.Nil(comparison.Tests[3].BaselineJobID)
+	s.NotNil(comparison.Tests[3].JobID)
+	s.Equal(api.ConflatedJobStatusBLOCKER, *comparison.Tests[0].Status)
+	s.Equal(api.ConflatedJobStatusPASSED, *comparison.Tests[0].BaselineStatus)
+}
+
+func (s *CommandsSuite) TestListBatchComparisonTestsPaginates() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	baselineID := uuid.New()
+	passed := Ptr(api.ConflatedJobStatusPASSED)
+
+	// The baseline is the "from" batch and the batch under test is the "other" batch.
+	s.mockClient.On("CompareBatchesWithResponse", matchContext, projectID, baselineID, batchID,
+		mock.MatchedBy(func(p *api.CompareBatchesParams) bool { return p.PageToken == nil })).Return(
+		&api.CompareBatchesResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200: &api.CompareBatchesOutput{
+				Tests:         []api.CompareBatchTest{compareTest("a", passed, passed)},
+				NextPageToken: "page-2",
+			},
+		}, nil).Once()
+	s.mockClient.On("CompareBatchesWithResponse", matchContext, projectID, baselineID, batchID,
+		mock.MatchedBy(func(p *api.CompareBatchesParams) bool { return p.PageToken != nil && *p.PageToken == "page-2" })).Return(
+		&api.CompareBatchesResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200: &api.CompareBatchesOutput{
+				Tests: []api.CompareBatchTest{compareTest("b", passed, passed)},
+			},
+		}, nil).Once()
+
+	tests, err := listBatchComparisonTests(projectID, batchID, baselineID)
+	s.NoError(err)
+	s.Len(tests, 2)
+	s.Equal("a", tests[0].ExperienceName)
+	s.Equal("b", tests[1].ExperienceName)
+}
+
+func (s *CommandsSuite) TestFormatBatchComparison() {
+	batch := &api.Batch{BatchID: Ptr(uuid.New()), FriendlyName: Ptr("new-batch")}
+	baseline := &api.Batch{BatchID: Ptr(uuid.New()), FriendlyName: Ptr("old-batch")}
+	comparison := buildBatchComparison(uuid.New(), batch, baseline, []api.CompareBatchTest{
+		compareTest("lane-change", Ptr(api.ConflatedJobStatusPASSED), Ptr(api.ConflatedJobStatusBLOCKER)),
+		compareTest("new-scenario", nil, Ptr(api.ConflatedJobStatusPASSED)),
+	})
+
+	out := formatBatchComparison(comparison)
+	s.Contains(out, "1 regressed, 0 fixed, 1 new, 0 missing, 0 unchanged")
+	s.Contains(out, "CLASSIFICATION")
+	s.Contains(lineWith(out, "lane-change"), "REGRESSED")
+	s.Contains(lineWith(out, "lane-change"), "PASSED")
+	s.Contains(lineWith(out, "lane-change"), "BLOCKER")
+	s.True(strings.HasPrefix(strings.TrimSpace(lineWith(out, "new-scenario")), "NEW"))
+	s.Contains(lineWith(out, "new-scenario"), "-")
+}
+
+func (s *CommandsSuite) TestFormatBatchComparisonEmpty() {
+	batch := &api.Batch{BatchID: Ptr(uuid.New()), FriendlyName: Ptr("new-batch")}
+	baseline := &api.Batch{BatchID: Ptr(uuid.New()), FriendlyName: Ptr("old-batch")}
+	out := formatBatchComparison(buildBatchComparison(uuid.New(), batch, baseline, nil))
+	s.Contains(out, "No tests to compare.")
+	s.NotContains(out, "CLASSIFICATION")
+}
+
+func (s *CommandsSuite) TestBatchComparisonToMarkdown() {
+	viper.Reset()
+	viper.Set(auth.KeyURL, "https://api.resim.ai/v1/")
+	defer viper.Reset()
+
+	projectID := uuid.New()
+	batch := &api.Batch{BatchID: Ptr(uuid.New()), FriendlyName: Ptr("new-batch")}
+	baseline := &api.Batch{BatchID: Ptr(uuid.New()), FriendlyName: Ptr("old-batch")}
+	comparison := buildBatchComparison(projectID, batch, baseline, []api.CompareBatchTest{
+		compareTest("lane-change", Ptr(api.ConflatedJobStatusPASSED), Ptr(api.ConflatedJobStatusBLOCKER)),
+		compareTest("merge", Ptr(api.ConflatedJobStatusPASSED), Ptr(api.ConflatedJobStatusPASSED)),
+	})
+
+	out := batchComparisonToMarkdown(comparison)
+	batchURL := "https://app.resim.ai/projects/" + projectID.String() + "/batches/" + batch.BatchID.String()
+	s.Contains(out, "Comparing **[new-batch]("+batchURL+")** against baseline [old-batch]")
+	s.Contains(out, "- 2 total tests\n")
+	s.Contains(out, "- 1 **Regressed**\n")
+	s.Contains(out, "- 0 **Fixed**\n")
+	s.NotContains(out, "**New**")
+	s.Contains(out, "#### Regressed")
+	s.NotContains(out, "#### Fixed")
+	s.Contains(out, "[lane-change]("+batchURL+"/jobs/"+comparison.Tests[0].JobID.String()+"): PASSED → BLOCKER")
+	// Unchanged experiences are counted but not listed.
+	s.NotContains(out, "[merge]")
+}
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/cmd/resim/commands/utils"
+)
+
+const batchDryRunKey = "dry-run"
+
+// dryRunExperience is an experience a batch would run, with the flags or tags
+// that selected it.
+type dryRunExperience struct {
+	ExperienceID uuid.UUID
+	Name         string
+	SelectedBy   []string
+}
+
+// batchDryRun is everything a dry run resolved for `batches create`.
+type batchDryRun struct {
+	Body        api.BatchInput
+	Experiences []dryRunExperience
+	// SelectedBy counts the experiences selected by each flag or tag, in the
+	// order the sources were given.
+	SelectedBy []dryRunSource
+	Notes      []string
+}
+
+type dryRunSource struct {
+	Name  string
+	Count int
+}
+
+// dryRunBatch checks the build and blueprint of a batch and expands its
+// experiences, without creating the batch.
+func dryRunBatch(projectID uuid.UUID, body api.BatchInput, selection batchExperienceSelection) *batchDryRun {
+	dryRun := &batchDryRun{Body: body}
+
+	build, err := Client.GetBuildWithResponse(context.Background(), projectID, *body.BuildID)
+	if err != nil {
+		log.Fatal("unable to retrieve build:", err)
+	}
+	ValidateResponse(http.StatusOK, "unable to retrieve build", build.HTTPResponse, build.Body)
+
+	if body.BlueprintName != nil {
+		blueprint := actualGetBlueprint(*body.BlueprintName, nil)
+		dryRun.Notes = append(dryRun.Notes, fmt.Sprintf("Blueprint %s resolves to version %d.", blueprint.Name, blueprint.Version))
+	}
+
+	dryRun.Experiences, dryRun.SelectedBy = expandDryRunExperiences(projectID, selection)
+	return dryRun
+}
+
+// expandDryRunExperiences resolves a selection to the experiences a batch would
+// run. Unlike a real batch creation, where the API resolves names and tags, it
+// fails on experience IDs, names and tags that do not exist.
+func expandDryRunExperiences(projectID uuid.UUID, selection batchExperienceSelection) ([]dryRunExperience, []dryRunSource) {
+	var experiences []dryRunExperience
+	var sources []dryRunSource
+	index := map[uuid.UUID]int{}
+	add := func(source string, experienceID uuid.UUID, name string) {
+		if i, ok := index[experienceID]; ok {
+			experiences[i].SelectedBy = append(experiences[i].SelectedBy, source)
+		} else {
+			index[experienceID] = len(experiences)
+			experiences = append(experiences, dryRunExperience{ExperienceID: experienceID, Name: name, SelectedBy: []string{source}})
+		}
+		for i := range sources {
+			if sources[i].Name == source {
+				sources[i].Count++
+				return
+			}
+		}
+		sources = append(sources, dryRunSource{Name: source, Count: 1})
+	}
+
+	for _, experienceID := range selection.ExperienceIDs {
+		response, err := Client.GetExperienceWithResponse(context.Background(), projectID, experienceID)
+		if err != nil {
+			log.Fatal("unable to retrieve experience:", err)
+		}
+		ValidateResponse(http.StatusOK, fmt.Sprintf("unable to retrieve experience %s", experienceID), response.HTTPResponse, response.Body)
+		if response.JSON200 == nil {
+			log.Fatal("empty response")
+		}
+		add("experience IDs", experienceID, response.JSON200.Name)
+	}
+	for _, name := range selection.ExperienceNames {
+		add("experience names", getExperienceID(Client, projectID, name, true, false), name)
+	}
+	for _, experienceTagID := range selection.ExperienceTagIDs {
+		for _, experience := range fetchExperiencesWithTag(projectID, experienceTagID) {
+			add("tag "+experienceTagID.String(), experience.ExperienceID, experience.Name)
+		}
+	}
+	for _, name := range selection.ExperienceTagNames {
+		experienceTagID := getExperienceTagIDForName(Client, projectID, name, true)
+		for _, experience := range fetchExperiencesWithTag(projectID, experienceTagID) {
+			add("tag "+name, experience.ExperienceID, experience.Name)
+		}
+	}
+	return experiences, sources
+}
+
+// formatBatchDryRun renders the request body a batch creation would send and
+// the experiences it would run.
+func formatBatchDryRun(dryRun *batchDryRun) string {
+	var b strings.Builder
+	b.WriteString("Dry run: no batch was created.\n\nBatch input:\n")
+	data, err := json.MarshalIndent(dryRun.Body, "", "  ")
+	if err != nil {
+		log.Fatal("unable to format batch input:", err)
+	}
+	b.Write(data)
+	b.WriteString("\n\n")
+
+	for _, note := range dryRun.Notes {
+		b.WriteString(note + "\n")
+	}
+	if len(dryRun.Notes) > 0 {
+		b.WriteString("\n")
+	}
+
+	if len(dryRun.Experiences) == 0 {
+		b.WriteString("No experiences would run.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%s would run:\n", pluralize(len(dryRun.Experiences), "experience"))
+	for _, source := range dryRun.SelectedBy {
+		fmt.Fprintf(&b, "  %s: %d\n", source.Name, source.Count)
+	}
+	b.WriteString("\n")
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EXPERIENCE\tID\tSELECTED BY")
+	for _, experience := range dryRun.Experiences {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", experience.Name, experience.ExperienceID, strings.Join(experience.SelectedBy, ", "))
+	}
+	w.Flush()
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *CommandsSuite) TestCreateBatchDryRun() {
+	viper.Reset()
+	projectID := uuid.New()
+	buildID := uuid.New()
+	tagID := uuid.New()
+	laneChange, merge, cutIn := uuid.New(), uuid.New(), uuid.New()
+
+	viper.Set(batchProjectKey, projectID.String())
+	viper.Set(batchBuildIDKey, buildID.String())
+	viper.Set(batchExperiencesKey, laneChange.String()+",merge")
+	viper.Set(batchExperienceTagNamesKey, "regression")
+	viper.Set(batchBlueprintKey, "gpu-runner")
+	viper.Set(batchSyncMetricsConfigKey, true)
+	viper.Set(batchDryRunKey, true)
+
+	s.mockClient.On("GetProjectWithResponse", matchContext, projectID).Return(&api.GetProjectResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Project{ProjectID: projectID, Name: "test-project"},
+	}, nil)
+	s.mockClient.On("GetBuildWithResponse", matchContext, projectID, buildID).Return(&api.GetBuildResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Build{BuildID: buildID},
+	}, nil).Once()
+	s.mockClient.On("GetLatestBlueprintWithResponse", matchContext, "gpu-runner").Return(&api.GetLatestBlueprintResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Blueprint{Name: "gpu-runner", Version: 3},
+	}, nil).Once()
+	s.mockClient.On("GetExperienceWithResponse", matchContext, projectID, laneChange).Return(&api.GetExperienceResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Experience{ExperienceID: laneChange, Name: "lane-change"},
+	}, nil).Once()
+	s.mockClient.On("ListExperiencesWithResponse", matchContext, projectID, mock.Anything).Return(&api.ListExperiencesResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListExperiencesOutput{Experiences: &[]api.Experience{{ExperienceID: merge, Name: "merge"}}},
+	}, nil).Once()
+	s.mockClient.On("ListExperienceTagsWithResponse", matchContext, projectID, mock.Anything).Return(&api.ListExperienceTagsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListExperienceTagsOutput{ExperienceTags: &[]api.ExperienceTag{{ExperienceTagID: tagID, Name: "regression"}}},
+	}, nil).Once()
+	s.mockClient.On("ListExperiencesWithExperienceTagWithResponse", matchContext, projectID, tagID, mock.Anything).Return(&api.ListExperiencesWithExperienceTagResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.ListExperiencesOutput{Experiences: &[]api.Experience{
+			{ExperienceID: merge, Name: "merge"},
+			{ExperienceID: cutIn, Name: "cut-in"},
+		}},
+	}, nil).Once()
+
+	output := captureStdout(s, func() { createBatch(nil, nil) })
+
+	s.mockClient.AssertNotCalled(s.T(), "CreateBatchWithResponse", mock.Anything, mock.Anything, mock.Anything)
+	s.Contains(output, "Dry run: no batch was created.")
+	s.Contains(output, `"buildID": "`+buildID.String()+`"`)
+	s.Contains(output, `"blueprintName": "gpu-runner"`)
+	s.Contains(output, "Blueprint gpu-runner resolves to version 3.")
+	s.Contains(output, "a dry run does not sync it")
+	s.Contains(output, "3 experiences would run:")
+	s.Contains(output, "  experience IDs: 1\n  experience names: 1\n  tag regression: 2\n")
+	s.Contains(lineWith(output, "merge "), "experience names, tag regression")
+	s.Contains(lineWith(output, "cut-in"), cutIn.String())
+}
+
+func (s *CommandsSuite) TestFormatBatchDryRunWithoutExperiences() {
+	output := formatBatchDryRun(&batchDryRun{Body: api.BatchInput{Priority: Ptr(10)}})
+	s.Contains(output, `"priority": 10`)
+	s.Contains(output, "No experiences would run.\n")
+}
@@ -0,0 +1,349 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var errorsBatchCmd = &cobra.Command{
+	Use:   "errors",
+	Short: "errors - Lists the execution errors of a batch, grouped by error code and by experience",
+	Long: `errors - Lists the execution errors recorded for a batch and its tests.
+
+Errors are grouped by error code and by experience, each group showing how many
+errors it holds and a representative message. Errors raised by the batch itself
+rather than one of its tests are grouped under the experience "(batch)".`,
+	Run: listBatchErrors,
+}
+
+const (
+	batchOutputKey = "output"
+	batchLimitKey  = "limit"
+)
+
+const (
+	batchOutputTable = "table"
+	batchOutputJSON  = "json"
+)
+
+// batchLevelErrorGroup is the experience group for errors that do not belong to a test.
+const batchLevelErrorGroup = "(batch)"
+
+// unknownTestErrorGroup is the experience group for test errors that do not name their test.
+const unknownTestErrorGroup = "(unknown test)"
+
+// topBatchErrorsLimit is how many error codes a failed wait or supervise prints.
+const topBatchErrorsLimit = 5
+
+// batchErrorMessageWidth caps the representative message in tables and logs.
+const batchErrorMessageWidth = 100
+
+func init() {
+	errorsBatchCmd.Flags().String(batchProjectKey, "", "The name or ID of the project the batch is associated with")
+	errorsBatchCmd.MarkFlagRequired(batchProjectKey)
+	errorsBatchCmd.Flags().String(batchIDKey, "", "The ID of the batch to list errors for.")
+	errorsBatchCmd.Flags().String(batchNameKey, "", "The name of the batch to list errors for (e.g. rejoicing-aquamarine-starfish). If the name is not unique, this lists errors for the most recent batch with that name.")
+	errorsBatchCmd.MarkFlagsMutuallyExclusive(batchIDKey, batchNameKey)
+	errorsBatchCmd.MarkFlagsOneRequired(batchIDKey, batchNameKey)
+	errorsBatchCmd.Flags().String(batchOutputKey, batchOutputTable, "Output format: table or json")
+	errorsBatchCmd.Flags().Int(batchLimitKey, 0, "(Optional) Only show the largest N groups of each kind. 0 shows every group.")
+	batchCmd.AddCommand(errorsBatchCmd)
+}
+
+// batchErrorGroup aggregates the errors that share an error code or an experience.
+type batchErrorGroup struct {
+	Key     string   `json:"key"`
+	Count   int      `json:"count"`
+	Tests   int      `json:"tests"`
+	Codes   []string `json:"codes"`
+	Message string   `json:"message"`
+}
+
+// batchErrorSummary is the grouped view of a batch's execution errors.
+type batchErrorSummary struct {
+	BatchID      uuid.UUID         `json:"batchID"`
+	TotalErrors  int               `json:"totalErrors"`
+	ByCode       []batchErrorGroup `json:"byCode"`
+	ByExperience []batchErrorGroup `json:"byExperience"`
+}
+
+// fetchBatchErrors returns the batch's execution errors. It returns an error rather
+// than exiting so the exit path of wait and supervise can treat failures as non-fatal.
+func fetchBatchErrors(projectID uuid.UUID, batchID uuid.UUID) (*api.ListBatchErrorsOutput, error) {
+	response, err := Client.ListBatchErrorsWithResponse(context.Background(), projectID, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if response.HTTPResponse == nil || response.HTTPResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response listing batch errors: %s", response.Status())
+	}
+	if response.JSON200 == nil {
+		return nil, fmt.Errorf("empty response from listBatchErrors")
+	}
+	return response.JSON200, nil
+}
+
+// batchError is an execution error along with the list it came from.
+type batchError struct {
+	api.ExecutionError
+	// job is set for errors from JobExecutionErrors, which were raised by a test
+	// rather than the batch itself.
+	job bool
+}
+
+// jobID returns the test that raised the error, or nil for batch-level errors and
+// test errors that do not name their test.
+func (e batchError) jobID() *uuid.UUID {
+	if !e.job {
+		return nil
+	}
+	return e.ParentID
+}
+
+// allBatchErrors flattens the batch-level and job-level errors into one list.
+func allBatchErrors(output *api.ListBatchErrorsOutput) []batchError {
+	var errs []batchError
+	if output.Errors != nil {
+		for _, e := range *output.Errors {
+			errs = append(errs, batchError{ExecutionError: e})
+		}
+	}
+	if output.JobExecutionErrors != nil {
+		for _, e := range *output.JobExecutionErrors {
+			errs = append(errs, batchError{ExecutionError: e, job: true})
+		}
+	}
+	return errs
+}
+
+// batchErrorCode returns the error's code, or UNKNOWN_ERROR if it has none.
+func batchErrorCode(e api.ExecutionError) string {
+	if e.ErrorCode == "" {
+		return "UNKNOWN_ERROR"
+	}
+	return e.ErrorCode
+}
+
+// batchErrorMessage returns the error text on a single line, falling back to the
+// exit code when there is no text.
+func batchErrorMessage(e api.ExecutionError) string {
+	if e.ErrorText != nil && strings.TrimSpace(*e.ErrorText) != "" {
+		return strings.Join(strings.Fields(*e.ErrorText), " ")
+	}
+	if e.ExitCode != nil {
+		return fmt.Sprintf("exited with code %d", *e.ExitCode)
+	}
+	return ""
+}
+
+// groupBatchErrors groups errors by key. The representative message is the most
+// common message in the group, ties going to the one seen first.
+func groupBatchErrors(errs []batchError, keyOf func(batchError) string) []batchErrorGroup {
+	type accumulator struct {
+		group         batchErrorGroup
+		tests         map[uuid.UUID]struct{}
+		codes         map[string]struct{}
+		messageCounts map[string]int
+		messageOrder  []string
+	}
+	byKey := map[string]*accumulator{}
+	for _, e := range errs {
+		key := keyOf(e)
+		acc, ok := byKey[key]
+		if !ok {
+			acc = &accumulator{
+				group:         batchErrorGroup{Key: key, Codes: []string{}},
+				tests:         map[uuid.UUID]struct{}{},
+				codes:         map[string]struct{}{},
+				messageCounts: map[string]int{},
+			}
+			byKey[key] = acc
+		}
+		acc.group.Count++
+		if jobID := e.jobID(); jobID != nil {
+			acc.tests[*jobID] = struct{}{}
+		}
+		if code := batchErrorCode(e.ExecutionError); !containsKey(acc.codes, code) {
+			acc.codes[code] = struct{}{}
+			acc.group.Codes = append(acc.group.Codes, code)
+		}
+		if message := batchErrorMessage(e.ExecutionError); message != "" {
+			if acc.messageCounts[message] == 0 {
+				acc.messageOrder = append(acc.messageOrder, message)
+			}
+			acc.messageCounts[message]++
+		}
+	}
+
+	groups := make([]batchErrorGroup, 0, len(byKey))
+	for _, acc := range byKey {
+		acc.group.Tests = len(acc.tests)
+		best := 0
+		for _, message := range acc.messageOrder {
+			if acc.messageCounts[message] > best {
+				best = acc.messageCounts[message]
+				acc.group.Message = message
+			}
+		}
+		groups = append(groups, acc.group)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
+
+func containsKey(set map[string]struct{}, key string) bool {
+	_, ok := set[key]
+	return ok
+}
+
+// buildBatchErrorSummary groups the errors by code and by experience. experienceNames
+// maps job IDs to experience names; jobs missing from it are shown by job ID.
+func buildBatchErrorSummary(batchID uuid.UUID, output *api.ListBatchErrorsOutput, experienceNames map[uuid.UUID]string) *batchErrorSummary {
+	errs := allBatchErrors(output)
+	return &batchErrorSummary{
+		BatchID:     batchID,
+		TotalErrors: len(errs),
+		ByCode: groupBatchErrors(errs, func(e batchError) string {
+			return batchErrorCode(e.ExecutionError)
+		}),
+		ByExperience: groupBatchErrors(errs, func(e batchError) string {
+			if !e.job {
+				return batchLevelErrorGroup
+			}
+			jobID := e.jobID()
+			if jobID == nil {
+				return unknownTestErrorGroup
+			}
+			if name, ok := experienceNames[*jobID]; ok {
+				return name
+			}
+			return jobID.String()
+		}),
+	}
+}
+
+// jobExperienceNames maps each job in the batch to its experience name.
+func jobExperienceNames(jobs []api.Job) map[uuid.UUID]string {
+	names := map[uuid.UUID]string{}
+	for _, job := range jobs {
+		if job.JobID != nil && job.ExperienceName != nil {
+			names[*job.JobID] = *job.ExperienceName
+		}
+	}
+	return names
+}
+
+// limitBatchErrorGroups returns the first limit groups, or all of them if limit <= 0.
+func limitBatchErrorGroups(groups []batchErrorGroup, limit int) []batchErrorGroup {
+	if limit > 0 && len(groups) > limit {
+		return groups[:limit]
+	}
+	return groups
+}
+
+// formatBatchErrorSummary renders the summary as two tables.
+func formatBatchErrorSummary(summary *batchErrorSummary) string {
+	var b strings.Builder
+	if summary.TotalErrors == 0 {
+		fmt.Fprintf(&b, "Batch %s has no errors.\n", summary.BatchID)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Batch %s has %s.\n\n", summary.BatchID, pluralize(summary.TotalErrors, "error"))
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ERROR CODE\tCOUNT\tTESTS\tMESSAGE")
+	for _, group := range summary.ByCode {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", group.Key, group.Count, group.Tests, truncate(group.Message, batchErrorMessageWidth))
+	}
+	w.Flush()
+
+	b.WriteString("\n")
+	w = tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EXPERIENCE\tCOUNT\tERROR CODES\tMESSAGE")
+	for _, group := range summary.ByExperience {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", group.Key, group.Count, strings.Join(group.Codes, ","), truncate(group.Message, batchErrorMessageWidth))
+	}
+	w.Flush()
+	return b.String()
+}
+
+func listBatchErrors(ccmd *cobra.Command, args []string) {
+	projectID := getProjectID(Client, viper.GetString(batchProjectKey))
+	output := viper.GetString(batchOutputKey)
+	if output != batchOutputTable && output != batchOutputJSON {
+		log.Fatalf("Unsupported output: %s. Valid outputs are: %s, %s", output, batchOutputTable, batchOutputJSON)
+	}
+	batch := actualGetBatch(projectID, viper.GetString(batchIDKey), viper.GetString(batchNameKey))
+
+	errs, err := fetchBatchErrors(projectID, *batch.BatchID)
+	if err != nil {
+		log.Fatal("unable to list batch errors:", err)
+	}
+	summary := buildBatchErrorSummary(*batch.BatchID, errs, jobExperienceNames(getAllJobs(projectID, *batch.BatchID)))
+	limit := viper.GetInt(batchLimitKey)
+	summary.ByCode = limitBatchErrorGroups(summary.ByCode, limit)
+	summary.ByExperience = limitBatchErrorGroups(summary.ByExperience, limit)
+
+	if output == batchOutputJSON {
+		OutputJson(summary)
+	} else {
+		fmt.Print(formatBatchErrorSummary(summary))
+	}
+}
+
+// formatTopBatchErrors renders the most common error codes of a failed batch for
+// the exit log of wait and supervise.
+func formatTopBatchErrors(projectID uuid.UUID, summary *batchErrorSummary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Batch %s has %s", summary.BatchID, pluralize(summary.TotalErrors, "error"))
+	top := limitBatchErrorGroups(summary.ByCode, topBatchErrorsLimit)
+	for _, group := range top {
+		fmt.Fprintf(&b, "\n  %s (%d): %s", group.Key, group.Count, truncate(group.Message, batchErrorMessageWidth))
+	}
+	if len(summary.ByCode) > len(top) {
+		fmt.Fprintf(&b, "\n  …and %s", pluralize(len(summary.ByCode)-len(top), "more error code"))
+	}
+	fmt.Fprintf(&b, "\nRun `resim batches errors --project %s --batch-id %s` for details.", projectID, summary.BatchID)
+	return b.String()
+}
+
+// logTopBatchErrors prints the top errors of every errored batch in results. Failures
+// to fetch errors are logged and otherwise ignored, so they never change the exit code.
+func logTopBatchErrors(results []*SuperviseResult) {
+	for _, res := range results {
+		if res == nil || res.Batch == nil || res.Batch.BatchID == nil || res.Batch.ProjectID == nil {
+			continue
+		}
+		batch := res.Batch
+		errored := (batch.Status != nil && *batch.Status == api.BatchStatusERROR) ||
+			(batch.ConflatedStatus != nil && *batch.ConflatedStatus == api.ConflatedBatchStatusERROR)
+		if !errored {
+			continue
+		}
+		errs, err := fetchBatchErrors(*batch.ProjectID, *batch.BatchID)
+		if err != nil {
+			log.Printf("unable to list errors for batch %s: %v\n", *batch.BatchID, err)
+			continue
+		}
+		summary := buildBatchErrorSummary(*batch.BatchID, errs, nil)
+		if summary.TotalErrors == 0 {
+			continue
+		}
+		log.Println(formatTopBatchErrors(*batch.ProjectID, summary))
+	}
+}
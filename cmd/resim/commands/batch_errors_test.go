@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+)
+
+func jobError(jobID uuid.UUID, code string, text string) api.ExecutionError {
+	return api.ExecutionError{
+		ErrorCode:  code,
+		ErrorText:  Ptr(text),
+		ParentID:   Ptr(jobID),
+		ParentType: Ptr("JOB"),
+	}
+}
+
+func (s *CommandsSuite) TestBuildBatchErrorSummary() {
+	batchID := uuid.New()
+	jobA := uuid.New()
+	jobB := uuid.New()
+	unknownJob := uuid.New()
+	output := &api.ListBatchErrorsOutput{
+		Errors: &[]api.ExecutionError{{
+			ErrorCode:  "BATCH_METRICS_FAILED",
+			ErrorText:  Ptr("metrics container\n  crashed"),
+			ParentID:   Ptr(batchID),
+			ParentType: Ptr("BATCH"),
+		}},
+		JobExecutionErrors: &[]api.ExecutionError{
+			jobError(jobA, "NONZERO_EXIT_CODE", "segfault in planner"),
+			jobError(jobB, "NONZERO_EXIT_CODE", "out of memory"),
+			jobError(jobA, "NONZERO_EXIT_CODE", "segfault in planner"),
+			jobError(jobB, "TIMEOUT", "container timed out"),
+			{ErrorCode: "", ExitCode: Ptr(137), ParentID: Ptr(unknownJob), ParentType: Ptr("JOB")},
+		},
+	}
+
+	summary := buildBatchErrorSummary(batchID, output, map[uuid.UUID]string{jobA: "lane-change", jobB: "merge"})
+	s.Equal(6, summary.TotalErrors)
+
+	s.Require().Len(summary.ByCode, 4)
+	s.Equal(batchErrorGroup{Key: "NONZERO_EXIT_CODE", Count: 3, Tests: 2, Codes: []string{"NONZERO_EXIT_CODE"}, Message: "segfault in planner"}, summary.ByCode[0])
+	// Ties are broken by key.
+	s.Equal("BATCH_METRICS_FAILED", summary.ByCode[1].Key)
+	s.Equal(0, summary.ByCode[1].Tests)
+	s.Equal("metrics container crashed", summary.ByCode[1].Message)
+	s.Equal("TIMEOUT", summary.ByCode[2].Key)
+	s.Equal("UNKNOWN_ERROR", summary.ByCode[3].Key)
+	s.Equal("exited with code 137", summary.ByCode[3].Message)
+
+	s.Require().Len(summary.ByExperience, 4)
+	s.Equal("lane-change", summary.ByExperience[0].Key)
+	s.Equal(2, summary.ByExperience[0].Count)
+	s.Equal("merge", summary.ByExperience[1].Key)
+	s.Equal([]string{"NONZERO_EXIT_CODE", "TIMEOUT"}, summary.ByExperience[1].Codes)
+	// The first message wins when every message appears once.
+	s.Equal("out of memory", summary.ByExperience[1].Message)
+	s.Equal(batchLevelErrorGroup, summary.ByExperience[2].Key)
+	s.Equal(unknownJob.String(), summary.ByExperience[3].Key)
+}
+
+func (s *CommandsSuite) TestBuildBatchErrorSummaryClassifiesBySource() {
+	batchID := uuid.New()
+	jobID := uuid.New()
+	summary := buildBatchErrorSummary(batchID, &api.ListBatchErrorsOutput{
+		// Batch-level errors may name a parent without saying what kind it is.
+		Errors: &[]api.ExecutionError{
+			{ErrorCode: "BATCH_METRICS_FAILED", ParentID: Ptr(batchID)},
+		},
+		JobExecutionErrors: &[]api.ExecutionError{
+			{ErrorCode: "NONZERO_EXIT_CODE", ParentID: Ptr(jobID)},
+			{ErrorCode: "TIMEOUT"},
+		},
+	}, map[uuid.UUID]string{jobID: "lane-change"})
+
+	keys := []string{}
+	for _, group := range summary.ByExperience {
+		keys = append(keys, group.Key)
+	}
+	s.ElementsMatch([]string{batchLevelErrorGroup, "lane-change", unknownTestErrorGroup}, keys)
+	for _, group := range summary.ByCode {
+		if group.Key == "BATCH_METRICS_FAILED" {
+			s.Equal(0, group.Tests)
+		}
+	}
+}
+
+func (s *CommandsSuite) TestFormatBatchErrorSummary() {
+	batchID := uuid.New()
+	jobID := uuid.New()
+	summary := buildBatchErrorSummary(batchID, &api.ListBatchErrorsOutput{
+		JobExecutionErrors: &[]api.ExecutionError{
+			jobError(jobID, "NONZERO_EXIT_CODE", strings.Repeat("x", 2*batchErrorMessageWidth)),
+		},
+	}, map[uuid.UUID]string{jobID: "lane-change"})
+
+	out := formatBatchErrorSummary(summary)
+	s.Contains(out, "Batch "+batchID.String()+" has 1 error.")
+	s.Contains(out, "ERROR CODE")
+	s.Contains(out, "EXPERIENCE")
+	s.Contains(lineWith(out, "lane-change"), "NONZERO_EXIT_CODE")
+	s.Contains(lineWith(out, "lane-change"), strings.Repeat("x", batchErrorMessageWidth-1)+"…")
+
+	empty := formatBatchErrorSummary(buildBatchErrorSummary(batchID, &api.ListBatchErrorsOutput{}, nil))
+	s.Equal("Batch "+batchID.String()+" has no errors.\n", empty)
+}
+
+func (s *CommandsSuite) TestFormatTopBatchErrors() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	var errs []api.ExecutionError
+	for _, code := range []string{"A", "B", "C", "D", "E", "F", "G"} {
+		errs = append(errs, jobError(uuid.New(), code, "failed with "+code))
+	}
+	summary := buildBatchErrorSummary(batchID, &api.ListBatchErrorsOutput{JobExecutionErrors: &errs}, nil)
+
+	out := formatTopBatchErrors(projectID, summary)
+	s.Contains(out, "Batch "+batchID.String()+" has 7 errors")
+	s.Contains(out, "\n  A (1): failed with A")
+	s.Contains(out, "\n  E (1): failed with E")
+	s.NotContains(out, "F (1)")
+	s.Contains(out, "…and 2 more error codes")
+	s.Contains(out, "resim batches errors --project "+projectID.String()+" --batch-id "+batchID.String())
+}
+
+func (s *CommandsSuite) TestFetchBatchErrors() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	s.mockClient.On("ListBatchErrorsWithResponse", matchContext, projectID, batchID).Return(
+		&api.ListBatchErrorsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.ListBatchErrorsOutput{Errors: &[]api.ExecutionError{{ErrorCode: "X"}}},
+		}, nil).Once()
+	output, err := fetchBatchErrors(projectID, batchID)
+	s.NoError(err)
+	s.Len(*output.Errors, 1)
+
+	s.mockClient.On("ListBatchErrorsWithResponse", matchContext, projectID, batchID).Return(
+		&api.ListBatchErrorsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found"},
+		}, nil).Once()
+	_, err = fetchBatchErrors(projectID, batchID)
+	s.ErrorContains(err, "404")
+
+	s.mockClient.On("ListBatchErrorsWithResponse", matchContext, projectID, batchID).Return(
+		(*api.ListBatchErrorsResponse)(nil), errors.New("connection reset")).Once()
+	_, err = fetchBatchErrors(projectID, batchID)
+	s.ErrorContains(err, "connection reset")
+}
+
+func (s *CommandsSuite) TestLogTopBatchErrorsSkipsSuccessfulBatches() {
+	succeeded := &api.Batch{
+		BatchID:   Ptr(uuid.New()),
+		ProjectID: Ptr(uuid.New()),
+		Status:    Ptr(api.BatchStatusSUCCEEDED),
+	}
+	logTopBatchErrors([]*SuperviseResult{{Batch: succeeded}, {Error: errors.New("timed out")}, nil})
+	s.mockClient.AssertNotCalled(s.T(), "ListBatchErrorsWithResponse", matchContext, *succeeded.ProjectID, *succeeded.BatchID)
+}
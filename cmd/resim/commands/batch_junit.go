@@ -0,0 +1,240 @@
+package commands
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+)
+
+// JUnit XML as understood by Jenkins, GitLab and Buildkite: a testsuites root holding
+// one testsuite per batch and one testcase per test (experience) in the batch.
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr,omitempty"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	ID         string          `xml:"id,attr,omitempty"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	ClassName  string          `xml:"classname,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitResult    `xml:"failure,omitempty"`
+	Error      *junitResult    `xml:"error,omitempty"`
+	Skipped    *junitResult    `xml:"skipped,omitempty"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitResult struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// batchJUnitKey is the --junit flag shared by the batch commands that can write a report.
+const batchJUnitKey = "junit"
+
+const junitFlagDescription = "(Optional) Write the results as a JUnit XML report to this path, with one testcase per test. BLOCKER tests are reported as failures, ERROR tests as errors and WARNING tests as passing with a status property."
+
+// jobToJUnitTestCase maps a test's conflated status onto JUnit: BLOCKER is a failure,
+// ERROR an error, CANCELLED and unfinished tests are skipped, and everything else
+// passes. The status (including WARNING) is always recorded as a property.
+func jobToJUnitTestCase(projectID uuid.UUID, batchID uuid.UUID, className string, job api.Job) junitTestCase {
+	testCase := junitTestCase{
+		Name:      "<unknown experience>",
+		ClassName: className,
+	}
+	if job.ExperienceName != nil {
+		testCase.Name = *job.ExperienceName
+	}
+
+	status := "UNKNOWN"
+	if job.ConflatedStatus != nil {
+		status = string(*job.ConflatedStatus)
+	}
+	testCase.Properties = append(testCase.Properties, junitProperty{Name: "resim.status", Value: status})
+	if job.ExperienceID != nil {
+		testCase.Properties = append(testCase.Properties, junitProperty{Name: "resim.experienceID", Value: job.ExperienceID.String()})
+	}
+	var url string
+	if job.JobID != nil {
+		url = buildJobURL(projectID, batchID, *job.JobID)
+		testCase.Properties = append(testCase.Properties, junitProperty{Name: "resim.url", Value: url})
+		testCase.SystemOut = url
+	}
+
+	if job.ConflatedStatus == nil {
+		return testCase
+	}
+	switch *job.ConflatedStatus {
+	case api.ConflatedJobStatusBLOCKER:
+		testCase.Failure = &junitResult{
+			Message: "test failed with blocking metrics",
+			Type:    status,
+			Text:    url,
+		}
+	case api.ConflatedJobStatusERROR:
+		testCase.Error = &junitResult{
+			Message: "test errored",
+			Type:    status,
+			Text:    url,
+		}
+		if job.ExecutionError != nil {
+			testCase.Error.Type = batchErrorCode(*job.ExecutionError)
+			if message := batchErrorMessage(*job.ExecutionError); message != "" {
+				testCase.Error.Message = message
+			}
+		}
+	case api.ConflatedJobStatusCANCELLED:
+		testCase.Skipped = &junitResult{Message: "test was cancelled"}
+	case api.ConflatedJobStatusQUEUED, api.ConflatedJobStatusRUNNING:
+		testCase.Skipped = &junitResult{Message: "test had not finished"}
+	}
+	return testCase
+}
+
+// batchToJUnitTestSuite builds the testsuite for one batch. Test cases are sorted by
+// experience name so reports are stable across runs.
+func batchToJUnitTestSuite(batch *api.Batch, jobs []api.Job) junitTestSuite {
+	var projectID, batchID uuid.UUID
+	if batch.ProjectID != nil {
+		projectID = *batch.ProjectID
+	}
+	if batch.BatchID != nil {
+		batchID = *batch.BatchID
+	}
+	name := batchID.String()
+	if batch.FriendlyName != nil && *batch.FriendlyName != "" {
+		name = *batch.FriendlyName
+	}
+
+	suite := junitTestSuite{
+		Name: name,
+		ID:   batchID.String(),
+		Properties: []junitProperty{
+			{Name: "resim.batchID", Value: batchID.String()},
+			{Name: "resim.url", Value: buildProjectBaseURL(projectID).JoinPath("batches", batchID.String()).String()},
+		},
+		TestCases: []junitTestCase{},
+	}
+	if batch.Status != nil {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "resim.status", Value: string(*batch.Status)})
+	}
+	if batch.ConflatedStatus != nil {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "resim.conflatedStatus", Value: string(*batch.ConflatedStatus)})
+	}
+	if batch.CreationTimestamp != nil {
+		suite.Timestamp = batch.CreationTimestamp.UTC().Format("2006-01-02T15:04:05")
+	}
+
+	for _, job := range jobs {
+		testCase := jobToJUnitTestCase(projectID, batchID, name, job)
+		suite.Tests++
+		switch {
+		case testCase.Failure != nil:
+			suite.Failures++
+		case testCase.Error != nil:
+			suite.Errors++
+		case testCase.Skipped != nil:
+			suite.Skipped++
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	sort.SliceStable(suite.TestCases, func(i, j int) bool {
+		return suite.TestCases[i].Name < suite.TestCases[j].Name
+	})
+	return suite
+}
+
+// buildJUnitReport totals the suites into a testsuites root.
+func buildJUnitReport(name string, suites []junitTestSuite) *junitTestSuites {
+	report := &junitTestSuites{Name: name, Suites: suites}
+	for _, suite := range suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+	}
+	return report
+}
+
+func marshalJUnitReport(report *junitTestSuites) ([]byte, error) {
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// writeJUnitReport fetches the tests of each batch and writes the report to path.
+// Nil batches (e.g. from a supervise that timed out before the batch was found) are skipped.
+func writeJUnitReport(path string, name string, batches []*api.Batch) error {
+	var suites []junitTestSuite
+	for _, batch := range batches {
+		if batch == nil || batch.BatchID == nil || batch.ProjectID == nil {
+			continue
+		}
+		jobs, err := fetchAllJobs(*batch.ProjectID, *batch.BatchID)
+		if err != nil {
+			return err
+		}
+		suites = append(suites, batchToJUnitTestSuite(batch, jobs))
+	}
+	data, err := marshalJUnitReport(buildJUnitReport(name, suites))
+	if err != nil {
+		return fmt.Errorf("unable to encode JUnit report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("unable to write JUnit report: %w", err)
+	}
+	infoLog("Wrote JUnit report to %s\n", path)
+	return nil
+}
+
+// writeJUnitReportForResults writes the report for the batches of a wait or supervise,
+// if path is set. Errors are logged rather than fatal, so they never replace the
+// exit code of the batches.
+func writeJUnitReportForResults(path string, name string, results []*SuperviseResult) {
+	if path == "" {
+		return
+	}
+	var batches []*api.Batch
+	for _, res := range results {
+		if res != nil {
+			batches = append(batches, res.Batch)
+		}
+	}
+	if len(batches) == 0 {
+		log.Printf("No batches to report; not writing JUnit report to %s\n", path)
+		return
+	}
+	if err := writeJUnitReport(path, name, batches); err != nil {
+		log.Printf("Unable to write JUnit report to %s: %v\n", path, err)
+	}
+}
@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"encoding/xml"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	"github.com/resim-ai/api-client/auth"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+)
+
+func junitPropertyValue(properties []junitProperty, name string) string {
+	for _, property := range properties {
+		if property.Name == name {
+			return property.Value
+		}
+	}
+	return ""
+}
+
+func (s *CommandsSuite) TestBatchToJUnitTestSuite() {
+	viper.Reset()
+	viper.Set(auth.KeyURL, "https://api.resim.ai/v1/")
+	defer viper.Reset()
+
+	projectID := uuid.New()
+	batch := &api.Batch{
+		ProjectID:       Ptr(projectID),
+		BatchID:         Ptr(uuid.New()),
+		FriendlyName:    Ptr("rejoicing-aquamarine-starfish"),
+		Status:          Ptr(api.BatchStatusSUCCEEDED),
+		ConflatedStatus: Ptr(api.ConflatedBatchStatusBLOCKER),
+	}
+	errored := testJob("b-errored", api.ConflatedJobStatusERROR)
+	errored.ExecutionError = &api.ExecutionError{ErrorCode: "NONZERO_EXIT_CODE", ErrorText: Ptr("segfault")}
+	jobs := []api.Job{
+		testJob("e-passed", api.ConflatedJobStatusPASSED),
+		testJob("a-blocker", api.ConflatedJobStatusBLOCKER),
+		errored,
+		testJob("d-warning", api.ConflatedJobStatusWARNING),
+		testJob("c-cancelled", api.ConflatedJobStatusCANCELLED),
+		testJob("f-running", api.ConflatedJobStatusRUNNING),
+	}
+
+	suite := batchToJUnitTestSuite(batch, jobs)
+	s.Equal("rejoicing-aquamarine-starfish", suite.Name)
+	s.Equal(6, suite.Tests)
+	s.Equal(1, suite.Failures)
+	s.Equal(1, suite.Errors)
+	s.Equal(2, suite.Skipped)
+	s.Equal("https://app.resim.ai/projects/"+projectID.String()+"/batches/"+batch.BatchID.String(), junitPropertyValue(suite.Properties, "resim.url"))
+	s.Equal("BLOCKER", junitPropertyValue(suite.Properties, "resim.conflatedStatus"))
+
+	var names []string
+	for _, testCase := range suite.TestCases {
+		names = append(names, testCase.Name)
+		s.Equal("rejoicing-aquamarine-starfish", testCase.ClassName)
+	}
+	s.Equal([]string{"a-blocker", "b-errored", "c-cancelled", "d-warning", "e-passed", "f-running"}, names)
+
+	blocker := suite.TestCases[0]
+	s.Require().NotNil(blocker.Failure)
+	s.Equal("BLOCKER", blocker.Failure.Type)
+	s.Contains(blocker.Failure.Text, "/jobs/"+jobs[1].JobID.String())
+
+	s.Require().NotNil(suite.TestCases[1].Error)
+	s.Equal("NONZERO_EXIT_CODE", suite.TestCases[1].Error.Type)
+	s.Equal("segfault", suite.TestCases[1].Error.Message)
+
+	warning := suite.TestCases[3]
+	s.Nil(warning.Failure)
+	s.Nil(warning.Error)
+	s.Nil(warning.Skipped)
+	s.Equal("WARNING", junitPropertyValue(warning.Properties, "resim.status"))
+
+	s.Nil(suite.TestCases[4].Failure)
+	s.Nil(suite.TestCases[4].Skipped)
+}
+
+func (s *CommandsSuite) TestMarshalJUnitReport() {
+	viper.Reset()
+	viper.Set(auth.KeyURL, "https://api.resim.ai/v1/")
+	defer viper.Reset()
+
+	batch := &api.Batch{ProjectID: Ptr(uuid.New()), BatchID: Ptr(uuid.New()), FriendlyName: Ptr("nightly")}
+	suites := []junitTestSuite{
+		batchToJUnitTestSuite(batch, []api.Job{testJob("lane-change", api.ConflatedJobStatusBLOCKER)}),
+		batchToJUnitTestSuite(batch, []api.Job{testJob("merge", api.ConflatedJobStatusPASSED)}),
+	}
+	data, err := marshalJUnitReport(buildJUnitReport("ReSim", suites))
+	s.Require().NoError(err)
+	out := string(data)
+	s.True(strings.HasPrefix(out, xml.Header))
+	s.Contains(out, `<testsuites name="ReSim" tests="2" failures="1" errors="0" skipped="0">`)
+	s.Contains(out, `<testcase name="lane-change" classname="nightly">`)
+	s.Contains(out, `<failure message="test failed with blocking metrics" type="BLOCKER">`)
+	s.Contains(out, `<property name="resim.status" value="PASSED"></property>`)
+
+	var parsed junitTestSuites
+	s.Require().NoError(xml.Unmarshal(data, &parsed))
+	s.Len(parsed.Suites, 2)
+}
+
+func (s *CommandsSuite) TestWriteJUnitReportForResults() {
+	viper.Reset()
+	viper.Set(auth.KeyURL, "https://api.resim.ai/v1/")
+	defer viper.Reset()
+
+	projectID := uuid.New()
+	batch := &api.Batch{ProjectID: Ptr(projectID), BatchID: Ptr(uuid.New()), FriendlyName: Ptr("nightly")}
+	jobs := []api.Job{testJob("lane-change", api.ConflatedJobStatusERROR)}
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, *batch.BatchID, mock.Anything).Return(
+		&api.ListJobsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.ListJobsOutput{Jobs: &jobs},
+		}, nil)
+
+	path := filepath.Join(s.T().TempDir(), "report.xml")
+	// Results without a batch (e.g. a timeout) are skipped.
+	writeJUnitReportForResults(path, "ReSim", []*SuperviseResult{{Batch: batch}, {Error: &TimeoutError{message: "timeout"}}})
+
+	data, err := os.ReadFile(path)
+	s.Require().NoError(err)
+	var report junitTestSuites
+	s.Require().NoError(xml.Unmarshal(data, &report))
+	s.Equal(1, report.Tests)
+	s.Equal(1, report.Errors)
+	s.Require().Len(report.Suites, 1)
+	s.Equal("lane-change", report.Suites[0].TestCases[0].Name)
+
+	// Nothing is written when no path is given.
+	writeJUnitReportForResults("", "ReSim", []*SuperviseResult{{Batch: batch}})
+	s.mockClient.AssertNumberOfCalls(s.T(), "ListJobsWithResponse", 1)
+}
+
+func (s *CommandsSuite) TestWriteJUnitReportForResultsIgnoresAPIErrors() {
+	projectID := uuid.New()
+	batch := &api.Batch{ProjectID: Ptr(projectID), BatchID: Ptr(uuid.New())}
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, *batch.BatchID, mock.Anything).Return(
+		&api.ListJobsResponse{HTTPResponse: &http.Response{StatusCode: http.StatusServiceUnavailable}}, nil)
+
+	path := filepath.Join(s.T().TempDir(), "report.xml")
+	// The error is logged and no report is written, leaving the exit code to the batch status.
+	writeJUnitReportForResults(path, "ReSim", []*SuperviseResult{{Batch: batch}})
+
+	_, err := os.Stat(path)
+	s.True(os.IsNotExist(err))
+}
@@ -0,0 +1,261 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/cmd/resim/commands/utils"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var createLightBatchCmd = &cobra.Command{
+	Use:   "create-light",
+	Short: "create-light - Creates a new light batch",
+	Long: `Creates a light batch: a batch that does not run a build image. Instead of a build ID, it takes
+a branch, a system and an optional version. The latest build matching them is reused, and a new one
+is created only if none exists.
+
+Experiences are selected with the same flags as 'batches create'. Experience and experience tag names
+are resolved to IDs before the batch is created, and one test is added to the batch per experience.
+Pass --test-suite to link the batch to a test suite and add the experiences of its revision.
+
+As with 'ingest --light', each test is closed as succeeded once it has been added, and the batch is
+then closed so that its metrics are computed. If a test cannot be added, the batch is cancelled.
+
+Light batches do not accept build parameters, pool labels or a metrics build.`,
+	Run: createLightBatchCommand,
+}
+
+const (
+	batchVersionKey           = "version"
+	batchTestSuiteRevisionKey = "test-suite-revision"
+)
+
+func init() {
+	createLightBatchCmd.Flags().Bool(batchGithubKey, false, "Whether to output format in github action friendly format")
+	createLightBatchCmd.Flags().String(batchProjectKey, "", "The name or ID of the project to associate with the batch")
+	createLightBatchCmd.MarkFlagRequired(batchProjectKey)
+	createLightBatchCmd.Flags().String(batchBranchKey, "", "The name or ID of the branch to run on")
+	createLightBatchCmd.MarkFlagRequired(batchBranchKey)
+	createLightBatchCmd.Flags().String(batchSystemKey, "", "The name or ID of the system to run. Defaults to the test suite's system when --test-suite is set.")
+	createLightBatchCmd.Flags().String(batchVersionKey, "", "(Optional) The version (often a commit SHA) of the software under test. If not provided, a new build is created with version \"n/a\".")
+	createLightBatchCmd.Flags().String(batchTestSuiteKey, "", "(Optional) The name or ID of a test suite to link the batch to. Its experiences are added to the batch.")
+	createLightBatchCmd.Flags().Int32(batchTestSuiteRevisionKey, 0, "(Optional) The revision of the test suite. Defaults to the latest revision.")
+	createLightBatchCmd.Flags().String(batchExperienceIDsKey, "", "Comma-separated list of experience IDs to run.")
+	createLightBatchCmd.Flags().String(batchExperiencesKey, "", "List of experience names or list of experience IDs to run, comma-separated")
+	createLightBatchCmd.Flags().String(batchExperienceTagIDsKey, "", "Comma-separated list of experience tag IDs to run.")
+	createLightBatchCmd.Flags().String(batchExperienceTagNamesKey, "", "Comma-separated list of experience tag names to run.")
+	createLightBatchCmd.Flags().String(batchExperienceTagsKey, "", "List of experience tag names or list of experience tag IDs to run, comma-separated.")
+	createLightBatchCmd.MarkFlagsOneRequired(batchTestSuiteKey, batchExperienceIDsKey, batchExperiencesKey, batchExperienceTagIDsKey, batchExperienceTagNamesKey, batchExperienceTagsKey)
+	createLightBatchCmd.Flags().String(batchNameKey, "", "An optional name for the batch. If not supplied, ReSim generates a pseudo-unique name e.g rejoicing-aquamarine-starfish.")
+	createLightBatchCmd.Flags().Int(batchPriorityKey, requestPriorityDefault, requestPriorityDescription)
+	createLightBatchCmd.Flags().String(batchMetricsSetKey, "", "The name of the metrics set to use to generate test and batch metrics")
+	batchCmd.AddCommand(createLightBatchCmd)
+}
+
+func createLightBatchCommand(ccmd *cobra.Command, args []string) {
+	projectID := getProjectID(Client, viper.GetString(batchProjectKey))
+	batchGithub := viper.GetBool(batchGithubKey)
+	if !batchGithub {
+		fmt.Println("Creating a light batch...")
+	}
+
+	branchID := getBranchID(Client, projectID, viper.GetString(batchBranchKey), true)
+	body := api.LightBatchInput{
+		BranchID: branchID,
+		Priority: getRequestPriority(batchPriorityKey),
+	}
+
+	var testSuiteExperienceIDs []uuid.UUID
+	if viper.IsSet(batchTestSuiteKey) {
+		var revision *int32
+		if viper.IsSet(batchTestSuiteRevisionKey) {
+			revision = Ptr(viper.GetInt32(batchTestSuiteRevisionKey))
+		}
+		testSuite := actualGetTestSuite(projectID, viper.GetString(batchTestSuiteKey), revision, false)
+		body.TestSuiteID = Ptr(testSuite.TestSuiteID)
+		body.TestSuiteRevision = Ptr(testSuite.TestSuiteRevision)
+		body.SystemID = Ptr(testSuite.SystemID)
+		testSuiteExperienceIDs = testSuite.Experiences
+	}
+	if viper.IsSet(batchSystemKey) {
+		body.SystemID = Ptr(getSystemID(Client, projectID, viper.GetString(batchSystemKey), true))
+	}
+	if body.SystemID == nil {
+		log.Fatalf("failed to create light batch: one of --%v or --%v is required", batchSystemKey, batchTestSuiteKey)
+	}
+
+	if viper.IsSet(batchVersionKey) {
+		body.Version = Ptr(viper.GetString(batchVersionKey))
+	}
+	if viper.IsSet(batchNameKey) {
+		body.BatchName = Ptr(viper.GetString(batchNameKey))
+	}
+
+	body.MetricsSetName = ProcessMetricsSet(batchMetricsSetKey, nil)
+	if err := validateMetricsSetExists(branchID, body.MetricsSetName); err != nil {
+		log.Fatal(err)
+	}
+
+	experienceIDs := resolveLightBatchExperiences(projectID, testSuiteExperienceIDs, parseBatchExperienceFlags())
+	if len(experienceIDs) == 0 {
+		log.Fatal("failed to create light batch: no experiences selected")
+	}
+	batch, jobs := createLightBatch(projectID, body, experienceIDs)
+	closeLightBatch(projectID, *batch.BatchID, jobs)
+	reportCreatedBatch(batch, batchGithub)
+}
+
+// resolveLightBatchExperiences turns the experiences of a test suite and an
+// experience selection into the IDs of the experiences to add to a light batch.
+// The light batch API only accepts experience IDs, so names and tags are
+// resolved here. Duplicates are dropped and the order of the flags is kept,
+// after the test suite's experiences.
+func resolveLightBatchExperiences(projectID uuid.UUID, testSuiteExperienceIDs []uuid.UUID, selection batchExperienceSelection) []uuid.UUID {
+	var experienceIDs []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	add := func(experienceID uuid.UUID) {
+		if !seen[experienceID] {
+			seen[experienceID] = true
+			experienceIDs = append(experienceIDs, experienceID)
+		}
+	}
+
+	for _, experienceID := range testSuiteExperienceIDs {
+		add(experienceID)
+	}
+	for _, experienceID := range selection.ExperienceIDs {
+		add(experienceID)
+	}
+	for _, name := range selection.ExperienceNames {
+		add(getExperienceID(Client, projectID, name, true, false))
+	}
+
+	experienceTagIDs := append([]uuid.UUID{}, selection.ExperienceTagIDs...)
+	for _, name := range selection.ExperienceTagNames {
+		experienceTagIDs = append(experienceTagIDs, getExperienceTagIDForName(Client, projectID, name, true))
+	}
+	for _, experienceTagID := range experienceTagIDs {
+		for _, experience := range fetchExperiencesWithTag(projectID, experienceTagID) {
+			add(experience.ExperienceID)
+		}
+	}
+	return experienceIDs
+}
+
+func fetchExperiencesWithTag(projectID uuid.UUID, experienceTagID uuid.UUID) []api.Experience {
+	var experiences []api.Experience
+	var pageToken *string = nil
+	for {
+		response, err := Client.ListExperiencesWithExperienceTagWithResponse(context.Background(), projectID, experienceTagID,
+			&api.ListExperiencesWithExperienceTagParams{
+				PageSize:  Ptr(100),
+				PageToken: pageToken,
+			})
+		if err != nil {
+			log.Fatal("failed to list experiences: ", err)
+		}
+		ValidateResponse(http.StatusOK, "failed to list experiences", response.HTTPResponse, response.Body)
+		if response.JSON200 == nil {
+			log.Fatal("empty response")
+		}
+		if response.JSON200.Experiences != nil {
+			experiences = append(experiences, *response.JSON200.Experiences...)
+		}
+		pageToken = response.JSON200.NextPageToken
+		if pageToken == nil || *pageToken == "" {
+			break
+		}
+	}
+	return experiences
+}
+
+// createLightBatch creates a light batch and adds one test to it for each
+// experience. It returns the batch and the tests in experience order. If a test
+// cannot be added, the batch is cancelled rather than left half-populated.
+func createLightBatch(projectID uuid.UUID, body api.LightBatchInput, experienceIDs []uuid.UUID) (api.Batch, []api.Job) {
+	response, err := Client.CreateLightBatchWithResponse(context.Background(), projectID, body)
+	if err != nil {
+		log.Fatal("failed to create light batch:", err)
+	}
+	ValidateResponse(http.StatusCreated, "failed to create light batch", response.HTTPResponse, response.Body)
+	if response.JSON201 == nil {
+		log.Fatal("empty response")
+	}
+	batch := *response.JSON201
+	if batch.BatchID == nil {
+		log.Fatal("empty ID")
+	}
+
+	jobs, err := addLightBatchJobs(projectID, *batch.BatchID, experienceIDs)
+	if err != nil {
+		cancelLightBatch(projectID, *batch.BatchID)
+		log.Fatal(err)
+	}
+	return batch, jobs
+}
+
+// addLightBatchJobs adds one test to a light batch for each experience, stopping
+// at the first that cannot be added.
+func addLightBatchJobs(projectID uuid.UUID, batchID uuid.UUID, experienceIDs []uuid.UUID) ([]api.Job, error) {
+	jobs := make([]api.Job, 0, len(experienceIDs))
+	for _, experienceID := range experienceIDs {
+		response, err := Client.CreateJobForBatchWithResponse(context.Background(), projectID, batchID, api.CreateJobForBatchInput{
+			ExperienceID: Ptr(experienceID),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add test to light batch: %w", err)
+		}
+		if err := ValidateResponseSafe(http.StatusCreated, "failed to add test to light batch", response.HTTPResponse, response.Body); err != nil {
+			return nil, err
+		}
+		if response.JSON201 == nil {
+			return nil, fmt.Errorf("failed to add test to light batch: empty response")
+		}
+		jobs = append(jobs, *response.JSON201)
+	}
+	return jobs, nil
+}
+
+// cancelLightBatch cancels a light batch that could not be populated. Failing to
+// cancel it is only logged, since the caller is already exiting with an error.
+func cancelLightBatch(projectID uuid.UUID, batchID uuid.UUID) {
+	response, err := Client.CancelBatchWithResponse(context.Background(), projectID, batchID)
+	if err == nil {
+		err = ValidateResponseSafe(http.StatusOK, "failed to cancel batch", response.HTTPResponse, response.Body)
+	}
+	if err != nil {
+		log.Printf("Unable to cancel light batch %s: %v\n", batchID, err)
+		return
+	}
+	log.Printf("Cancelled light batch %s\n", batchID)
+}
+
+// closeLightBatch closes each test of a light batch as succeeded and then
+// closes the batch itself.
+func closeLightBatch(projectID uuid.UUID, batchID uuid.UUID, jobs []api.Job) {
+	for _, job := range jobs {
+		if job.JobID == nil {
+			log.Fatal("empty test ID")
+		}
+		response, err := Client.CloseJobWithResponse(context.Background(), projectID, batchID, *job.JobID, api.CloseJobInput{
+			Status: api.SUCCEEDED,
+		})
+		if err != nil {
+			log.Fatal("failed to close test:", err)
+		}
+		ValidateResponse(http.StatusNoContent, "failed to close test", response.HTTPResponse, response.Body)
+	}
+
+	response, err := Client.CloseBatchWithResponse(context.Background(), projectID, batchID)
+	if err != nil {
+		log.Fatal("failed to close batch:", err)
+	}
+	ValidateResponse(http.StatusNoContent, "failed to close batch", response.HTTPResponse, response.Body)
+}
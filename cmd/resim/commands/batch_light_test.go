@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *CommandsSuite) TestCreateLightBatchResolvesExperiences() {
+	viper.Reset()
+	projectID := uuid.New()
+	branchID := uuid.New()
+	systemID := uuid.New()
+	batchID := uuid.New()
+	laneChange, merge, cutIn := uuid.New(), uuid.New(), uuid.New()
+	tagID := uuid.New()
+
+	viper.Set(batchProjectKey, projectID.String())
+	viper.Set(batchBranchKey, branchID.String())
+	viper.Set(batchSystemKey, systemID.String())
+	viper.Set(batchVersionKey, "abc123")
+	viper.Set(batchExperiencesKey, laneChange.String()+",merge")
+	viper.Set(batchExperienceTagIDsKey, tagID.String())
+
+	s.mockClient.On("GetProjectWithResponse", matchContext, projectID).Return(&api.GetProjectResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Project{ProjectID: projectID, Name: "test-project"},
+	}, nil)
+	s.mockClient.On("GetBranchForProjectWithResponse", matchContext, projectID, branchID).Return(&api.GetBranchForProjectResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+	}, nil)
+	s.mockClient.On("GetSystemWithResponse", matchContext, projectID, systemID).Return(&api.GetSystemResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+	}, nil)
+	s.mockClient.On("ListExperiencesWithResponse", matchContext, projectID, mock.Anything).Return(&api.ListExperiencesResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.ListExperiencesOutput{Experiences: &[]api.Experience{
+			{ExperienceID: merge, Name: "merge"},
+		}},
+	}, nil)
+	// The tag also contains lane-change, which is only added once.
+	s.mockClient.On("ListExperiencesWithExperienceTagWithResponse", matchContext, projectID, tagID, mock.Anything).Return(&api.ListExperiencesWithExperienceTagResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.ListExperiencesOutput{Experiences: &[]api.Experience{
+			{ExperienceID: cutIn, Name: "cut-in"},
+			{ExperienceID: laneChange, Name: "lane-change"},
+		}},
+	}, nil)
+	s.mockClient.On("CreateLightBatchWithResponse", matchContext, projectID, mock.MatchedBy(func(body api.LightBatchInput) bool {
+		return body.BranchID == branchID && *body.SystemID == systemID && *body.Version == "abc123" && body.TestSuiteID == nil
+	})).Return(&api.CreateLightBatchResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusCreated},
+		JSON201: &api.Batch{
+			BatchID:      Ptr(batchID),
+			FriendlyName: Ptr("light-batch"),
+			Status:       Ptr(api.BatchStatusSUBMITTED),
+		},
+	}, nil).Once()
+	var added []uuid.UUID
+	s.mockClient.On("CreateJobForBatchWithResponse", matchContext, projectID, batchID, mock.Anything).Run(func(args mock.Arguments) {
+		added = append(added, *args.Get(3).(api.CreateJobForBatchInput).ExperienceID)
+	}).Return(&api.CreateJobForBatchResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusCreated},
+		JSON201:      &api.Job{JobID: Ptr(uuid.New())},
+	}, nil)
+
+	// The tests and the batch are closed once the tests have been added.
+	s.mockClient.On("CloseJobWithResponse", matchContext, projectID, batchID, mock.Anything, api.CloseJobInput{Status: api.SUCCEEDED}).Return(
+		&api.CloseJobResponse{HTTPResponse: &http.Response{StatusCode: http.StatusNoContent}}, nil)
+	s.mockClient.On("CloseBatchWithResponse", matchContext, projectID, batchID).Return(
+		&api.CloseBatchResponse{HTTPResponse: &http.Response{StatusCode: http.StatusNoContent}}, nil).Once()
+
+	createLightBatchCommand(nil, nil)
+
+	s.Equal([]uuid.UUID{laneChange, merge, cutIn}, added)
+	s.mockClient.AssertNumberOfCalls(s.T(), "CloseJobWithResponse", 3)
+	s.mockClient.AssertNumberOfCalls(s.T(), "CloseBatchWithResponse", 1)
+	s.mockClient.AssertNotCalled(s.T(), "CreateBatchWithResponse", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *CommandsSuite) TestResolveLightBatchExperiencesAddsTestSuiteExperiences() {
+	projectID := uuid.New()
+	laneChange, merge := uuid.New(), uuid.New()
+
+	experienceIDs := resolveLightBatchExperiences(projectID, []uuid.UUID{laneChange, merge}, batchExperienceSelection{
+		ExperienceIDs: []uuid.UUID{merge},
+	})
+
+	s.Equal([]uuid.UUID{laneChange, merge}, experienceIDs)
+}
+
+func (s *CommandsSuite) TestAddLightBatchJobsStopsAtFirstError() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	laneChange, merge, cutIn := uuid.New(), uuid.New(), uuid.New()
+	s.mockClient.On("CreateJobForBatchWithResponse", matchContext, projectID, batchID, api.CreateJobForBatchInput{ExperienceID: Ptr(laneChange)}).Return(
+		&api.CreateJobForBatchResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusCreated},
+			JSON201:      &api.Job{JobID: Ptr(uuid.New())},
+		}, nil).Once()
+	s.mockClient.On("CreateJobForBatchWithResponse", matchContext, projectID, batchID, api.CreateJobForBatchInput{ExperienceID: Ptr(merge)}).Return(
+		&api.CreateJobForBatchResponse{HTTPResponse: &http.Response{StatusCode: http.StatusInternalServerError}}, nil).Once()
+
+	_, err := addLightBatchJobs(projectID, batchID, []uuid.UUID{laneChange, merge, cutIn})
+
+	s.ErrorContains(err, "failed to add test to light batch")
+	s.mockClient.AssertNumberOfCalls(s.T(), "CreateJobForBatchWithResponse", 2)
+}
+
+func (s *CommandsSuite) TestCloseLightBatch() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	jobs := []api.Job{{JobID: Ptr(uuid.New())}, {JobID: Ptr(uuid.New())}}
+
+	for _, job := range jobs {
+		s.mockClient.On("CloseJobWithResponse", matchContext, projectID, batchID, *job.JobID, api.CloseJobInput{Status: api.SUCCEEDED}).Return(
+			&api.CloseJobResponse{HTTPResponse: &http.Response{StatusCode: http.StatusNoContent}}, nil).Once()
+	}
+	s.mockClient.On("CloseBatchWithResponse", matchContext, projectID, batchID).Return(
+		&api.CloseBatchResponse{HTTPResponse: &http.Response{StatusCode: http.StatusNoContent}}, nil).Once()
+
+	closeLightBatch(projectID, batchID, jobs)
+
+	s.mockClient.AssertNumberOfCalls(s.T(), "CloseJobWithResponse", 2)
+	s.mockClient.AssertNumberOfCalls(s.T(), "CloseBatchWithResponse", 1)
+}
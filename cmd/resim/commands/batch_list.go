@@ -0,0 +1,391 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/cmd/resim/commands/utils"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var listBatchesCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list - Lists the batches in a project, most recent first",
+	Long: `list - Lists the batches in a project, most recent first.
+
+Batches can be filtered by branch, build, test suite, system, account, status and
+creation time. Results are printed page by page as they are fetched, so large
+listings start immediately; --limit stops once that many batches have been printed.
+
+--created-after and --created-before accept an RFC 3339 timestamp
+(2026-01-02T15:04:05Z), a date (2026-01-02) or a duration relative to now (24h).`,
+	Run: listBatches,
+}
+
+const (
+	batchBranchKey        = "branch"
+	batchBuildKey         = "build"
+	batchTestSuiteKey     = "test-suite"
+	batchSystemKey        = "system"
+	batchStatusKey        = "status"
+	batchCreatedAfterKey  = "created-after"
+	batchCreatedBeforeKey = "created-before"
+)
+
+// batchListPageSize is the page size requested from the server.
+const batchListPageSize = 100
+
+func init() {
+	listBatchesCmd.Flags().String(batchProjectKey, "", "The name or ID of the project to list batches for")
+	listBatchesCmd.MarkFlagRequired(batchProjectKey)
+	listBatchesCmd.Flags().String(batchBranchKey, "", "(Optional) Only list batches on this branch (name or ID)")
+	listBatchesCmd.Flags().String(batchBuildKey, "", "(Optional) Only list batches of this build ID")
+	listBatchesCmd.Flags().String(batchTestSuiteKey, "", "(Optional) Only list batches run from this test suite (name or ID)")
+	listBatchesCmd.Flags().String(batchSystemKey, "", "(Optional) Only list batches for this system (name or ID)")
+	listBatchesCmd.Flags().String(batchAccountKey, "", "(Optional) Only list batches associated with this CI/CD account")
+	listBatchesCmd.Flags().String(batchStatusKey, "", "(Optional) Comma-separated list of batch statuses to list (SUBMITTED, EXPERIENCES_RUNNING, BATCH_METRICS_QUEUED, BATCH_METRICS_RUNNING, SUCCEEDED, ERROR, CANCELLED)")
+	listBatchesCmd.Flags().String(batchCreatedAfterKey, "", "(Optional) Only list batches created after this time")
+	listBatchesCmd.Flags().String(batchCreatedBeforeKey, "", "(Optional) Only list batches created before this time")
+	listBatchesCmd.Flags().Int(batchLimitKey, 50, "The maximum number of batches to list. 0 lists every matching batch.")
+	listBatchesCmd.Flags().String(batchOutputKey, batchOutputTable, "Output format: table or json")
+	listBatchesCmd.Flags().SetNormalizeFunc(aliasProjectNameFunc)
+	batchCmd.AddCommand(listBatchesCmd)
+}
+
+// batchListFilter holds the resolved filters of batches list. Zero values match everything.
+type batchListFilter struct {
+	BranchID      uuid.UUID
+	BuildID       uuid.UUID
+	TestSuiteID   uuid.UUID
+	SystemID      uuid.UUID
+	Account       string
+	Statuses      []api.BatchStatus
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// searchQuery builds the ListBatches search expression for the filters the server
+// supports. The test suite and account have no search field and are applied locally.
+func (f batchListFilter) searchQuery() string {
+	var clauses []string
+	if f.BranchID != uuid.Nil {
+		clauses = append(clauses, fmt.Sprintf("branch_id=\"%v\"", f.BranchID))
+	}
+	if f.BuildID != uuid.Nil {
+		clauses = append(clauses, fmt.Sprintf("build_id=\"%v\"", f.BuildID))
+	}
+	if f.SystemID != uuid.Nil {
+		clauses = append(clauses, fmt.Sprintf("system_id=\"%v\"", f.SystemID))
+	}
+	if len(f.Statuses) > 0 {
+		var statuses []string
+		for _, status := range f.Statuses {
+			statuses = append(statuses, fmt.Sprintf("status=\"%s\"", status))
+		}
+		if len(statuses) == 1 {
+			clauses = append(clauses, statuses[0])
+		} else {
+			clauses = append(clauses, "("+strings.Join(statuses, " OR ")+")")
+		}
+	}
+	if !f.CreatedAfter.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("created_at>\"%s\"", f.CreatedAfter.UTC().Format(time.RFC3339)))
+	}
+	if !f.CreatedBefore.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("created_at<\"%s\"", f.CreatedBefore.UTC().Format(time.RFC3339)))
+	}
+	return strings.Join(clauses, " AND ")
+}
+
+// matches applies every filter to a batch. The server already applies most of them,
+// but the test suite endpoint takes no search and the account is never server-side.
+func (f batchListFilter) matches(batch api.Batch) bool {
+	if f.BranchID != uuid.Nil && (batch.BranchID == nil || *batch.BranchID != f.BranchID) {
+		return false
+	}
+	if f.BuildID != uuid.Nil && (batch.BuildID == nil || *batch.BuildID != f.BuildID) {
+		return false
+	}
+	if f.TestSuiteID != uuid.Nil && (batch.TestSuiteID == nil || *batch.TestSuiteID != f.TestSuiteID) {
+		return false
+	}
+	if f.SystemID != uuid.Nil && (batch.SystemID == nil || *batch.SystemID != f.SystemID) {
+		return false
+	}
+	if f.Account != "" && batch.AssociatedAccount != f.Account {
+		return false
+	}
+	if len(f.Statuses) > 0 && (batch.Status == nil || !slices.Contains(f.Statuses, *batch.Status)) {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && (batch.CreationTimestamp == nil || !batch.CreationTimestamp.After(f.CreatedAfter)) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && (batch.CreationTimestamp == nil || !batch.CreationTimestamp.Before(f.CreatedBefore)) {
+		return false
+	}
+	return true
+}
+
+// parseBatchStatuses parses a comma-separated list of batch statuses.
+func parseBatchStatuses(raw string) ([]api.BatchStatus, error) {
+	valid := []api.BatchStatus{
+		api.BatchStatusSUBMITTED,
+		api.BatchStatusEXPERIENCESRUNNING,
+		api.BatchStatusBATCHMETRICSQUEUED,
+		api.BatchStatusBATCHMETRICSRUNNING,
+		api.BatchStatusSUCCEEDED,
+		api.BatchStatusERROR,
+		api.BatchStatusCANCELLED,
+	}
+	var statuses []api.BatchStatus
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.ToUpper(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		status := api.BatchStatus(part)
+		if !slices.Contains(valid, status) {
+			return nil, fmt.Errorf("invalid batch status: %s", part)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// parseBatchListTime parses an RFC 3339 timestamp, a date, or a duration before now.
+func parseBatchListTime(raw string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, raw); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(raw); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected an RFC 3339 timestamp, a date (YYYY-MM-DD) or a duration (e.g. 24h)", raw)
+}
+
+// checkBatchAccount fails unless the account has run at least one batch in the project.
+func checkBatchAccount(projectID uuid.UUID, account string) {
+	response, err := Client.ListBatchAccountsWithResponse(context.Background(), projectID, &api.ListBatchAccountsParams{
+		Name: Ptr(account),
+	})
+	if err != nil {
+		log.Fatal("unable to list batch accounts:", err)
+	}
+	ValidateResponse(http.StatusOK, "unable to list batch accounts", response.HTTPResponse, response.Body)
+	if response.JSON200 == nil || !slices.Contains(*response.JSON200, account) {
+		log.Fatalf("no batches found for account %q", account)
+	}
+}
+
+// streamBatches pages through the batches matching the filter, most recent first, and
+// calls emit for each one until limit batches have been emitted (0 means no limit).
+// It returns the number of batches emitted.
+func streamBatches(projectID uuid.UUID, filter batchListFilter, limit int, emit func(api.Batch)) int {
+	emitted := 0
+	var pageToken *string = nil
+	for {
+		var batches []api.Batch
+		var nextPageToken *string
+		if filter.TestSuiteID != uuid.Nil {
+			response, err := Client.ListBatchesForTestSuiteWithResponse(context.Background(), projectID, filter.TestSuiteID, &api.ListBatchesForTestSuiteParams{
+				PageSize:  Ptr(batchListPageSize),
+				PageToken: pageToken,
+				OrderBy:   Ptr("timestamp"),
+			})
+			if err != nil {
+				log.Fatal("unable to list batches for test suite:", err)
+			}
+			ValidateResponse(http.StatusOK, "unable to list batches for test suite", response.HTTPResponse, response.Body)
+			if response.JSON200 == nil {
+				log.Fatal("unable to list batches for test suite")
+			}
+			if response.JSON200.Batches != nil {
+				batches = *response.JSON200.Batches
+			}
+			nextPageToken = response.JSON200.NextPageToken
+		} else {
+			params := &api.ListBatchesParams{
+				PageSize:  Ptr(batchListPageSize),
+				PageToken: pageToken,
+				OrderBy:   Ptr("timestamp"),
+			}
+			if search := filter.searchQuery(); search != "" {
+				params.Search = Ptr(search)
+			}
+			response, err := Client.ListBatchesWithResponse(context.Background(), projectID, params)
+			if err != nil {
+				log.Fatal("unable to list batches:", err)
+			}
+			ValidateResponse(http.StatusOK, "unable to list batches", response.HTTPResponse, response.Body)
+			if response.JSON200 == nil {
+				log.Fatal("unable to list batches")
+			}
+			if response.JSON200.Batches != nil {
+				batches = *response.JSON200.Batches
+			}
+			nextPageToken = response.JSON200.NextPageToken
+		}
+
+		for _, batch := range batches {
+			if !filter.matches(batch) {
+				continue
+			}
+			emit(batch)
+			emitted++
+			if limit > 0 && emitted >= limit {
+				return emitted
+			}
+		}
+
+		if nextPageToken == nil || *nextPageToken == "" {
+			return emitted
+		}
+		pageToken = nextPageToken
+	}
+}
+
+// batchListWriter prints batches as they are streamed.
+type batchListWriter interface {
+	write(batch api.Batch)
+	close(count int)
+}
+
+// batchListTableFormat uses fixed column widths, since rows are printed before the
+// widest value is known.
+const batchListTableFormat = "%-36s  %-32s  %-21s  %-9s  %-20s  %s\n"
+
+type batchListTableWriter struct {
+	out           io.Writer
+	headerWritten bool
+}
+
+func (w *batchListTableWriter) write(batch api.Batch) {
+	if !w.headerWritten {
+		fmt.Fprintf(w.out, batchListTableFormat, "BATCH ID", "NAME", "STATUS", "CONFLATED", "CREATED", "ACCOUNT")
+		w.headerWritten = true
+	}
+	id, name, status, conflated, created := "-", "-", "-", "-", "-"
+	if batch.BatchID != nil {
+		id = batch.BatchID.String()
+	}
+	if batch.FriendlyName != nil && *batch.FriendlyName != "" {
+		name = *batch.FriendlyName
+	}
+	if batch.Status != nil {
+		status = string(*batch.Status)
+	}
+	if batch.ConflatedStatus != nil {
+		conflated = string(*batch.ConflatedStatus)
+	}
+	if batch.CreationTimestamp != nil {
+		created = batch.CreationTimestamp.UTC().Format(time.RFC3339)
+	}
+	account := batch.AssociatedAccount
+	if account == "" {
+		account = "-"
+	}
+	fmt.Fprintf(w.out, batchListTableFormat, id, name, status, conflated, created, account)
+}
+
+func (w *batchListTableWriter) close(count int) {
+	if count == 0 {
+		fmt.Fprintln(w.out, "no batches")
+	}
+}
+
+// batchListJSONWriter streams a JSON array, one element per batch.
+type batchListJSONWriter struct {
+	out     io.Writer
+	written int
+}
+
+func (w *batchListJSONWriter) write(batch api.Batch) {
+	data, err := json.MarshalIndent(batch, "  ", "  ")
+	if err != nil {
+		log.Fatal("unable to encode batch:", err)
+	}
+	if w.written == 0 {
+		fmt.Fprint(w.out, "[\n  ")
+	} else {
+		fmt.Fprint(w.out, ",\n  ")
+	}
+	w.out.Write(data)
+	w.written++
+}
+
+func (w *batchListJSONWriter) close(count int) {
+	if w.written == 0 {
+		fmt.Fprintln(w.out, "[]")
+		return
+	}
+	fmt.Fprint(w.out, "\n]\n")
+}
+
+func newBatchListWriter(output string, out io.Writer) batchListWriter {
+	switch output {
+	case batchOutputTable:
+		return &batchListTableWriter{out: out}
+	case batchOutputJSON:
+		return &batchListJSONWriter{out: out}
+	}
+	log.Fatalf("Unsupported output: %s. Valid outputs are: %s, %s", output, batchOutputTable, batchOutputJSON)
+	return nil
+}
+
+func listBatches(ccmd *cobra.Command, args []string) {
+	projectID := getProjectID(Client, viper.GetString(batchProjectKey))
+	writer := newBatchListWriter(viper.GetString(batchOutputKey), os.Stdout)
+
+	var filter batchListFilter
+	if branch := viper.GetString(batchBranchKey); branch != "" {
+		filter.BranchID = getBranchID(Client, projectID, branch, true)
+	}
+	if build := viper.GetString(batchBuildKey); build != "" {
+		filter.BuildID = getBuildID(Client, projectID, build)
+	}
+	if testSuite := viper.GetString(batchTestSuiteKey); testSuite != "" {
+		filter.TestSuiteID = actualGetTestSuite(projectID, testSuite, nil, false).TestSuiteID
+	}
+	if system := viper.GetString(batchSystemKey); system != "" {
+		filter.SystemID = getSystemID(Client, projectID, system, true)
+	}
+	if account := viper.GetString(batchAccountKey); account != "" {
+		checkBatchAccount(projectID, account)
+		filter.Account = account
+	}
+	statuses, err := parseBatchStatuses(viper.GetString(batchStatusKey))
+	if err != nil {
+		log.Fatal(err)
+	}
+	filter.Statuses = statuses
+	now := time.Now()
+	if raw := viper.GetString(batchCreatedAfterKey); raw != "" {
+		if filter.CreatedAfter, err = parseBatchListTime(raw, now); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if raw := viper.GetString(batchCreatedBeforeKey); raw != "" {
+		if filter.CreatedBefore, err = parseBatchListTime(raw, now); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	count := streamBatches(projectID, filter, viper.GetInt(batchLimitKey), writer.write)
+	writer.close(count)
+}
@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *CommandsSuite) TestBatchListFilterSearchQuery() {
+	s.Equal("", batchListFilter{}.searchQuery())
+
+	branchID := uuid.New()
+	systemID := uuid.New()
+	filter := batchListFilter{
+		BranchID:     branchID,
+		SystemID:     systemID,
+		TestSuiteID:  uuid.New(),
+		Account:      "ci-bot",
+		Statuses:     []api.BatchStatus{api.BatchStatusERROR, api.BatchStatusCANCELLED},
+		CreatedAfter: time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC),
+	}
+	s.Equal(
+		`branch_id="`+branchID.String()+`" AND system_id="`+systemID.String()+`" AND (status="ERROR" OR status="CANCELLED") AND created_at>"2026-01-02T15:04:05Z"`,
+		filter.searchQuery(),
+	)
+	s.Equal(`status="SUCCEEDED"`, batchListFilter{Statuses: []api.BatchStatus{api.BatchStatusSUCCEEDED}}.searchQuery())
+}
+
+func (s *CommandsSuite) TestBatchListFilterMatches() {
+	suiteID := uuid.New()
+	created := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	batch := api.Batch{
+		TestSuiteID:       Ptr(suiteID),
+		AssociatedAccount: "ci-bot",
+		Status:            Ptr(api.BatchStatusSUCCEEDED),
+		CreationTimestamp: Ptr(created),
+	}
+
+	s.True(batchListFilter{}.matches(batch))
+	s.True(batchListFilter{TestSuiteID: suiteID, Account: "ci-bot"}.matches(batch))
+	s.False(batchListFilter{TestSuiteID: uuid.New()}.matches(batch))
+	s.False(batchListFilter{Account: "someone-else"}.matches(batch))
+	s.False(batchListFilter{BranchID: uuid.New()}.matches(batch))
+	s.True(batchListFilter{Statuses: []api.BatchStatus{api.BatchStatusERROR, api.BatchStatusSUCCEEDED}}.matches(batch))
+	s.False(batchListFilter{Statuses: []api.BatchStatus{api.BatchStatusERROR}}.matches(batch))
+	s.True(batchListFilter{CreatedAfter: created.Add(-time.Hour), CreatedBefore: created.Add(time.Hour)}.matches(batch))
+	s.False(batchListFilter{CreatedAfter: created}.matches(batch))
+	s.False(batchListFilter{CreatedBefore: created}.matches(batch))
+}
+
+func (s *CommandsSuite) TestParseBatchStatuses() {
+	statuses, err := parseBatchStatuses("error, succeeded,")
+	s.NoError(err)
+	s.Equal([]api.BatchStatus{api.BatchStatusERROR, api.BatchStatusSUCCEEDED}, statuses)
+
+	statuses, err = parseBatchStatuses("")
+	s.NoError(err)
+	s.Empty(statuses)
+
+	_, err = parseBatchStatuses("RUNNING")
+	s.ErrorContains(err, "invalid batch status: RUNNING")
+}
+
+func (s *CommandsSuite) TestParseBatchListTime() {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	t, err := parseBatchListTime("2026-01-02T15:04:05Z", now)
+	s.NoError(err)
+	s.Equal(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC), t)
+
+	t, err = parseBatchListTime("2026-01-02", now)
+	s.NoError(err)
+	s.Equal(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), t)
+
+	t, err = parseBatchListTime("36h", now)
+	s.NoError(err)
+	s.Equal(time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), t)
+
+	_, err = parseBatchListTime("yesterday", now)
+	s.Error(err)
+}
+
+func listBatchesPage(names []string, nextPageToken string) *api.ListBatchesResponse {
+	batches := []api.Batch{}
+	for _, name := range names {
+		batches = append(batches, api.Batch{BatchID: Ptr(uuid.New()), FriendlyName: Ptr(name), AssociatedAccount: name})
+	}
+	return &api.ListBatchesResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListBatchesOutput{Batches: &batches, NextPageToken: Ptr(nextPageToken)},
+	}
+}
+
+func (s *CommandsSuite) TestStreamBatchesPaginatesAndLimits() {
+	projectID := uuid.New()
+	s.mockClient.On("ListBatchesWithResponse", matchContext, projectID, mock.MatchedBy(func(p *api.ListBatchesParams) bool {
+		return p.PageToken == nil && p.Search == nil
+	})).Return(listBatchesPage([]string{"a", "b"}, "page-2"), nil).Once()
+	s.mockClient.On("ListBatchesWithResponse", matchContext, projectID, mock.MatchedBy(func(p *api.ListBatchesParams) bool {
+		return p.PageToken != nil && *p.PageToken == "page-2"
+	})).Return(listBatchesPage([]string{"c", "d", "e"}, "page-3"), nil).Once()
+
+	var names []string
+	count := streamBatches(projectID, batchListFilter{}, 4, func(batch api.Batch) {
+		names = append(names, *batch.FriendlyName)
+	})
+	s.Equal(4, count)
+	s.Equal([]string{"a", "b", "c", "d"}, names)
+	// The limit is reached on the second page, so the third is never fetched.
+	s.mockClient.AssertNumberOfCalls(s.T(), "ListBatchesWithResponse", 2)
+}
+
+func (s *CommandsSuite) TestStreamBatchesSendsSearch() {
+	projectID := uuid.New()
+	branchID := uuid.New()
+	batches := []api.Batch{{BranchID: Ptr(branchID), FriendlyName: Ptr("on-branch")}}
+	s.mockClient.On("ListBatchesWithResponse", matchContext, projectID, mock.MatchedBy(func(p *api.ListBatchesParams) bool {
+		return p.Search != nil && *p.Search == `branch_id="`+branchID.String()+`"`
+	})).Return(&api.ListBatchesResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListBatchesOutput{Batches: &batches},
+	}, nil).Once()
+
+	count := streamBatches(projectID, batchListFilter{BranchID: branchID}, 0, func(batch api.Batch) {})
+	s.Equal(1, count)
+}
+
+func (s *CommandsSuite) TestStreamBatchesForTestSuiteFiltersLocally() {
+	projectID := uuid.New()
+	suiteID := uuid.New()
+	batches := []api.Batch{
+		{TestSuiteID: Ptr(suiteID), FriendlyName: Ptr("mine"), AssociatedAccount: "ci-bot"},
+		{TestSuiteID: Ptr(suiteID), FriendlyName: Ptr("theirs"), AssociatedAccount: "someone-else"},
+	}
+	s.mockClient.On("ListBatchesForTestSuiteWithResponse", matchContext, projectID, suiteID, mock.Anything).Return(
+		&api.ListBatchesForTestSuiteResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.ListBatchesOutput{Batches: &batches},
+		}, nil)
+
+	var names []string
+	count := streamBatches(projectID, batchListFilter{TestSuiteID: suiteID, Account: "ci-bot"}, 0, func(batch api.Batch) {
+		names = append(names, *batch.FriendlyName)
+	})
+	s.Equal(1, count)
+	s.Equal([]string{"mine"}, names)
+}
+
+func (s *CommandsSuite) TestBatchListTableWriter() {
+	var out bytes.Buffer
+	writer := newBatchListWriter(batchOutputTable, &out)
+	writer.write(api.Batch{
+		BatchID:           Ptr(uuid.New()),
+		FriendlyName:      Ptr("rejoicing-aquamarine-starfish"),
+		Status:            Ptr(api.BatchStatusSUCCEEDED),
+		ConflatedStatus:   Ptr(api.ConflatedBatchStatusWARNING),
+		CreationTimestamp: Ptr(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)),
+	})
+	writer.write(api.Batch{FriendlyName: Ptr("second")})
+	writer.close(2)
+
+	text := out.String()
+	s.Contains(text, "BATCH ID")
+	row := lineWith(text, "rejoicing-aquamarine-starfish")
+	s.Contains(row, "SUCCEEDED")
+	s.Contains(row, "WARNING")
+	s.Contains(row, "2026-01-02T15:04:05Z")
+	s.Contains(lineWith(text, "second"), "-")
+
+	out.Reset()
+	writer = newBatchListWriter(batchOutputTable, &out)
+	writer.close(0)
+	s.Equal("no batches\n", out.String())
+}
+
+func (s *CommandsSuite) TestBatchListJSONWriter() {
+	var out bytes.Buffer
+	writer := newBatchListWriter(batchOutputJSON, &out)
+	writer.write(api.Batch{FriendlyName: Ptr("a")})
+	writer.write(api.Batch{FriendlyName: Ptr("b")})
+	writer.close(2)
+
+	var batches []api.Batch
+	s.Require().NoError(json.Unmarshal(out.Bytes(), &batches))
+	s.Len(batches, 2)
+	s.Equal("b", *batches[1].FriendlyName)
+
+	out.Reset()
+	writer = newBatchListWriter(batchOutputJSON, &out)
+	writer.close(0)
+	s.Equal("[]\n", out.String())
+}
@@ -0,0 +1,516 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// batchMetricRulesKey is the --metric-rules flag shared by wait, watch and supervise, and
+// batchAllowMissingBaselineKey its opt-out for batches without a baseline.
+const (
+	batchMetricRulesKey          = "metric-rules"
+	batchAllowMissingBaselineKey = "allow-missing-baseline"
+)
+
+const metricRulesFlagDescription = "(Optional) Path to a YAML file of metric rules, e.g. maximum values or maximum increases compared with a baseline batch. The rules are checked once the batch finishes; if any is violated or they cannot be checked, a markdown table of violations is printed or the error logged, and an otherwise successful batch exits with code 9. Relative rules use the --baseline batch (auto if unset)."
+
+const allowMissingBaselineFlagDescription = "With --metric-rules, skip the relative rules instead of failing when no baseline batch is found."
+
+// metricRulesOutcome is the result of checking metric rules against a batch. The
+// outcomes are ordered so that the worst of several batches is the largest.
+type metricRulesOutcome int
+
+const (
+	metricRulesPassed metricRulesOutcome = iota
+	metricRulesViolated
+	// metricRulesUnevaluated means the rules could not be checked, e.g. because
+	// metrics could not be fetched or no baseline was found for relative rules.
+	metricRulesUnevaluated
+)
+
+// metricRulesConcurrency is how many tests' metrics are fetched at once when checking rules.
+const metricRulesConcurrency = 8
+
+const (
+	metricRuleScopeTest  = "test"
+	metricRuleScopeBatch = "batch"
+)
+
+// metricRule is one numeric check on a metric. Test-scoped rules are checked per test,
+// matched to the baseline by experience, unless an aggregate combines the values of
+// every test into one. Batch-scoped rules check a batch-level metric.
+type metricRule struct {
+	Name               string   `yaml:"name"`
+	Metric             string   `yaml:"metric"`
+	Scope              string   `yaml:"scope"`
+	Aggregate          string   `yaml:"aggregate"`
+	Min                *float64 `yaml:"min"`
+	Max                *float64 `yaml:"max"`
+	MaxIncrease        *float64 `yaml:"max_increase"`
+	MaxDecrease        *float64 `yaml:"max_decrease"`
+	MaxIncreasePercent *float64 `yaml:"max_increase_percent"`
+	MaxDecreasePercent *float64 `yaml:"max_decrease_percent"`
+}
+
+type metricRules struct {
+	Rules []metricRule `yaml:"rules"`
+	// allowMissingBaseline skips the relative rules when no baseline batch is found.
+	allowMissingBaseline bool
+}
+
+// label is how the rule is referred to in violations: its name, or its metric and aggregate.
+func (r metricRule) label() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if r.Aggregate != "" {
+		return r.Aggregate + " " + r.Metric
+	}
+	return r.Metric
+}
+
+// isRelative reports whether the rule needs a baseline batch.
+func (r metricRule) isRelative() bool {
+	return r.MaxIncrease != nil || r.MaxDecrease != nil || r.MaxIncreasePercent != nil || r.MaxDecreasePercent != nil
+}
+
+func (r metricRule) validate() error {
+	if r.Metric == "" {
+		return fmt.Errorf("metric is required")
+	}
+	switch r.Scope {
+	case "", metricRuleScopeTest, metricRuleScopeBatch:
+	default:
+		return fmt.Errorf("invalid scope %q: expected %s or %s", r.Scope, metricRuleScopeTest, metricRuleScopeBatch)
+	}
+	if r.Aggregate != "" {
+		if r.Scope == metricRuleScopeBatch {
+			return fmt.Errorf("aggregate is only valid for test-scoped rules")
+		}
+		if _, err := aggregateMetricValues(r.Aggregate, []float64{0}); err != nil {
+			return err
+		}
+	}
+	if r.Min == nil && r.Max == nil && !r.isRelative() {
+		return fmt.Errorf("at least one of min, max, max_increase, max_decrease, max_increase_percent or max_decrease_percent is required")
+	}
+	return nil
+}
+
+func (r metricRules) needsBaseline() bool {
+	for _, rule := range r.Rules {
+		if rule.isRelative() {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMetricRules decodes and validates a rules file. Unknown keys are rejected so a
+// misspelled threshold does not silently disable a check.
+func parseMetricRules(data []byte) (*metricRules, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var rules metricRules
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("unable to parse metric rules: %w", err)
+	}
+	if len(rules.Rules) == 0 {
+		return nil, fmt.Errorf("no rules found in metric rules")
+	}
+	for i, rule := range rules.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, rule.label(), err)
+		}
+	}
+	return &rules, nil
+}
+
+// getMetricRulesFlag loads the --metric-rules file, or returns nil if the flag is unset.
+// It is called before waiting so a bad file fails fast.
+func getMetricRulesFlag() *metricRules {
+	path := viper.GetString(batchMetricRulesKey)
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal("unable to read metric rules: ", err)
+	}
+	rules, err := parseMetricRules(data)
+	if err != nil {
+		log.Fatalf("%s: %v", path, err)
+	}
+	rules.allowMissingBaseline = viper.GetBool(batchAllowMissingBaselineKey)
+	return rules
+}
+
+// aggregateMetricValues combines values with min, max, mean, sum or a pNN percentile
+// (nearest rank).
+func aggregateMetricValues(aggregate string, values []float64) (float64, error) {
+	if len(values) == 0 {
+		return 0, fmt.Errorf("no values to aggregate")
+	}
+	switch aggregate {
+	case "min":
+		return sortedCopy(values)[0], nil
+	case "max":
+		sorted := sortedCopy(values)
+		return sorted[len(sorted)-1], nil
+	case "sum", "mean":
+		sum := 0.0
+		for _, value := range values {
+			sum += value
+		}
+		if aggregate == "mean" {
+			return sum / float64(len(values)), nil
+		}
+		return sum, nil
+	}
+	if strings.HasPrefix(aggregate, "p") {
+		if p, err := strconv.ParseFloat(aggregate[1:], 64); err == nil && p > 0 && p <= 100 {
+			sorted := sortedCopy(values)
+			rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+			return sorted[max(rank, 1)-1], nil
+		}
+	}
+	return 0, fmt.Errorf("invalid aggregate %q: expected min, max, mean, sum or a percentile such as p95", aggregate)
+}
+
+func sortedCopy(values []float64) []float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// metricRuleSubject is one value a rule is checked against: a test's value, an
+// aggregate over the tests, or a batch-level value.
+type metricRuleSubject struct {
+	Name     string
+	Value    float64
+	Baseline *float64
+}
+
+// metricRuleViolation is one failed check.
+type metricRuleViolation struct {
+	Rule      string   `json:"rule"`
+	Subject   string   `json:"subject"`
+	Value     float64  `json:"value"`
+	Baseline  *float64 `json:"baseline,omitempty"`
+	Threshold string   `json:"threshold"`
+}
+
+// metricRulesResult summarizes a rules check.
+type metricRulesResult struct {
+	BaselineBatchID *uuid.UUID
+	Checks          int
+	// Skipped counts relative checks that had no baseline value to compare with.
+	Skipped    int
+	Violations []metricRuleViolation
+}
+
+// metricRowKey matches a test's rows across batches: by experience, or by name for
+// rows without an experience ID.
+func metricRowKey(row metricExportRow) string {
+	if row.ExperienceID != nil {
+		return row.ExperienceID.String()
+	}
+	return row.ExperienceName
+}
+
+func metricRowSubjectName(row metricExportRow) string {
+	if row.ExperienceName != "" {
+		return row.ExperienceName
+	}
+	if row.TestID != nil {
+		return row.TestID.String()
+	}
+	return "batch"
+}
+
+// valuesByKey indexes the values of one metric by test key.
+func valuesByKey(rows []metricExportRow, metric string) map[string]float64 {
+	values := map[string]float64{}
+	for _, row := range rows {
+		if row.Name == metric && row.Value != nil {
+			values[metricRowKey(row)] = *row.Value
+		}
+	}
+	return values
+}
+
+// ruleSubjects returns the values rule is checked against. Baseline values are nil when
+// there is no baseline, or the baseline did not report the metric.
+func ruleSubjects(rule metricRule, current []metricExportRow, baseline []metricExportRow, hasBaseline bool) ([]metricRuleSubject, error) {
+	var subjects []metricRuleSubject
+	baselineValues := valuesByKey(baseline, rule.Metric)
+
+	if rule.Aggregate == "" {
+		for _, row := range current {
+			if row.Name != rule.Metric || row.Value == nil {
+				continue
+			}
+			subject := metricRuleSubject{Name: metricRowSubjectName(row), Value: *row.Value}
+			if value, ok := baselineValues[metricRowKey(row)]; ok && hasBaseline {
+				subject.Baseline = &value
+			}
+			subjects = append(subjects, subject)
+		}
+		return subjects, nil
+	}
+
+	var values []float64
+	for _, row := range current {
+		if row.Name == rule.Metric && row.Value != nil {
+			values = append(values, *row.Value)
+		}
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	value, err := aggregateMetricValues(rule.Aggregate, values)
+	if err != nil {
+		return nil, err
+	}
+	subject := metricRuleSubject{Name: fmt.Sprintf("%s of %s", rule.Aggregate, pluralize(len(values), "test")), Value: value}
+	if hasBaseline && len(baselineValues) > 0 {
+		var baseValues []float64
+		for _, v := range baselineValues {
+			baseValues = append(baseValues, v)
+		}
+		baseValue, err := aggregateMetricValues(rule.Aggregate, baseValues)
+		if err != nil {
+			return nil, err
+		}
+		subject.Baseline = &baseValue
+	}
+	return []metricRuleSubject{subject}, nil
+}
+
+func formatMetricNumber(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// checkMetricRuleSubject returns the thresholds of rule that subject violates, and how
+// many relative checks could not be made for lack of a baseline value.
+func checkMetricRuleSubject(rule metricRule, subject metricRuleSubject) (violated []string, checks int, skipped int) {
+	check := func(limit *float64, name string, failed func(limit float64) bool) {
+		if limit == nil {
+			return
+		}
+		checks++
+		if failed(*limit) {
+			violated = append(violated, name+" "+formatMetricNumber(*limit))
+		}
+	}
+	relative := func(limit *float64, name string, failed func(limit float64, base float64) bool) {
+		if limit == nil {
+			return
+		}
+		if subject.Baseline == nil {
+			skipped++
+			return
+		}
+		checks++
+		if failed(*limit, *subject.Baseline) {
+			violated = append(violated, name+" "+formatMetricNumber(*limit))
+		}
+	}
+
+	value := subject.Value
+	check(rule.Min, "min", func(limit float64) bool { return value < limit })
+	check(rule.Max, "max", func(limit float64) bool { return value > limit })
+	relative(rule.MaxIncrease, "max_increase", func(limit, base float64) bool { return value-base > limit })
+	relative(rule.MaxDecrease, "max_decrease", func(limit, base float64) bool { return base-value > limit })
+	// Percentage changes from a zero baseline are undefined; any change then counts
+	// as exceeding the limit.
+	relative(rule.MaxIncreasePercent, "max_increase_percent", func(limit, base float64) bool {
+		if base == 0 {
+			return value > 0
+		}
+		return (value-base)/math.Abs(base)*100 > limit
+	})
+	relative(rule.MaxDecreasePercent, "max_decrease_percent", func(limit, base float64) bool {
+		if base == 0 {
+			return value < 0
+		}
+		return (base-value)/math.Abs(base)*100 > limit
+	})
+	return violated, checks, skipped
+}
+
+// metricRulesInput holds the exported metric rows of a batch and, optionally, its baseline.
+type metricRulesInput struct {
+	TestRows          []metricExportRow
+	BatchRows         []metricExportRow
+	BaselineTestRows  []metricExportRow
+	BaselineBatchRows []metricExportRow
+	HasBaseline       bool
+}
+
+// evaluateMetricRules checks every rule. A metric that the batch does not report at all
+// is a violation, since a gate should not pass because a metric disappeared.
+func evaluateMetricRules(rules *metricRules, input metricRulesInput) (*metricRulesResult, error) {
+	result := &metricRulesResult{Violations: []metricRuleViolation{}}
+	for _, rule := range rules.Rules {
+		current, baseline := input.TestRows, input.BaselineTestRows
+		if rule.Scope == metricRuleScopeBatch {
+			current, baseline = input.BatchRows, input.BaselineBatchRows
+		}
+		subjects, err := ruleSubjects(rule, current, baseline, input.HasBaseline)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.label(), err)
+		}
+		if len(subjects) == 0 {
+			result.Checks++
+			result.Violations = append(result.Violations, metricRuleViolation{
+				Rule:      rule.label(),
+				Subject:   "-",
+				Value:     math.NaN(),
+				Threshold: "metric not reported",
+			})
+			continue
+		}
+		for _, subject := range subjects {
+			violated, checks, skipped := checkMetricRuleSubject(rule, subject)
+			result.Checks += checks
+			result.Skipped += skipped
+			if len(violated) > 0 {
+				result.Violations = append(result.Violations, metricRuleViolation{
+					Rule:      rule.label(),
+					Subject:   subject.Name,
+					Value:     subject.Value,
+					Baseline:  subject.Baseline,
+					Threshold: strings.Join(violated, ", "),
+				})
+			}
+		}
+	}
+	return result, nil
+}
+
+func formatViolationValue(value float64) string {
+	if math.IsNaN(value) {
+		return "-"
+	}
+	return formatMetricNumber(value)
+}
+
+// metricViolationsToMarkdown renders the violations as a markdown table.
+func metricViolationsToMarkdown(result *metricRulesResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** in %s", pluralize(len(result.Violations), "metric rule violation"), pluralize(result.Checks, "check"))
+	if result.BaselineBatchID != nil {
+		fmt.Fprintf(&b, " against baseline batch %s", result.BaselineBatchID)
+	}
+	b.WriteString(":\n\n")
+	b.WriteString("| Rule | Test | Value | Baseline | Threshold |\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, violation := range result.Violations {
+		baseline := "-"
+		if violation.Baseline != nil {
+			baseline = formatMetricNumber(*violation.Baseline)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+			escapeMarkdownTableCell(violation.Rule),
+			escapeMarkdownTableCell(violation.Subject),
+			formatViolationValue(violation.Value),
+			baseline,
+			violation.Threshold,
+		)
+	}
+	return b.String()
+}
+
+func escapeMarkdownTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// fetchMetricRulesRows fetches the test and batch metric rows of a batch for metric rules.
+func fetchMetricRulesRows(projectID uuid.UUID, batchID uuid.UUID) ([]metricExportRow, []metricExportRow, error) {
+	jobs, err := fetchAllJobs(projectID, batchID)
+	if err != nil {
+		return nil, nil, err
+	}
+	testRows, err := collectTestMetricRows(projectID, batchID, jobs, metricRulesConcurrency)
+	if err != nil {
+		return nil, nil, err
+	}
+	batchRows, err := batchMetricRows(projectID, batchID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return testRows, batchRows, nil
+}
+
+// checkMetricRules evaluates rules against a finished batch, printing a markdown table
+// of any violations to stdout. Metrics are fetched for the baseline only if a rule is
+// relative. Rules that cannot be checked fail the gate rather than pass it: errors
+// fetching metrics are logged and reported as metricRulesUnevaluated, as is a missing
+// baseline for relative rules unless the rules allow it.
+func checkMetricRules(projectID uuid.UUID, batch *api.Batch, mode baselineMode, rules *metricRules) metricRulesOutcome {
+	if rules == nil || batch == nil || batch.BatchID == nil {
+		return metricRulesPassed
+	}
+	input := metricRulesInput{}
+	var err error
+	input.TestRows, input.BatchRows, err = fetchMetricRulesRows(projectID, *batch.BatchID)
+	if err != nil {
+		log.Printf("Unable to fetch metrics for batch %s; metric rules could not be checked: %v\n", *batch.BatchID, err)
+		return metricRulesUnevaluated
+	}
+
+	var baselineID *uuid.UUID
+	if rules.needsBaseline() {
+		if mode == "" {
+			mode = baselineModeAuto
+		}
+		baseline, err := resolveBaselineBatch(projectID, batch, mode)
+		if err != nil {
+			log.Printf("Unable to find a %s baseline batch for batch %s; metric rules could not be checked: %v\n", mode, *batch.BatchID, err)
+			return metricRulesUnevaluated
+		}
+		if baseline == nil || baseline.BatchID == nil {
+			if !rules.allowMissingBaseline {
+				log.Printf("No %s baseline batch found for batch %s; relative metric rules could not be checked (pass --%s to skip them)\n", mode, *batch.BatchID, batchAllowMissingBaselineKey)
+				return metricRulesUnevaluated
+			}
+			log.Printf("No %s baseline batch found for batch %s; skipping relative metric rules\n", mode, *batch.BatchID)
+		} else {
+			input.BaselineTestRows, input.BaselineBatchRows, err = fetchMetricRulesRows(projectID, *baseline.BatchID)
+			if err != nil {
+				log.Printf("Unable to fetch metrics for baseline batch %s; metric rules could not be checked: %v\n", *baseline.BatchID, err)
+				return metricRulesUnevaluated
+			}
+			baselineID = baseline.BatchID
+			input.HasBaseline = true
+		}
+	}
+
+	result, err := evaluateMetricRules(rules, input)
+	if err != nil {
+		log.Printf("Unable to evaluate metric rules for batch %s: %v\n", *batch.BatchID, err)
+		return metricRulesUnevaluated
+	}
+	result.BaselineBatchID = baselineID
+	if result.Skipped > 0 {
+		log.Printf("Skipped %s without a baseline value\n", pluralize(result.Skipped, "relative metric check"))
+	}
+	if len(result.Violations) == 0 {
+		infoLog("All %s passed\n", pluralize(result.Checks, "metric rule check"))
+		return metricRulesPassed
+	}
+	fmt.Print(metricViolationsToMarkdown(result))
+	return metricRulesViolated
+}
@@ -0,0 +1,224 @@
+package commands
+
+import (
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/stretchr/testify/mock"
+)
+
+func metricRow(experienceID uuid.UUID, experience string, metric string, value float64) metricExportRow {
+	return metricExportRow{
+		ExperienceID:   Ptr(experienceID),
+		ExperienceName: experience,
+		Name:           metric,
+		Value:          Ptr(value),
+	}
+}
+
+func (s *CommandsSuite) TestParseMetricRules() {
+	rules, err := parseMetricRules([]byte(`
+rules:
+  - metric: collision_count
+    max_increase: 0
+  - name: planning latency
+    metric: planning_latency
+    aggregate: p95
+    max: 120
+  - metric: pass_rate
+    scope: batch
+    min: 0.9
+`))
+	s.Require().NoError(err)
+	s.Len(rules.Rules, 3)
+	s.True(rules.needsBaseline())
+	s.Equal("planning latency", rules.Rules[1].label())
+	s.Equal(120.0, *rules.Rules[1].Max)
+
+	_, err = parseMetricRules([]byte("rules:\n  - metric: x\n    max_increse: 0\n"))
+	s.ErrorContains(err, "max_increse")
+
+	_, err = parseMetricRules([]byte("rules:\n  - metric: x\n"))
+	s.ErrorContains(err, "rule 1 (x): at least one of min, max")
+
+	_, err = parseMetricRules([]byte("rules:\n  - metric: x\n    aggregate: median\n    max: 1\n"))
+	s.ErrorContains(err, `invalid aggregate "median"`)
+
+	_, err = parseMetricRules([]byte("rules:\n  - metric: x\n    scope: batch\n    aggregate: max\n    max: 1\n"))
+	s.ErrorContains(err, "aggregate is only valid for test-scoped rules")
+
+	_, err = parseMetricRules([]byte("rules: []\n"))
+	s.ErrorContains(err, "no rules found")
+}
+
+func (s *CommandsSuite) TestAggregateMetricValues() {
+	values := []float64{5, 1, 4, 2, 3, 10, 6, 9, 7, 8}
+	for aggregate, expected := range map[string]float64{
+		"min":  1,
+		"max":  10,
+		"sum":  55,
+		"mean": 5.5,
+		"p50":  5,
+		"p95":  10,
+		"p90":  9,
+		"p100": 10,
+	} {
+		value, err := aggregateMetricValues(aggregate, values)
+		s.NoError(err, aggregate)
+		s.Equal(expected, value, aggregate)
+	}
+	_, err := aggregateMetricValues("p0", values)
+	s.Error(err)
+}
+
+func (s *CommandsSuite) TestEvaluateMetricRules() {
+	laneChange, merge, cutIn := uuid.New(), uuid.New(), uuid.New()
+	rules, err := parseMetricRules([]byte(`
+rules:
+  - metric: collision_count
+    max_increase: 0
+  - metric: planning_latency
+    aggregate: p95
+    max: 120
+    max_increase_percent: 10
+  - metric: pass_rate
+    scope: batch
+    min: 0.9
+  - metric: comfort_score
+    min: 0
+`))
+	s.Require().NoError(err)
+
+	input := metricRulesInput{
+		TestRows: []metricExportRow{
+			metricRow(laneChange, "lane-change", "collision_count", 2),
+			metricRow(merge, "merge", "collision_count", 0),
+			metricRow(cutIn, "cut-in", "collision_count", 1),
+			metricRow(laneChange, "lane-change", "planning_latency", 100),
+			metricRow(merge, "merge", "planning_latency", 130),
+		},
+		BatchRows: []metricExportRow{{Name: "pass_rate", Value: Ptr(0.85)}},
+		BaselineTestRows: []metricExportRow{
+			metricRow(laneChange, "lane-change", "collision_count", 1),
+			metricRow(merge, "merge", "collision_count", 0),
+			metricRow(laneChange, "lane-change", "planning_latency", 100),
+			metricRow(merge, "merge", "planning_latency", 110),
+		},
+		HasBaseline: true,
+	}
+	result, err := evaluateMetricRules(rules, input)
+	s.Require().NoError(err)
+
+	// cut-in is new, so its collision_count increase cannot be checked.
+	s.Equal(1, result.Skipped)
+	s.Require().Len(result.Violations, 4)
+
+	collision := result.Violations[0]
+	s.Equal("collision_count", collision.Rule)
+	s.Equal("lane-change", collision.Subject)
+	s.Equal(2.0, collision.Value)
+	s.Equal(1.0, *collision.Baseline)
+	s.Equal("max_increase 0", collision.Threshold)
+
+	latency := result.Violations[1]
+	s.Equal("p95 planning_latency", latency.Rule)
+	s.Equal("p95 of 2 tests", latency.Subject)
+	s.Equal("max 120, max_increase_percent 10", latency.Threshold)
+
+	s.Equal("pass_rate", result.Violations[2].Rule)
+	s.Equal("min 0.9", result.Violations[2].Threshold)
+
+	// A metric that is not reported at all fails the gate.
+	s.Equal("comfort_score", result.Violations[3].Rule)
+	s.Equal("metric not reported", result.Violations[3].Threshold)
+	s.True(math.IsNaN(result.Violations[3].Value))
+
+	markdown := metricViolationsToMarkdown(result)
+	s.True(strings.HasPrefix(markdown, "**4 metric rule violations** in "))
+	s.Contains(markdown, "| Rule | Test | Value | Baseline | Threshold |")
+	s.Contains(markdown, "| collision_count | lane-change | 2 | 1 | max_increase 0 |")
+	s.Contains(markdown, "| comfort_score | - | - | - | metric not reported |")
+}
+
+func (s *CommandsSuite) TestEvaluateMetricRulesWithoutBaseline() {
+	rules, err := parseMetricRules([]byte("rules:\n  - metric: collision_count\n    max: 3\n    max_increase: 0\n"))
+	s.Require().NoError(err)
+	input := metricRulesInput{
+		TestRows:         []metricExportRow{metricRow(uuid.New(), "lane-change", "collision_count", 2)},
+		BaselineTestRows: []metricExportRow{metricRow(uuid.New(), "lane-change", "collision_count", 0)},
+	}
+	result, err := evaluateMetricRules(rules, input)
+	s.Require().NoError(err)
+	s.Empty(result.Violations)
+	s.Equal(1, result.Checks)
+	s.Equal(1, result.Skipped)
+}
+
+func (s *CommandsSuite) TestCheckMetricRulesFailsOnAPIErrors() {
+	projectID := uuid.New()
+	batch := &api.Batch{BatchID: Ptr(uuid.New())}
+	rules, err := parseMetricRules([]byte("rules:\n  - metric: collision_count\n    max: 3\n"))
+	s.Require().NoError(err)
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, *batch.BatchID, mock.Anything).Return(
+		&api.ListJobsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusInternalServerError},
+		}, nil).Once()
+
+	// Rules that could not be checked fail the gate instead of passing it.
+	s.Equal(metricRulesUnevaluated, checkMetricRules(projectID, batch, "", rules))
+}
+
+func (s *CommandsSuite) TestCheckMetricRulesWithoutBaseline() {
+	projectID := uuid.New()
+	batch := &api.Batch{BatchID: Ptr(uuid.New())}
+	rules, err := parseMetricRules([]byte("rules:\n  - metric: pass-rate\n    scope: batch\n    min: 0.5\n    max_decrease: 0.1\n"))
+	s.Require().NoError(err)
+	passRate := api.BatchMetric{MetricID: Ptr(uuid.New()), Name: Ptr("pass-rate"), Type: Ptr(api.SCALAR), Value: Ptr(0.75)}
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, *batch.BatchID, mock.Anything).Return(
+		&api.ListJobsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.ListJobsOutput{Jobs: &[]api.Job{}},
+		}, nil)
+	s.mockClient.On("ListBatchMetricsWithResponse", matchContext, projectID, *batch.BatchID, mock.Anything).Return(
+		&api.ListBatchMetricsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.ListBatchMetricsOutput{BatchMetrics: &[]api.BatchMetric{passRate}},
+		}, nil)
+	s.mockClient.On("ListTagsForBatchMetricsWithResponse", matchContext, projectID, *batch.BatchID, []uuid.UUID{*passRate.MetricID}, mock.Anything).Return(
+		&api.ListTagsForBatchMetricsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.ListTagsForBatchMetricsOutput{},
+		}, nil)
+	s.mockClient.On("GetBatchSuggestionsWithResponse", matchContext, projectID, *batch.BatchID).Return(
+		&api.GetBatchSuggestionsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.BatchSuggestionsOutput{},
+		}, nil)
+
+	// Relative rules without a baseline fail the gate unless the user opts out.
+	s.Equal(metricRulesUnevaluated, checkMetricRules(projectID, batch, "", rules))
+
+	rules.allowMissingBaseline = true
+	s.Equal(metricRulesPassed, checkMetricRules(projectID, batch, "", rules))
+}
+
+func (s *CommandsSuite) TestComputeExitCode_MetricRulesFailed() {
+	succeeded := []*SuperviseResult{{Batch: &api.Batch{Status: Ptr(api.BatchStatusSUCCEEDED)}}}
+	s.Equal(exitCodeMetricRules, computeExitCode(succeeded, exitCodeOptions{metricRules: metricRulesViolated}))
+	s.Equal(exitCodeMetricRules, computeExitCode(succeeded, exitCodeOptions{metricRules: metricRulesUnevaluated}))
+	s.Equal(exitCodeSucceeded, computeExitCode(succeeded, exitCodeOptions{metricRules: metricRulesPassed}))
+
+	// Batch failures take precedence over metric rules.
+	errored := []*SuperviseResult{{Batch: &api.Batch{Status: Ptr(api.BatchStatusERROR)}}}
+	s.Equal(exitCodeError, computeExitCode(errored, exitCodeOptions{metricRules: metricRulesViolated}))
+
+	blocker := []*SuperviseResult{{Batch: &api.Batch{Status: Ptr(api.BatchStatusSUCCEEDED), ConflatedStatus: Ptr(api.ConflatedBatchStatusBLOCKER)}}}
+	s.Equal(exitCodeBlocker, computeExitCode(blocker, exitCodeOptions{
+		failOnStates: []api.ConflatedBatchStatus{api.ConflatedBatchStatusBLOCKER},
+		metricRules:  metricRulesUnevaluated,
+	}))
+}
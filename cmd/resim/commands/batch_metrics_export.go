@@ -0,0 +1,508 @@
+package commands
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	metricsBatchCmd = &cobra.Command{
+		Use:   "metrics",
+		Short: "metrics contains commands for working with the metrics of a batch",
+		Long:  ``,
+	}
+
+	exportMetricsBatchCmd = &cobra.Command{
+		Use:   "export",
+		Short: "export - Exports the scalar metrics of a batch and its tests to CSV or JSON Lines",
+		Long: `export - Exports the scalar metrics of a batch and its tests for offline analysis.
+
+Writes two files to the --out directory: test_metrics.<ext>, with one row per test and
+metric, and batch_metrics.<ext>, with one row per batch-level metric. Each row includes
+the metric's status, value, unit and tags. Composite metrics are not exported.
+
+The tests of the batch are fetched concurrently, up to --concurrency at a time.`,
+		Run: exportBatchMetrics,
+	}
+)
+
+const (
+	batchMetricsFormatKey      = "format"
+	batchMetricsOutKey         = "out"
+	batchMetricsConcurrencyKey = "concurrency"
+)
+
+const (
+	metricsFormatCSV   = "csv"
+	metricsFormatJSONL = "jsonl"
+)
+
+// metricTagsChunkSize caps how many metric IDs are sent in the path of one tag request.
+const metricTagsChunkSize = 50
+
+func init() {
+	exportMetricsBatchCmd.Flags().String(batchProjectKey, "", "The name or ID of the project the batch is associated with")
+	exportMetricsBatchCmd.MarkFlagRequired(batchProjectKey)
+	exportMetricsBatchCmd.Flags().String(batchIDKey, "", "The ID of the batch to export metrics for.")
+	exportMetricsBatchCmd.Flags().String(batchNameKey, "", "The name of the batch to export metrics for (e.g. rejoicing-aquamarine-starfish). If the name is not unique, this exports the most recent batch with that name.")
+	exportMetricsBatchCmd.MarkFlagsMutuallyExclusive(batchIDKey, batchNameKey)
+	exportMetricsBatchCmd.MarkFlagsOneRequired(batchIDKey, batchNameKey)
+	exportMetricsBatchCmd.Flags().String(batchMetricsFormatKey, metricsFormatCSV, "Output format: csv or jsonl")
+	exportMetricsBatchCmd.Flags().String(batchMetricsOutKey, "", "The directory to write the exported files to. It is created if it does not exist.")
+	exportMetricsBatchCmd.MarkFlagRequired(batchMetricsOutKey)
+	exportMetricsBatchCmd.Flags().Int(batchMetricsConcurrencyKey, 8, "The number of tests to fetch metrics for at once")
+	exportMetricsBatchCmd.Flags().SetNormalizeFunc(aliasProjectNameFunc)
+	metricsBatchCmd.AddCommand(exportMetricsBatchCmd)
+	batchCmd.AddCommand(metricsBatchCmd)
+}
+
+// metricExportRow is one exported scalar metric. Test fields are empty for batch-level metrics.
+type metricExportRow struct {
+	BatchID        uuid.UUID         `json:"batchID"`
+	TestID         *uuid.UUID        `json:"testID,omitempty"`
+	ExperienceID   *uuid.UUID        `json:"experienceID,omitempty"`
+	ExperienceName string            `json:"experienceName,omitempty"`
+	MetricID       uuid.UUID         `json:"metricID"`
+	Name           string            `json:"name"`
+	Status         string            `json:"status"`
+	Value          *float64          `json:"value"`
+	Unit           string            `json:"unit,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+var (
+	testMetricColumns  = []string{"batch_id", "test_id", "experience_id", "experience_name", "metric_id", "metric_name", "status", "value", "unit", "tags"}
+	batchMetricColumns = []string{"batch_id", "metric_id", "metric_name", "status", "value", "unit", "tags"}
+)
+
+// formatMetricTags renders tags as name=value pairs sorted by name and separated by
+// semicolons, for a single CSV column.
+func formatMetricTags(tags map[string]string) string {
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+"="+tags[name])
+	}
+	return strings.Join(pairs, ";")
+}
+
+func (r metricExportRow) csvRecord(perTest bool) []string {
+	value := ""
+	if r.Value != nil {
+		value = strconv.FormatFloat(*r.Value, 'g', -1, 64)
+	}
+	tail := []string{r.MetricID.String(), r.Name, r.Status, value, r.Unit, formatMetricTags(r.Tags)}
+	if !perTest {
+		return append([]string{r.BatchID.String()}, tail...)
+	}
+	testID, experienceID := "", ""
+	if r.TestID != nil {
+		testID = r.TestID.String()
+	}
+	if r.ExperienceID != nil {
+		experienceID = r.ExperienceID.String()
+	}
+	return append([]string{r.BatchID.String(), testID, experienceID, r.ExperienceName}, tail...)
+}
+
+// metricExportWriter writes rows in one of the export formats.
+type metricExportWriter interface {
+	write(row metricExportRow) error
+	close() error
+}
+
+type csvMetricExportWriter struct {
+	w       *csv.Writer
+	perTest bool
+}
+
+func (c *csvMetricExportWriter) write(row metricExportRow) error {
+	return c.w.Write(row.csvRecord(c.perTest))
+}
+
+func (c *csvMetricExportWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlMetricExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (j *jsonlMetricExportWriter) write(row metricExportRow) error {
+	return j.encoder.Encode(row)
+}
+
+func (j *jsonlMetricExportWriter) close() error {
+	return nil
+}
+
+// newMetricExportWriter returns a writer for format; perTest selects the test or the
+// batch CSV columns and writes the header.
+func newMetricExportWriter(format string, out io.Writer, perTest bool) (metricExportWriter, error) {
+	if format == metricsFormatJSONL {
+		return &jsonlMetricExportWriter{encoder: json.NewEncoder(out)}, nil
+	}
+	w := csv.NewWriter(out)
+	columns := batchMetricColumns
+	if perTest {
+		columns = testMetricColumns
+	}
+	if err := w.Write(columns); err != nil {
+		return nil, err
+	}
+	return &csvMetricExportWriter{w: w, perTest: perTest}, nil
+}
+
+// isScalarMetric reports whether the metric has a single value to export. Metrics
+// without a type are treated as scalar if they have a value.
+func isScalarMetric(metricType *api.MetricType, value *float64) bool {
+	if metricType != nil {
+		return *metricType == api.SCALAR
+	}
+	return value != nil
+}
+
+func fetchJobMetrics(projectID uuid.UUID, batchID uuid.UUID, jobID uuid.UUID) ([]api.JobMetric, error) {
+	var metrics []api.JobMetric
+	var pageToken *string = nil
+	for {
+		response, err := Client.ListMetricsForJobWithResponse(context.Background(), projectID, batchID, jobID, &api.ListMetricsForJobParams{
+			PageSize:  Ptr(100),
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+			return nil, fmt.Errorf("unexpected response listing metrics for test %s: %s", jobID, response.Status())
+		}
+		if response.JSON200.Metrics != nil {
+			metrics = append(metrics, *response.JSON200.Metrics...)
+		}
+		if response.JSON200.NextPageToken != nil && *response.JSON200.NextPageToken != "" {
+			pageToken = response.JSON200.NextPageToken
+		} else {
+			break
+		}
+	}
+	return metrics, nil
+}
+
+func fetchBatchMetrics(projectID uuid.UUID, batchID uuid.UUID) ([]api.BatchMetric, error) {
+	var metrics []api.BatchMetric
+	var pageToken *string = nil
+	for {
+		response, err := Client.ListBatchMetricsWithResponse(context.Background(), projectID, batchID, &api.ListBatchMetricsParams{
+			PageSize:  Ptr(100),
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+			return nil, fmt.Errorf("unexpected response listing batch metrics: %s", response.Status())
+		}
+		if response.JSON200.BatchMetrics != nil {
+			metrics = append(metrics, *response.JSON200.BatchMetrics...)
+		}
+		if response.JSON200.NextPageToken != nil && *response.JSON200.NextPageToken != "" {
+			pageToken = response.JSON200.NextPageToken
+		} else {
+			break
+		}
+	}
+	return metrics, nil
+}
+
+// metricTagsPage fetches one page of tags for a chunk of metric IDs.
+type metricTagsPage func(metricIDs []uuid.UUID, pageToken *string) (tags []api.MetricTag, nextPageToken *string, err error)
+
+// fetchMetricTags collects the tags of the given metrics, keyed by metric ID and then by
+// tag name. Metric IDs are sent in chunks since they are part of the request path.
+func fetchMetricTags(metricIDs []uuid.UUID, fetchPage metricTagsPage) (map[uuid.UUID]map[string]string, error) {
+	tags := map[uuid.UUID]map[string]string{}
+	for start := 0; start < len(metricIDs); start += metricTagsChunkSize {
+		chunk := metricIDs[start:min(start+metricTagsChunkSize, len(metricIDs))]
+		var pageToken *string = nil
+		for {
+			page, next, err := fetchPage(chunk, pageToken)
+			if err != nil {
+				return nil, err
+			}
+			for _, tag := range page {
+				if tag.MetricID == nil {
+					continue
+				}
+				if tags[*tag.MetricID] == nil {
+					tags[*tag.MetricID] = map[string]string{}
+				}
+				tags[*tag.MetricID][tag.Name] = tag.Value
+			}
+			if next != nil && *next != "" {
+				pageToken = next
+			} else {
+				break
+			}
+		}
+	}
+	return tags, nil
+}
+
+func jobMetricTagsPage(projectID uuid.UUID, batchID uuid.UUID, jobID uuid.UUID) metricTagsPage {
+	return func(metricIDs []uuid.UUID, pageToken *string) ([]api.MetricTag, *string, error) {
+		response, err := Client.ListTagsForJobMetricsWithResponse(context.Background(), projectID, batchID, jobID, metricIDs, &api.ListTagsForJobMetricsParams{
+			PageSize:  Ptr(100),
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+			return nil, nil, fmt.Errorf("unexpected response listing metric tags for test %s: %s", jobID, response.Status())
+		}
+		if response.JSON200.Tags == nil {
+			return nil, response.JSON200.NextPageToken, nil
+		}
+		return *response.JSON200.Tags, response.JSON200.NextPageToken, nil
+	}
+}
+
+func batchMetricTagsPage(projectID uuid.UUID, batchID uuid.UUID) metricTagsPage {
+	return func(metricIDs []uuid.UUID, pageToken *string) ([]api.MetricTag, *string, error) {
+		response, err := Client.ListTagsForBatchMetricsWithResponse(context.Background(), projectID, batchID, metricIDs, &api.ListTagsForBatchMetricsParams{
+			PageSize:  Ptr(100),
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+			return nil, nil, fmt.Errorf("unexpected response listing batch metric tags: %s", response.Status())
+		}
+		if response.JSON200.Tags == nil {
+			return nil, response.JSON200.NextPageToken, nil
+		}
+		return *response.JSON200.Tags, response.JSON200.NextPageToken, nil
+	}
+}
+
+func metricStatusString(status *api.MetricStatus) string {
+	if status == nil {
+		return string(api.NOSTATUSREPORTED)
+	}
+	return string(*status)
+}
+
+// testMetricRows flattens the scalar metrics of one test into rows.
+func testMetricRows(projectID uuid.UUID, batchID uuid.UUID, job api.Job) ([]metricExportRow, error) {
+	metrics, err := fetchJobMetrics(projectID, batchID, *job.JobID)
+	if err != nil {
+		return nil, err
+	}
+	var scalars []api.JobMetric
+	var metricIDs []uuid.UUID
+	for _, metric := range metrics {
+		if metric.MetricID != nil && isScalarMetric(metric.Type, metric.Value) {
+			scalars = append(scalars, metric)
+			metricIDs = append(metricIDs, *metric.MetricID)
+		}
+	}
+	tags, err := fetchMetricTags(metricIDs, jobMetricTagsPage(projectID, batchID, *job.JobID))
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]metricExportRow, 0, len(scalars))
+	for _, metric := range scalars {
+		row := metricExportRow{
+			BatchID:      batchID,
+			TestID:       job.JobID,
+			ExperienceID: job.ExperienceID,
+			MetricID:     *metric.MetricID,
+			Status:       metricStatusString(metric.Status),
+			Value:        metric.Value,
+			Tags:         tags[*metric.MetricID],
+		}
+		if job.ExperienceName != nil {
+			row.ExperienceName = *job.ExperienceName
+		}
+		if metric.Name != nil {
+			row.Name = *metric.Name
+		}
+		if metric.Unit != nil {
+			row.Unit = *metric.Unit
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// batchMetricRows flattens the scalar batch-level metrics into rows.
+func batchMetricRows(projectID uuid.UUID, batchID uuid.UUID) ([]metricExportRow, error) {
+	metrics, err := fetchBatchMetrics(projectID, batchID)
+	if err != nil {
+		return nil, err
+	}
+	var scalars []api.BatchMetric
+	var metricIDs []uuid.UUID
+	for _, metric := range metrics {
+		if metric.MetricID != nil && isScalarMetric(metric.Type, metric.Value) {
+			scalars = append(scalars, metric)
+			metricIDs = append(metricIDs, *metric.MetricID)
+		}
+	}
+	tags, err := fetchMetricTags(metricIDs, batchMetricTagsPage(projectID, batchID))
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]metricExportRow, 0, len(scalars))
+	for _, metric := range scalars {
+		row := metricExportRow{
+			BatchID:  batchID,
+			MetricID: *metric.MetricID,
+			Status:   metricStatusString(metric.Status),
+			Value:    metric.Value,
+			Tags:     tags[*metric.MetricID],
+		}
+		if metric.Name != nil {
+			row.Name = *metric.Name
+		}
+		if metric.Unit != nil {
+			row.Unit = *metric.Unit
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// collectTestMetricRows fetches the metric rows of every test with a pool of workers.
+// Rows are returned in the order of jobs, so exports are stable regardless of which
+// worker finishes first. The first error stops workers from starting new tests.
+func collectTestMetricRows(projectID uuid.UUID, batchID uuid.UUID, jobs []api.Job, workers int) ([]metricExportRow, error) {
+	results := make([][]metricExportRow, len(jobs))
+	indexes := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				mu.Lock()
+				failed := firstErr != nil
+				mu.Unlock()
+				if failed || jobs[i].JobID == nil {
+					continue
+				}
+				rows, err := testMetricRows(projectID, batchID, jobs[i])
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				results[i] = rows
+			}
+		}()
+	}
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	var rows []metricExportRow
+	for _, jobRows := range results {
+		rows = append(rows, jobRows...)
+	}
+	return rows, nil
+}
+
+// writeMetricExport writes rows to path in format, with the test or batch columns.
+func writeMetricExport(path string, format string, perTest bool, rows []metricExportRow) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer, err := newMetricExportWriter(format, file, perTest)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := writer.write(row); err != nil {
+			return err
+		}
+	}
+	if err := writer.close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func exportBatchMetrics(ccmd *cobra.Command, args []string) {
+	projectID := getProjectID(Client, viper.GetString(batchProjectKey))
+	format := viper.GetString(batchMetricsFormatKey)
+	if format != metricsFormatCSV && format != metricsFormatJSONL {
+		log.Fatalf("Unsupported format: %s. Valid formats are: %s, %s", format, metricsFormatCSV, metricsFormatJSONL)
+	}
+	concurrency := viper.GetInt(batchMetricsConcurrencyKey)
+	if concurrency < 1 {
+		log.Fatal("--concurrency must be at least 1")
+	}
+	outDir := viper.GetString(batchMetricsOutKey)
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		log.Fatal("unable to create output directory:", err)
+	}
+	batch := actualGetBatch(projectID, viper.GetString(batchIDKey), viper.GetString(batchNameKey))
+	batchID := *batch.BatchID
+
+	jobs := getAllJobs(projectID, batchID)
+	testRows, err := collectTestMetricRows(projectID, batchID, jobs, concurrency)
+	if err != nil {
+		log.Fatal("unable to export test metrics: ", err)
+	}
+	batchRows, err := batchMetricRows(projectID, batchID)
+	if err != nil {
+		log.Fatal("unable to export batch metrics: ", err)
+	}
+
+	testPath := filepath.Join(outDir, "test_metrics."+format)
+	if err := writeMetricExport(testPath, format, true, testRows); err != nil {
+		log.Fatal("unable to write test metrics: ", err)
+	}
+	batchPath := filepath.Join(outDir, "batch_metrics."+format)
+	if err := writeMetricExport(batchPath, format, false, batchRows); err != nil {
+		log.Fatal("unable to write batch metrics: ", err)
+	}
+	infoLog("Exported %s from %s to %s\n", pluralize(len(testRows), "test metric"), pluralize(len(jobs), "test"), testPath)
+	infoLog("Exported %s to %s\n", pluralize(len(batchRows), "batch metric"), batchPath)
+}
@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/stretchr/testify/mock"
+)
+
+func scalarJobMetric(name string, value float64, status api.MetricStatus) api.JobMetric {
+	return api.JobMetric{
+		MetricID: Ptr(uuid.New()),
+		Name:     Ptr(name),
+		Type:     Ptr(api.SCALAR),
+		Value:    Ptr(value),
+		Status:   Ptr(status),
+		Unit:     Ptr("m"),
+	}
+}
+
+func listMetricsForJobResponse(metrics ...api.JobMetric) *api.ListMetricsForJobResponse {
+	return &api.ListMetricsForJobResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListJobMetricsOutput{Metrics: &metrics},
+	}
+}
+
+func (s *CommandsSuite) TestCollectTestMetricRows() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	jobs := []api.Job{
+		{JobID: Ptr(uuid.New()), ExperienceID: Ptr(uuid.New()), ExperienceName: Ptr("lane-change")},
+		{JobID: Ptr(uuid.New()), ExperienceID: Ptr(uuid.New()), ExperienceName: Ptr("merge")},
+		{JobID: Ptr(uuid.New()), ExperienceID: Ptr(uuid.New()), ExperienceName: Ptr("no-metrics")},
+	}
+	distance := scalarJobMetric("min-distance", 1.5, api.FAILBLOCK)
+	composite := api.JobMetric{MetricID: Ptr(uuid.New()), Name: Ptr("overview"), Type: Ptr(api.COMPOSITE)}
+	speed := scalarJobMetric("max-speed", 12, api.PASSED)
+
+	s.mockClient.On("ListMetricsForJobWithResponse", matchContext, projectID, batchID, *jobs[0].JobID, mock.Anything).Return(
+		listMetricsForJobResponse(distance, composite), nil).Once()
+	s.mockClient.On("ListMetricsForJobWithResponse", matchContext, projectID, batchID, *jobs[1].JobID, mock.Anything).Return(
+		listMetricsForJobResponse(speed), nil).Once()
+	s.mockClient.On("ListMetricsForJobWithResponse", matchContext, projectID, batchID, *jobs[2].JobID, mock.Anything).Return(
+		listMetricsForJobResponse(), nil).Once()
+
+	// Tags are only requested for the scalar metrics.
+	s.mockClient.On("ListTagsForJobMetricsWithResponse", matchContext, projectID, batchID, *jobs[0].JobID, []uuid.UUID{*distance.MetricID}, mock.Anything).Return(
+		&api.ListTagsForJobMetricsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200: &api.ListTagsForJobMetricsOutput{Tags: &[]api.MetricTag{
+				{MetricID: distance.MetricID, Name: "category", Value: "safety"},
+				{MetricID: distance.MetricID, Name: "owner", Value: "planning"},
+			}},
+		}, nil).Once()
+	s.mockClient.On("ListTagsForJobMetricsWithResponse", matchContext, projectID, batchID, *jobs[1].JobID, []uuid.UUID{*speed.MetricID}, mock.Anything).Return(
+		&api.ListTagsForJobMetricsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.ListTagsForJobMetricsOutput{},
+		}, nil).Once()
+
+	rows, err := collectTestMetricRows(projectID, batchID, jobs, 2)
+	s.Require().NoError(err)
+	s.Require().Len(rows, 2)
+	// Rows follow the order of the tests regardless of which worker finished first.
+	s.Equal("lane-change", rows[0].ExperienceName)
+	s.Equal("min-distance", rows[0].Name)
+	s.Equal("FAIL_BLOCK", rows[0].Status)
+	s.Equal(map[string]string{"category": "safety", "owner": "planning"}, rows[0].Tags)
+	s.Equal(*jobs[0].ExperienceID, *rows[0].ExperienceID)
+	s.Equal("merge", rows[1].ExperienceName)
+	s.Nil(rows[1].Tags)
+}
+
+func (s *CommandsSuite) TestCollectTestMetricRowsReturnsFirstError() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	jobs := []api.Job{{JobID: Ptr(uuid.New())}}
+	s.mockClient.On("ListMetricsForJobWithResponse", matchContext, projectID, batchID, *jobs[0].JobID, mock.Anything).Return(
+		&api.ListMetricsForJobResponse{HTTPResponse: &http.Response{StatusCode: http.StatusInternalServerError, Status: "500 Internal Server Error"}}, nil).Once()
+
+	_, err := collectTestMetricRows(projectID, batchID, jobs, 4)
+	s.ErrorContains(err, "500 Internal Server Error")
+}
+
+func (s *CommandsSuite) TestBatchMetricRows() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	passRate := api.BatchMetric{MetricID: Ptr(uuid.New()), Name: Ptr("pass-rate"), Type: Ptr(api.SCALAR), Value: Ptr(0.75)}
+	s.mockClient.On("ListBatchMetricsWithResponse", matchContext, projectID, batchID, mock.Anything).Return(
+		&api.ListBatchMetricsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.ListBatchMetricsOutput{BatchMetrics: &[]api.BatchMetric{passRate, {MetricID: Ptr(uuid.New()), Type: Ptr(api.COMPOSITE)}}},
+		}, nil).Once()
+	s.mockClient.On("ListTagsForBatchMetricsWithResponse", matchContext, projectID, batchID, []uuid.UUID{*passRate.MetricID}, mock.Anything).Return(
+		&api.ListTagsForBatchMetricsResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.ListTagsForBatchMetricsOutput{Tags: &[]api.MetricTag{{MetricID: passRate.MetricID, Name: "kind", Value: "summary"}}},
+		}, nil).Once()
+
+	rows, err := batchMetricRows(projectID, batchID)
+	s.Require().NoError(err)
+	s.Require().Len(rows, 1)
+	s.Equal("pass-rate", rows[0].Name)
+	s.Equal("NO_STATUS_REPORTED", rows[0].Status)
+	s.Nil(rows[0].TestID)
+	s.Equal("summary", rows[0].Tags["kind"])
+}
+
+func (s *CommandsSuite) TestFetchMetricTagsChunksMetricIDs() {
+	var metricIDs []uuid.UUID
+	for range 120 {
+		metricIDs = append(metricIDs, uuid.New())
+	}
+	var chunkSizes []int
+	tags, err := fetchMetricTags(metricIDs, func(ids []uuid.UUID, pageToken *string) ([]api.MetricTag, *string, error) {
+		chunkSizes = append(chunkSizes, len(ids))
+		return []api.MetricTag{{MetricID: Ptr(ids[0]), Name: "chunk", Value: "first"}}, nil, nil
+	})
+	s.Require().NoError(err)
+	s.Equal([]int{50, 50, 20}, chunkSizes)
+	s.Len(tags, 3)
+	s.Equal("first", tags[metricIDs[50]]["chunk"])
+}
+
+func (s *CommandsSuite) TestWriteMetricExport() {
+	dir := s.T().TempDir()
+	testID := uuid.New()
+	row := metricExportRow{
+		BatchID:        uuid.New(),
+		TestID:         Ptr(testID),
+		ExperienceName: "lane-change",
+		MetricID:       uuid.New(),
+		Name:           "min-distance",
+		Status:         "PASSED",
+		Value:          Ptr(1.5),
+		Unit:           "m",
+		Tags:           map[string]string{"owner": "planning", "category": "safety"},
+	}
+
+	csvPath := filepath.Join(dir, "test_metrics.csv")
+	s.Require().NoError(writeMetricExport(csvPath, metricsFormatCSV, true, []metricExportRow{row}))
+	file, err := os.Open(csvPath)
+	s.Require().NoError(err)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	s.Require().NoError(err)
+	s.Require().Len(records, 2)
+	s.Equal(testMetricColumns, records[0])
+	s.Equal(testID.String(), records[1][1])
+	s.Equal("", records[1][2])
+	s.Equal("1.5", records[1][7])
+	s.Equal("category=safety;owner=planning", records[1][9])
+
+	batchPath := filepath.Join(dir, "batch_metrics.csv")
+	s.Require().NoError(writeMetricExport(batchPath, metricsFormatCSV, false, nil))
+	data, err := os.ReadFile(batchPath)
+	s.Require().NoError(err)
+	s.Equal(strings.Join(batchMetricColumns, ",")+"\n", string(data))
+
+	jsonlPath := filepath.Join(dir, "test_metrics.jsonl")
+	s.Require().NoError(writeMetricExport(jsonlPath, metricsFormatJSONL, true, []metricExportRow{row, row}))
+	data, err = os.ReadFile(jsonlPath)
+	s.Require().NoError(err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	s.Require().Len(lines, 2)
+	var decoded map[string]any
+	s.Require().NoError(json.Unmarshal([]byte(lines[0]), &decoded))
+	s.Equal("min-distance", decoded["name"])
+	s.Equal(1.5, decoded["value"])
+	s.Equal(map[string]any{"owner": "planning", "category": "safety"}, decoded["tags"])
+}
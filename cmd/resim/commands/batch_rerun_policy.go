@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/cmd/resim/commands/utils"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	batchRerunDelayKey        = "rerun-delay"
+	batchRerunBackoffKey      = "rerun-backoff"
+	batchMaxRerunFractionKey  = "max-rerun-fraction"
+	batchRerunTagsKey         = "rerun-experience-tags"
+	batchMetricsOnlyRerunsKey = "metrics-only-reruns"
+)
+
+// RerunPolicy refines which failed tests supervise reruns, and when. The zero
+// policy reruns every failed test immediately.
+type RerunPolicy struct {
+	// Delay is the wait before the first rerun; each later rerun waits Backoff
+	// times longer than the previous one.
+	Delay   time.Duration
+	Backoff float64
+	// MaxRerunFraction caps the fraction of a batch's tests one attempt may
+	// rerun, counting only the failed tests left after the experience tag
+	// filter. When more tests need a rerun, the failure is treated as real and
+	// nothing is rerun. 0 means no cap.
+	MaxRerunFraction float64
+	// ExperienceTagIDs, if set, limits reruns to tests of experiences with one
+	// of the tags.
+	ExperienceTagIDs []uuid.UUID
+	// MetricsOnly reruns only the metrics of the tests when all of them failed
+	// in the metrics stage.
+	MetricsOnly bool
+}
+
+// rerunPlan is what one supervise attempt reruns.
+type rerunPlan struct {
+	JobIDs      []uuid.UUID
+	MetricsOnly bool
+}
+
+// addRerunPolicyFlags registers the rerun policy flags read by getRerunPolicy.
+func addRerunPolicyFlags(cmd *cobra.Command) {
+	cmd.Flags().String(batchRerunDelayKey, "0s", "Amount of time to wait before the first rerun, expressed in Golang duration string.")
+	cmd.Flags().Float64(batchRerunBackoffKey, 2, "Factor by which the wait grows before each later rerun (at least 1). Only used with --rerun-delay.")
+	cmd.Flags().Float64(batchMaxRerunFractionKey, 0, "(Optional) Maximum fraction (0-1) of a batch's tests one attempt may rerun, counting only the failed tests left after --rerun-experience-tags. Checked after --rerun-max-failure-percent, which counts every failed test. When more tests need a rerun, the failure is treated as real and nothing is rerun. 0 means no cap.")
+	cmd.Flags().String(batchRerunTagsKey, "", "(Optional) Comma-separated list of experience tag names or IDs. Only tests of experiences with one of these tags are rerun, e.g. rerunnable.")
+	cmd.Flags().Bool(batchMetricsOnlyRerunsKey, false, "When every test to rerun errored in the metrics stage, rerun only their metrics instead of the whole tests.")
+}
+
+// getRerunPolicy reads the rerun policy flags of batch supervise.
+func getRerunPolicy(projectID uuid.UUID) (RerunPolicy, error) {
+	policy := RerunPolicy{
+		Backoff:          viper.GetFloat64(batchRerunBackoffKey),
+		MaxRerunFraction: viper.GetFloat64(batchMaxRerunFractionKey),
+		MetricsOnly:      viper.GetBool(batchMetricsOnlyRerunsKey),
+	}
+	if delay := viper.GetString(batchRerunDelayKey); delay != "" {
+		var err error
+		policy.Delay, err = time.ParseDuration(delay)
+		if err != nil {
+			return RerunPolicy{}, fmt.Errorf("failed to parse rerun delay: %v", err)
+		}
+	}
+	if policy.Backoff == 0 {
+		policy.Backoff = 1
+	}
+	if policy.Backoff < 1 {
+		return RerunPolicy{}, fmt.Errorf("rerun-backoff must be at least 1, got: %f", policy.Backoff)
+	}
+	if policy.MaxRerunFraction < 0 || policy.MaxRerunFraction > 1 {
+		return RerunPolicy{}, fmt.Errorf("max-rerun-fraction must be between 0 and 1, got: %f", policy.MaxRerunFraction)
+	}
+	if tags := viper.GetString(batchRerunTagsKey); tags != "" {
+		for _, tag := range strings.Split(tags, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				continue
+			}
+			if tagID, err := uuid.Parse(tag); err == nil {
+				policy.ExperienceTagIDs = append(policy.ExperienceTagIDs, tagID)
+			} else {
+				policy.ExperienceTagIDs = append(policy.ExperienceTagIDs, getExperienceTagIDForName(Client, projectID, tag, true))
+			}
+		}
+	}
+	return policy, nil
+}
+
+// delayBefore returns the wait before the rerun of the given attempt, counting
+// from 0.
+func (p RerunPolicy) delayBefore(attempt int) time.Duration {
+	if p.Delay <= 0 {
+		return 0
+	}
+	return time.Duration(float64(p.Delay) * math.Pow(p.Backoff, float64(attempt)))
+}
+
+// applyRerunPolicy narrows the failed tests of a batch to those the policy
+// reruns. It runs after planRerun's --rerun-max-failure-percent check, and
+// returns an empty plan when none of the failed tests are rerunnable or when
+// too many of them are.
+func applyRerunPolicy(batch *api.Batch, params *SuperviseParams, allJobs []api.Job, matchingJobIDs []uuid.UUID) rerunPlan {
+	policy := params.RerunPolicy
+	selected := matchingJobIDs
+
+	if len(policy.ExperienceTagIDs) > 0 {
+		params.Limiter.wait()
+		tagged := getTaggedJobIDs(params.ProjectID, *batch.BatchID, policy.ExperienceTagIDs)
+		var rerunnable []uuid.UUID
+		for _, jobID := range selected {
+			if tagged[jobID] {
+				rerunnable = append(rerunnable, jobID)
+			}
+		}
+		params.logf("%d of %d failed tests have a rerunnable experience tag\n", len(rerunnable), len(selected))
+		selected = rerunnable
+	}
+	if len(selected) == 0 {
+		return rerunPlan{}
+	}
+
+	if policy.MaxRerunFraction > 0 && len(allJobs) > 0 {
+		fraction := float64(len(selected)) / float64(len(allJobs))
+		if fraction > policy.MaxRerunFraction {
+			params.logf(
+				"%d/%d tests need a rerun, more than the max rerun fraction %.2f; treating the failure as real and skipping rerun\n",
+				len(selected), len(allJobs), policy.MaxRerunFraction,
+			)
+			return rerunPlan{}
+		}
+	}
+
+	plan := rerunPlan{JobIDs: selected}
+	if policy.MetricsOnly {
+		jobs := map[uuid.UUID]api.Job{}
+		for _, job := range allJobs {
+			if job.JobID != nil {
+				jobs[*job.JobID] = job
+			}
+		}
+		plan.MetricsOnly = true
+		for _, jobID := range selected {
+			if !jobFailedInMetricsStage(jobs[jobID]) {
+				plan.MetricsOnly = false
+				break
+			}
+		}
+		if plan.MetricsOnly {
+			params.logf("All %s failed in the metrics stage; rerunning metrics only\n", pluralize(len(selected), "test"))
+		}
+	}
+	return plan
+}
+
+// jobFailedInMetricsStage reports whether a test errored after its experience
+// finished, i.e. while its metrics were queued or running.
+func jobFailedInMetricsStage(job api.Job) bool {
+	if job.JobStatus == nil || *job.JobStatus != api.JobStatusERROR || job.StatusHistory == nil {
+		return false
+	}
+	for _, entry := range *job.StatusHistory {
+		if entry.Status == nil {
+			continue
+		}
+		switch *entry.Status {
+		case api.JobStatusMETRICSQUEUED, api.JobStatusMETRICSRUNNING:
+			return true
+		}
+	}
+	return false
+}
+
+// getTaggedJobIDs returns the tests of a batch whose experiences have one of
+// the tags.
+func getTaggedJobIDs(projectID uuid.UUID, batchID uuid.UUID, tagIDs []uuid.UUID) map[uuid.UUID]bool {
+	jobIDs := map[uuid.UUID]bool{}
+	var pageToken *string = nil
+	for {
+		response, err := Client.ListJobsWithResponse(context.Background(), projectID, batchID, &api.ListJobsParams{
+			ExperienceTagIDs: &tagIDs,
+			PageSize:         Ptr(100),
+			PageToken:        pageToken,
+		})
+		if err != nil {
+			log.Fatal("unable to list jobs:", err)
+		}
+		ValidateResponse(http.StatusOK, "unable to list jobs", response.HTTPResponse, response.Body)
+		if response.JSON200.Jobs == nil {
+			log.Fatal("unable to list jobs")
+		}
+		for _, job := range *response.JSON200.Jobs {
+			if job.JobID != nil {
+				jobIDs[*job.JobID] = true
+			}
+		}
+		if response.JSON200.NextPageToken == nil || *response.JSON200.NextPageToken == "" {
+			return jobIDs
+		}
+		pageToken = response.JSON200.NextPageToken
+	}
+}
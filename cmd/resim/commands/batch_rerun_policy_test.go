@@ -0,0 +1,216 @@
+package commands
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *CommandsSuite) mockRerunPolicyJobs(projectID uuid.UUID, batchID uuid.UUID, jobs []api.Job) {
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, batchID, mock.MatchedBy(func(params *api.ListJobsParams) bool {
+		return params.ExperienceTagIDs == nil
+	})).Return(&api.ListJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListJobsOutput{Jobs: &jobs},
+	}, nil).Once()
+}
+
+func rerunPolicyParams(projectID uuid.UUID, policy RerunPolicy) *SuperviseParams {
+	return &SuperviseParams{
+		ProjectID:                projectID,
+		MaxRerunAttempts:         2,
+		RerunMaxFailurePercent:   100,
+		UndesiredConflatedStates: []api.ConflatedJobStatus{api.ConflatedJobStatusERROR},
+		RerunPolicy:              policy,
+	}
+}
+
+func (s *CommandsSuite) TestRerunPolicyDelayBefore() {
+	s.Equal(time.Duration(0), RerunPolicy{}.delayBefore(3))
+	policy := RerunPolicy{Delay: time.Minute, Backoff: 2}
+	s.Equal(time.Minute, policy.delayBefore(0))
+	s.Equal(2*time.Minute, policy.delayBefore(1))
+	s.Equal(4*time.Minute, policy.delayBefore(2))
+}
+
+func (s *CommandsSuite) TestGetRerunPolicy() {
+	viper.Reset()
+	projectID := uuid.New()
+	tagID := uuid.New()
+	otherTagID := uuid.New()
+	viper.Set(batchRerunDelayKey, "30s")
+	viper.Set(batchRerunBackoffKey, 3.0)
+	viper.Set(batchMaxRerunFractionKey, 0.2)
+	viper.Set(batchRerunTagsKey, "rerunnable, "+otherTagID.String())
+	viper.Set(batchMetricsOnlyRerunsKey, true)
+	s.mockClient.On("ListExperienceTagsWithResponse", matchContext, projectID, mock.Anything).Return(&api.ListExperienceTagsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListExperienceTagsOutput{ExperienceTags: &[]api.ExperienceTag{{ExperienceTagID: tagID, Name: "rerunnable"}}},
+	}, nil).Once()
+
+	policy, err := getRerunPolicy(projectID)
+
+	s.Require().NoError(err)
+	s.Equal(RerunPolicy{
+		Delay:            30 * time.Second,
+		Backoff:          3,
+		MaxRerunFraction: 0.2,
+		ExperienceTagIDs: []uuid.UUID{tagID, otherTagID},
+		MetricsOnly:      true,
+	}, policy)
+
+	viper.Reset()
+	viper.Set(batchMaxRerunFractionKey, 1.5)
+	_, err = getRerunPolicy(projectID)
+	s.ErrorContains(err, "max-rerun-fraction must be between 0 and 1")
+
+	viper.Reset()
+	viper.Set(batchRerunBackoffKey, 0.5)
+	_, err = getRerunPolicy(projectID)
+	s.ErrorContains(err, "rerun-backoff must be at least 1")
+}
+
+func (s *CommandsSuite) TestJobFailedInMetricsStage() {
+	s.True(jobFailedInMetricsStage(testJob("lane-change", api.ConflatedJobStatusERROR,
+		withStatusHistory(api.JobStatusSUBMITTED, api.JobStatusEXPERIENCERUNNING, api.JobStatusMETRICSRUNNING, api.JobStatusERROR))))
+	s.False(jobFailedInMetricsStage(testJob("lane-change", api.ConflatedJobStatusERROR,
+		withStatusHistory(api.JobStatusSUBMITTED, api.JobStatusEXPERIENCERUNNING, api.JobStatusERROR))))
+	s.False(jobFailedInMetricsStage(testJob("lane-change", api.ConflatedJobStatusBLOCKER,
+		withStatusHistory(api.JobStatusEXPERIENCERUNNING, api.JobStatusMETRICSRUNNING, api.JobStatusSUCCEEDED))))
+	s.False(jobFailedInMetricsStage(testJob("lane-change", api.ConflatedJobStatusERROR)))
+}
+
+func (s *CommandsSuite) TestPlanRerun_ExperienceTags() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	tagID := uuid.New()
+	jobs := []api.Job{
+		testJob("lane-change", api.ConflatedJobStatusERROR),
+		testJob("lane-change", api.ConflatedJobStatusERROR),
+		testJob("lane-change", api.ConflatedJobStatusPASSED),
+	}
+	s.mockRerunPolicyJobs(projectID, batchID, jobs)
+	// The second failed test and the passing one are tagged rerunnable.
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, batchID, mock.MatchedBy(func(params *api.ListJobsParams) bool {
+		return params.ExperienceTagIDs != nil && len(*params.ExperienceTagIDs) == 1 && (*params.ExperienceTagIDs)[0] == tagID
+	})).Return(&api.ListJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListJobsOutput{Jobs: &[]api.Job{jobs[1], jobs[2]}},
+	}, nil).Once()
+
+	batch := &api.Batch{BatchID: Ptr(batchID), Status: Ptr(api.BatchStatusERROR)}
+	plan := planRerun(batch, rerunPolicyParams(projectID, RerunPolicy{ExperienceTagIDs: []uuid.UUID{tagID}}), 0)
+
+	s.Equal([]uuid.UUID{*jobs[1].JobID}, plan.JobIDs)
+	s.False(plan.MetricsOnly)
+}
+
+func (s *CommandsSuite) TestPlanRerun_MaxRerunFraction() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	jobs := []api.Job{
+		testJob("lane-change", api.ConflatedJobStatusERROR),
+		testJob("lane-change", api.ConflatedJobStatusERROR),
+		testJob("lane-change", api.ConflatedJobStatusPASSED),
+		testJob("lane-change", api.ConflatedJobStatusPASSED),
+	}
+	batch := &api.Batch{BatchID: Ptr(batchID), Status: Ptr(api.BatchStatusERROR)}
+
+	// Two of four tests failed: more than a quarter, but not more than half.
+	s.mockRerunPolicyJobs(projectID, batchID, jobs)
+	s.Empty(planRerun(batch, rerunPolicyParams(projectID, RerunPolicy{MaxRerunFraction: 0.25}), 0).JobIDs)
+
+	s.mockRerunPolicyJobs(projectID, batchID, jobs)
+	s.Len(planRerun(batch, rerunPolicyParams(projectID, RerunPolicy{MaxRerunFraction: 0.5}), 0).JobIDs, 2)
+}
+
+func (s *CommandsSuite) TestPlanRerun_MaxRerunFractionAfterExperienceTags() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	tagID := uuid.New()
+	jobs := []api.Job{
+		testJob("lane-change", api.ConflatedJobStatusERROR),
+		testJob("lane-change", api.ConflatedJobStatusERROR),
+		testJob("lane-change", api.ConflatedJobStatusERROR),
+		testJob("lane-change", api.ConflatedJobStatusPASSED),
+	}
+	s.mockRerunPolicyJobs(projectID, batchID, jobs)
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, batchID, mock.MatchedBy(func(params *api.ListJobsParams) bool {
+		return params.ExperienceTagIDs != nil
+	})).Return(&api.ListJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListJobsOutput{Jobs: &[]api.Job{jobs[0]}},
+	}, nil).Once()
+
+	// Three of four tests failed, but only one of them is rerunnable, which is within the cap.
+	batch := &api.Batch{BatchID: Ptr(batchID), Status: Ptr(api.BatchStatusERROR)}
+	plan := planRerun(batch, rerunPolicyParams(projectID, RerunPolicy{
+		ExperienceTagIDs: []uuid.UUID{tagID},
+		MaxRerunFraction: 0.3,
+	}), 0)
+
+	s.Equal([]uuid.UUID{*jobs[0].JobID}, plan.JobIDs)
+}
+
+func (s *CommandsSuite) TestPlanRerun_MetricsOnly() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	metricsFailure := testJob("lane-change", api.ConflatedJobStatusERROR, withStatusHistory(api.JobStatusEXPERIENCERUNNING, api.JobStatusMETRICSQUEUED, api.JobStatusERROR))
+	experienceFailure := testJob("lane-change", api.ConflatedJobStatusERROR, withStatusHistory(api.JobStatusEXPERIENCERUNNING, api.JobStatusERROR))
+	batch := &api.Batch{BatchID: Ptr(batchID), Status: Ptr(api.BatchStatusERROR)}
+	params := rerunPolicyParams(projectID, RerunPolicy{MetricsOnly: true})
+
+	s.mockRerunPolicyJobs(projectID, batchID, []api.Job{metricsFailure, testJob("lane-change", api.ConflatedJobStatusPASSED)})
+	plan := planRerun(batch, params, 0)
+	s.Equal([]uuid.UUID{*metricsFailure.JobID}, plan.JobIDs)
+	s.True(plan.MetricsOnly)
+
+	// A test that failed before its metrics ran needs a full rerun.
+	s.mockRerunPolicyJobs(projectID, batchID, []api.Job{metricsFailure, experienceFailure})
+	plan = planRerun(batch, params, 0)
+	s.Len(plan.JobIDs, 2)
+	s.False(plan.MetricsOnly)
+}
+
+func (s *CommandsSuite) TestSuperviseBatch_RerunPolicy() {
+	viper.Reset()
+	projectID := uuid.New()
+	batchID := uuid.New()
+	rerunBatchID := uuid.New()
+	failed := testJob("lane-change", api.ConflatedJobStatusERROR, withStatusHistory(api.JobStatusEXPERIENCERUNNING, api.JobStatusMETRICSRUNNING, api.JobStatusERROR))
+	viper.Set(batchProjectKey, projectID.String())
+	viper.Set(batchMaxRerunAttemptsKey, 1)
+	viper.Set(batchRerunMaxFailurePercentKey, 50.0)
+	viper.Set(batchRerunOnStatesKey, "Error")
+	viper.Set(batchWaitTimeoutKey, "1m")
+	viper.Set(batchWaitPollKey, "1ms")
+	viper.Set(batchIDKey, batchID.String())
+	viper.Set(batchRerunDelayKey, "20ms")
+	viper.Set(batchMetricsOnlyRerunsKey, true)
+
+	s.mockClient.On("GetProjectWithResponse", matchContext, projectID).Return(&api.GetProjectResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Project{ProjectID: projectID, Name: "test-project"},
+	}, nil)
+	s.mockFinishedBatch(projectID, batchID, api.BatchStatusERROR)
+	s.mockRerunPolicyJobs(projectID, batchID, []api.Job{failed, testJob("lane-change", api.ConflatedJobStatusPASSED)})
+	s.mockClient.On("RerunBatchWithResponse", matchContext, projectID, batchID, mock.MatchedBy(func(body api.RerunBatchInput) bool {
+		return body.RerunMetricsOnly != nil && *body.RerunMetricsOnly && len(*body.JobIDs) == 1 && (*body.JobIDs)[0] == *failed.JobID
+	})).Return(&api.RerunBatchResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.RerunBatchOutput{BatchID: Ptr(rerunBatchID)},
+	}, nil).Once()
+	s.mockFinishedBatch(projectID, rerunBatchID, api.BatchStatusSUCCEEDED)
+
+	start := time.Now()
+	result := actualSuperviseBatch(nil, nil)
+
+	s.Require().NoError(result.Error)
+	s.Equal(rerunBatchID, *result.Batch.BatchID)
+	s.GreaterOrEqual(time.Since(start), 20*time.Millisecond)
+}
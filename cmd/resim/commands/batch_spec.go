@@ -0,0 +1,221 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	batchSpecKey      = "spec"
+	batchSpecBatchKey = "spec-batch"
+)
+
+// batchSpecFile is a YAML file of named batches for `batches create --spec`.
+// Each batch maps flag names of `batches create` to their values; `defaults`
+// applies to every batch and is overridden by the batch's own values.
+type batchSpecFile struct {
+	Defaults map[string]any            `yaml:"defaults"`
+	Batches  map[string]map[string]any `yaml:"batches"`
+}
+
+// applyBatchSpecFlags loads --spec, if set, before the required flags are
+// validated. Spec values become viper defaults, so flags given on the command
+// line take precedence, and flags provided by the spec are no longer required.
+func applyBatchSpecFlags(cmd *cobra.Command, args []string) {
+	if !viper.IsSet(batchSpecKey) {
+		return
+	}
+	spec, err := loadBatchSpec(viper.GetString(batchSpecKey), viper.GetString(batchSpecBatchKey))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := applyBatchSpec(cmd.Flags(), spec); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// loadBatchSpec reads a spec file and returns the values of the named batch
+// merged over the file's defaults. The name may be omitted when the file holds a
+// single batch. Environment variables in string values are expanded.
+func loadBatchSpec(path string, name string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch spec: %w", err)
+	}
+	var file batchSpecFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse batch spec %s: %w", path, err)
+	}
+	if len(file.Batches) == 0 {
+		return nil, fmt.Errorf("no batches found in batch spec %s", path)
+	}
+
+	names := make([]string, 0, len(file.Batches))
+	for batchName := range file.Batches {
+		names = append(names, batchName)
+	}
+	sort.Strings(names)
+	if name == "" {
+		if len(names) > 1 {
+			return nil, fmt.Errorf("batch spec %s defines several batches, choose one with --%s: %s", path, batchSpecBatchKey, strings.Join(names, ", "))
+		}
+		name = names[0]
+	}
+	batch, ok := file.Batches[name]
+	if !ok {
+		return nil, fmt.Errorf("batch %q not found in batch spec %s, expected one of: %s", name, path, strings.Join(names, ", "))
+	}
+
+	spec := map[string]any{}
+	for key, value := range file.Defaults {
+		spec[key] = value
+	}
+	for key, value := range batch {
+		spec[key] = value
+	}
+	for key, value := range spec {
+		expanded, err := expandBatchSpecValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("batch %q, %s: %w", name, key, err)
+		}
+		spec[key] = expanded
+	}
+	return spec, nil
+}
+
+// expandBatchSpecValue replaces $VAR and ${VAR} in the strings of a spec
+// value. Unset variables are an error rather than an empty string, so a missing
+// CI variable cannot silently change the batch.
+func expandBatchSpecValue(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		var missing []string
+		expanded := os.Expand(v, func(name string) string {
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				missing = append(missing, name)
+			}
+			return value
+		})
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+		}
+		return expanded, nil
+	case []any:
+		expanded := make([]any, len(v))
+		for i, item := range v {
+			var err error
+			if expanded[i], err = expandBatchSpecValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return expanded, nil
+	case map[string]any:
+		expanded := make(map[string]any, len(v))
+		for key, item := range v {
+			var err error
+			if expanded[key], err = expandBatchSpecValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return expanded, nil
+	default:
+		return value, nil
+	}
+}
+
+// applyBatchSpec sets the spec values as viper defaults for the matching flags.
+func applyBatchSpec(flags *pflag.FlagSet, spec map[string]any) error {
+	keys := make([]string, 0, len(spec))
+	for key := range spec {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		flag := flags.Lookup(key)
+		if flag == nil || key == batchSpecKey || key == batchSpecBatchKey {
+			return fmt.Errorf("unknown batch spec key %q: keys must be flags of this command", key)
+		}
+		value, err := batchSpecFlagValue(flag, spec[key])
+		if err != nil {
+			return fmt.Errorf("invalid batch spec value for %s: %w", key, err)
+		}
+		viper.SetDefault(key, value)
+		flags.SetAnnotation(key, cobra.BashCompOneRequiredFlag, []string{"false"})
+	}
+	return nil
+}
+
+// batchSpecFlagValue converts a YAML value to the form the flag's viper getter
+// expects. Lists may be used for comma-separated string flags, and a map of
+// parameters becomes a list of name=value pairs.
+func batchSpecFlagValue(flag *pflag.Flag, value any) (any, error) {
+	switch flag.Value.Type() {
+	case "string":
+		switch v := value.(type) {
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			return strings.Join(items, ","), nil
+		case map[string]any:
+			return nil, errors.New("expected a string or a list")
+		default:
+			return fmt.Sprint(v), nil
+		}
+	case "stringSlice":
+		switch v := value.(type) {
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			return items, nil
+		case map[string]any:
+			items := make([]string, 0, len(v))
+			for key, item := range v {
+				items = append(items, fmt.Sprintf("%s=%v", key, item))
+			}
+			slices.Sort(items)
+			return items, nil
+		default:
+			return []string{fmt.Sprint(v)}, nil
+		}
+	case "int", "int32":
+		switch v := value.(type) {
+		case int:
+			return v, nil
+		case string:
+			return strconv.Atoi(v)
+		default:
+			return nil, fmt.Errorf("expected an integer, got %v", v)
+		}
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(v)
+		default:
+			return nil, fmt.Errorf("expected true or false, got %v", v)
+		}
+	default:
+		return nil, fmt.Errorf("flag type %s is not supported in batch specs", flag.Value.Type())
+	}
+}
@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testBatchSpec = `
+defaults:
+  pool-labels: [gpu]
+  priority: 5
+batches:
+  nightly:
+    build-id: ${SPEC_TEST_BUILD_ID}
+    experience-tags: [nightly, regression]
+    parameter:
+      planner.max_speed: 12
+      planner.mode: $SPEC_TEST_MODE
+  smoke:
+    build-id: ${SPEC_TEST_BUILD_ID}
+    experiences: lane-change
+    priority: 1
+`
+
+func (s *CommandsSuite) writeBatchSpec(contents string) string {
+	path := filepath.Join(s.T().TempDir(), "batch.yaml")
+	s.Require().NoError(os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func (s *CommandsSuite) TestLoadBatchSpec() {
+	s.T().Setenv("SPEC_TEST_BUILD_ID", "build-123")
+	s.T().Setenv("SPEC_TEST_MODE", "cautious")
+	path := s.writeBatchSpec(testBatchSpec)
+
+	spec, err := loadBatchSpec(path, "nightly")
+	s.Require().NoError(err)
+	s.Equal("build-123", spec["build-id"])
+	s.Equal([]any{"nightly", "regression"}, spec["experience-tags"])
+	s.Equal(map[string]any{"planner.max_speed": 12, "planner.mode": "cautious"}, spec["parameter"])
+	s.Equal([]any{"gpu"}, spec["pool-labels"])
+	s.Equal(5, spec["priority"])
+
+	// The batch's own values override the defaults.
+	spec, err = loadBatchSpec(path, "smoke")
+	s.Require().NoError(err)
+	s.Equal(1, spec["priority"])
+
+	_, err = loadBatchSpec(path, "")
+	s.ErrorContains(err, "defines several batches, choose one with --spec-batch: nightly, smoke")
+	_, err = loadBatchSpec(path, "weekly")
+	s.ErrorContains(err, `batch "weekly" not found`)
+
+	os.Unsetenv("SPEC_TEST_MODE")
+	_, err = loadBatchSpec(path, "nightly")
+	s.ErrorContains(err, "environment variable SPEC_TEST_MODE is not set")
+
+	// A file with a single batch does not need --spec-batch.
+	spec, err = loadBatchSpec(s.writeBatchSpec("batches:\n  only:\n    build-id: abc\n"), "")
+	s.Require().NoError(err)
+	s.Equal("abc", spec["build-id"])
+
+	_, err = loadBatchSpec(s.writeBatchSpec("batch:\n  only: {}\n"), "")
+	s.ErrorContains(err, "field batch not found")
+}
+
+func (s *CommandsSuite) TestApplyBatchSpec() {
+	viper.Reset()
+	cmd := &cobra.Command{Use: "create"}
+	cmd.Flags().String(batchBuildIDKey, "", "")
+	cmd.MarkFlagRequired(batchBuildIDKey)
+	cmd.Flags().String(batchExperiencesKey, "", "")
+	cmd.Flags().StringSlice(batchParameterKey, []string{}, "")
+	cmd.Flags().Int(batchPriorityKey, 0, "")
+	cmd.Flags().Bool(batchSyncMetricsConfigKey, false, "")
+	cmd.Flags().String(batchSpecKey, "", "")
+	s.Require().NoError(cmd.Flags().Set(batchExperiencesKey, "from-cli"))
+	viper.BindPFlags(cmd.Flags())
+
+	s.Require().NoError(applyBatchSpec(cmd.Flags(), map[string]any{
+		batchBuildIDKey:           "build-123",
+		batchExperiencesKey:       []any{"lane-change", "merge"},
+		batchParameterKey:         map[string]any{"b": 2, "a": "x=y"},
+		batchPriorityKey:          "7",
+		batchSyncMetricsConfigKey: true,
+	}))
+	s.Equal("build-123", viper.GetString(batchBuildIDKey))
+	s.Equal("from-cli", viper.GetString(batchExperiencesKey))
+	s.Equal([]string{"a=x=y", "b=2"}, viper.GetStringSlice(batchParameterKey))
+	s.Equal(7, viper.GetInt(batchPriorityKey))
+	s.True(viper.GetBool(batchSyncMetricsConfigKey))
+	s.Equal([]string{"false"}, cmd.Flag(batchBuildIDKey).Annotations[cobra.BashCompOneRequiredFlag])
+	s.NoError(cmd.ValidateRequiredFlags())
+
+	s.ErrorContains(applyBatchSpec(cmd.Flags(), map[string]any{"experiance": "x"}), `unknown batch spec key "experiance"`)
+	s.ErrorContains(applyBatchSpec(cmd.Flags(), map[string]any{batchSpecKey: "other.yaml"}), `unknown batch spec key "spec"`)
+	s.ErrorContains(applyBatchSpec(cmd.Flags(), map[string]any{batchPriorityKey: []any{1}}), "invalid batch spec value for priority")
+}
+
+func (s *CommandsSuite) TestCreateBatchFromSpec() {
+	viper.Reset()
+	projectID := uuid.New()
+	buildID := uuid.New()
+	s.T().Setenv("SPEC_TEST_BUILD_ID", buildID.String())
+	s.T().Setenv("SPEC_TEST_MODE", "cautious")
+	viper.Set(batchProjectKey, projectID.String())
+
+	spec, err := loadBatchSpec(s.writeBatchSpec(testBatchSpec), "nightly")
+	s.Require().NoError(err)
+	s.Require().NoError(applyBatchSpec(createBatchCmd.Flags(), spec))
+
+	s.mockClient.On("GetProjectWithResponse", matchContext, projectID).Return(&api.GetProjectResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Project{ProjectID: projectID, Name: "test-project"},
+	}, nil)
+	s.mockClient.On("CreateBatchWithResponse", matchContext, projectID, mock.MatchedBy(func(body api.BatchInput) bool {
+		return *body.BuildID == buildID &&
+			assert.ObjectsAreEqual([]string{"nightly", "regression"}, *body.ExperienceTagNames) &&
+			assert.ObjectsAreEqual(api.BatchParameters{"planner.max_speed": "12", "planner.mode": "cautious"}, *body.Parameters) &&
+			*body.Priority == 5 &&
+			len(*body.PoolLabels) == 1
+	})).Return(&api.CreateBatchResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusCreated},
+		JSON201: &api.Batch{
+			BatchID:      Ptr(uuid.New()),
+			FriendlyName: Ptr("spec-batch"),
+			Status:       Ptr(api.BatchStatusSUBMITTED),
+		},
+	}, nil).Once()
+
+	createBatch(nil, nil)
+}
@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+const (
+	batchIDsKey               = "batch-ids"
+	batchesFromKey            = "batches-from"
+	batchRequestsPerSecondKey = "requests-per-second"
+)
+
+// requestLimiter spaces out API requests made by concurrent supervisions so that
+// supervising many batches does not flood the API. Its methods do nothing on a
+// nil limiter.
+type requestLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRequestLimiter returns a limiter allowing perSecond requests per second, or
+// nil if perSecond is not positive.
+func newRequestLimiter(perSecond float64) *requestLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &requestLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the next request is allowed.
+func (l *requestLimiter) wait() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+	time.Sleep(time.Until(at))
+}
+
+// supervisedBatchLogPrefix labels the log lines of a batch supervised in
+// parallel with the first 8 characters of its ID.
+func supervisedBatchLogPrefix(batchID uuid.UUID) string {
+	return "[" + batchID.String()[:8] + "] "
+}
+
+// getSuperviseBatchIDs returns the batches given by --batch-ids or
+// --batches-from, without duplicates. A --batches-from file lists IDs
+// separated by commas or newlines; blank lines and lines starting with # are
+// skipped.
+func getSuperviseBatchIDs() ([]uuid.UUID, error) {
+	var raw []string
+	if viper.IsSet(batchIDsKey) {
+		raw = strings.Split(viper.GetString(batchIDsKey), ",")
+	} else {
+		path := viper.GetString(batchesFromKey)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read batches file: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			raw = append(raw, strings.Split(line, ",")...)
+		}
+	}
+
+	var batchIDs []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	for _, s := range raw {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		batchID, err := uuid.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch ID %q: %w", s, err)
+		}
+		if !seen[batchID] {
+			seen[batchID] = true
+			batchIDs = append(batchIDs, batchID)
+		}
+	}
+	if len(batchIDs) == 0 {
+		return nil, fmt.Errorf("no batch IDs given")
+	}
+	return batchIDs, nil
+}
+
+// superviseManyBatches supervises the batches given by --batch-ids or
+// --batches-from in parallel, prints a summary and exits with the combined
+// exit code.
+func superviseManyBatches() {
+	baselineMode := getBaselineModeFlag()
+	rules := getMetricRulesFlag()
+
+	params, err := getSuperviseParams(nil, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	batchIDs, err := getSuperviseBatchIDs()
+	if err != nil {
+		log.Fatal(err)
+	}
+	params.Limiter = newRequestLimiter(viper.GetFloat64(batchRequestsPerSecondKey))
+
+	results := superviseBatchesInParallel(batchIDs, params)
+
+	metricRules := metricRulesPassed
+	for _, result := range results {
+		if result.Error == nil && result.Batch != nil && result.Batch.ProjectID != nil {
+			logRegressions(findBatchRegressions(*result.Batch.ProjectID, result.Batch, baselineMode))
+			metricRules = max(metricRules, checkMetricRules(*result.Batch.ProjectID, result.Batch, baselineMode, rules))
+		}
+	}
+	fmt.Print(formatSupervisedBatches(batchIDs, results))
+
+	failFilter := supervisorFailFilter(batchFailOnStatesKey, batchRerunOnStatesKey)
+	writeJUnitReportForResults(viper.GetString(batchJUnitKey), "ReSim", results)
+	exitWithBatchStatus(results, exitCodeOptions{failOnStates: failFilter, metricRules: metricRules}, true)
+}
+
+// formatSupervisedBatches summarizes the results of supervising batchIDs, in
+// the same order. The final batch differs from the batch when tests were rerun.
+func formatSupervisedBatches(batchIDs []uuid.UUID, results []*SuperviseResult) string {
+	var b strings.Builder
+	batches := fmt.Sprintf("%d batches", len(batchIDs))
+	if len(batchIDs) == 1 {
+		batches = "1 batch"
+	}
+	fmt.Fprintf(&b, "\nSupervised %s:\n\n", batches)
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BATCH ID\tFINAL BATCH ID\tNAME\tSTATUS\tCONFLATED STATUS")
+	var errors []string
+	for i, batchID := range batchIDs {
+		finalBatchID, name, status, conflated := "-", "-", "-", "-"
+		result := results[i]
+		switch {
+		case result == nil:
+		case result.Error != nil:
+			status = "FAILED"
+			if _, ok := result.Error.(*TimeoutError); ok {
+				status = "TIMED OUT"
+			}
+			errors = append(errors, fmt.Sprintf("%s: %v", batchID, result.Error))
+		case result.Batch != nil:
+			batch := result.Batch
+			if batch.BatchID != nil {
+				finalBatchID = batch.BatchID.String()
+			}
+			if batch.FriendlyName != nil {
+				name = *batch.FriendlyName
+			}
+			if batch.Status != nil {
+				status = string(*batch.Status)
+			}
+			if batch.ConflatedStatus != nil {
+				conflated = string(*batch.ConflatedStatus)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", batchID, finalBatchID, name, status, conflated)
+	}
+	w.Flush()
+
+	if len(errors) > 0 {
+		b.WriteString("\nErrors:\n")
+		for _, e := range errors {
+			fmt.Fprintf(&b, "  %s\n", e)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *CommandsSuite) TestGetSuperviseBatchIDs() {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+
+	viper.Reset()
+	viper.Set(batchIDsKey, a.String()+", "+b.String()+","+a.String())
+	batchIDs, err := getSuperviseBatchIDs()
+	s.Require().NoError(err)
+	s.Equal([]uuid.UUID{a, b}, batchIDs)
+
+	viper.Reset()
+	path := filepath.Join(s.T().TempDir(), "batches.txt")
+	s.Require().NoError(os.WriteFile(path, []byte("# per-system batches\n"+a.String()+"\n\n"+b.String()+","+c.String()+"\n"), 0o644))
+	viper.Set(batchesFromKey, path)
+	batchIDs, err = getSuperviseBatchIDs()
+	s.Require().NoError(err)
+	s.Equal([]uuid.UUID{a, b, c}, batchIDs)
+
+	viper.Reset()
+	viper.Set(batchIDsKey, a.String()+",not-a-batch")
+	_, err = getSuperviseBatchIDs()
+	s.ErrorContains(err, `invalid batch ID "not-a-batch"`)
+}
+
+func (s *CommandsSuite) TestRequestLimiter() {
+	s.Nil(newRequestLimiter(0))
+	var nilLimiter *requestLimiter
+	nilLimiter.wait()
+
+	limiter := newRequestLimiter(100)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		limiter.wait()
+	}
+	// The first request is immediate and the other four are 10ms apart.
+	s.GreaterOrEqual(time.Since(start), 40*time.Millisecond)
+}
+
+func (s *CommandsSuite) TestSuperviseManyBatches() {
+	viper.Reset()
+	projectID := uuid.New()
+	rerunID, passingID, timedOutID := uuid.New(), uuid.New(), uuid.New()
+	rerunBatchID := uuid.New()
+	failedJobID := uuid.New()
+
+	s.mockClient.On("GetBatchWithResponse", matchContext, projectID, rerunID).Return(&api.GetBatchResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Batch{BatchID: Ptr(rerunID), Status: Ptr(api.BatchStatusERROR)},
+	}, nil).Once()
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, rerunID, mock.Anything).Return(&api.ListJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.ListJobsOutput{Jobs: &[]api.Job{
+			{JobID: Ptr(failedJobID), ConflatedStatus: Ptr(api.ConflatedJobStatusERROR)},
+			{JobID: Ptr(uuid.New()), ConflatedStatus: Ptr(api.ConflatedJobStatusPASSED)},
+		}},
+	}, nil).Once()
+	s.mockClient.On("RerunBatchWithResponse", matchContext, projectID, rerunID, mock.Anything).Return(&api.RerunBatchResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.RerunBatchOutput{BatchID: Ptr(rerunBatchID)},
+	}, nil).Once()
+	s.mockClient.On("GetBatchWithResponse", matchContext, projectID, rerunBatchID).Return(&api.GetBatchResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.Batch{
+			BatchID:         Ptr(rerunBatchID),
+			FriendlyName:    Ptr("rerun-batch"),
+			Status:          Ptr(api.BatchStatusSUCCEEDED),
+			ConflatedStatus: Ptr(api.ConflatedBatchStatusCOMPLETE),
+		},
+	}, nil).Once()
+	s.mockClient.On("GetBatchWithResponse", matchContext, projectID, passingID).Return(&api.GetBatchResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.Batch{
+			BatchID:         Ptr(passingID),
+			FriendlyName:    Ptr("passing-batch"),
+			Status:          Ptr(api.BatchStatusSUCCEEDED),
+			ConflatedStatus: Ptr(api.ConflatedBatchStatusCOMPLETE),
+		},
+	}, nil).Once()
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, passingID, mock.Anything).Return(&api.ListJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.ListJobsOutput{Jobs: &[]api.Job{
+			{JobID: Ptr(uuid.New()), ConflatedStatus: Ptr(api.ConflatedJobStatusPASSED)},
+		}},
+	}, nil).Once()
+	s.mockClient.On("GetBatchWithResponse", matchContext, projectID, timedOutID).Return(&api.GetBatchResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Batch{BatchID: Ptr(timedOutID), Status: Ptr(api.BatchStatusEXPERIENCESRUNNING)},
+	}, nil)
+
+	params := &SuperviseParams{
+		ProjectID:                projectID,
+		MaxRerunAttempts:         1,
+		RerunMaxFailurePercent:   50,
+		UndesiredConflatedStates: []api.ConflatedJobStatus{api.ConflatedJobStatusERROR},
+		Timeout:                  20 * time.Millisecond,
+		PollInterval:             time.Millisecond,
+		Limiter:                  newRequestLimiter(1000),
+	}
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	batchIDs := []uuid.UUID{rerunID, passingID, timedOutID}
+	results := superviseBatchesInParallel(batchIDs, params)
+
+	s.Require().Len(results, 3)
+	s.Equal(rerunBatchID, *results[0].Batch.BatchID)
+	s.Equal(passingID, *results[1].Batch.BatchID)
+	var timeoutErr *TimeoutError
+	s.True(errors.As(results[2].Error, &timeoutErr))
+	s.Equal(exitCodeTimeout, computeExitCode(results, exitCodeOptions{failOnStates: []api.ConflatedBatchStatus{api.ConflatedBatchStatusERROR}}))
+
+	s.Contains(logs.String(), supervisedBatchLogPrefix(rerunID)+"Submitted rerun batch: "+rerunBatchID.String())
+	s.Contains(logs.String(), supervisedBatchLogPrefix(passingID)+"Batch "+passingID.String()+" completed with status: SUCCEEDED")
+
+	summary := formatSupervisedBatches(batchIDs, results)
+	s.Contains(summary, "Supervised 3 batches:")
+	s.Equal([]string{rerunID.String(), rerunBatchID.String(), "rerun-batch", "SUCCEEDED", "COMPLETE"}, strings.Fields(lineWith(summary, rerunID.String())))
+	s.Equal([]string{passingID.String(), passingID.String(), "passing-batch", "SUCCEEDED", "COMPLETE"}, strings.Fields(lineWith(summary, passingID.String())))
+	s.Equal([]string{timedOutID.String(), "-", "-", "TIMED", "OUT", "-"}, strings.Fields(lineWith(summary, timedOutID.String())))
+	s.Contains(summary, "Errors:\n  "+timedOutID.String()+": timeout after")
+}
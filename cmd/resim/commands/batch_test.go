@@ -1757,3 +1757,46 @@ func (s *CommandsSuite) TestSupervisorFailFilter_EmptyExplicitFallsBackToImplici
 	got := supervisorFailFilter(batchFailOnStatesKey, batchRerunOnStatesKey)
 	s.ElementsMatch([]api.ConflatedBatchStatus{api.ConflatedBatchStatusERROR}, got)
 }
+
+// testJob returns a job of a new test of the named experience, with the given
+// conflated status. Options set any other fields a test needs.
+func testJob(name string, status api.ConflatedJobStatus, options ...func(*api.Job)) api.Job {
+	job := api.Job{
+		JobID:           Ptr(uuid.New()),
+		ExperienceID:    Ptr(uuid.New()),
+		ExperienceName:  Ptr(name),
+		ConflatedStatus: Ptr(status),
+	}
+	for _, option := range options {
+		option(&job)
+	}
+	return job
+}
+
+func withJobID(id uuid.UUID) func(*api.Job) {
+	return func(job *api.Job) { job.JobID = Ptr(id) }
+}
+
+func withJobStatus(status api.JobStatus) func(*api.Job) {
+	return func(job *api.Job) { job.JobStatus = Ptr(status) }
+}
+
+func withExperienceID(id uuid.UUID) func(*api.Job) {
+	return func(job *api.Job) { job.ExperienceID = Ptr(id) }
+}
+
+func withRunCounter(runCounter int) func(*api.Job) {
+	return func(job *api.Job) { job.RunCounter = Ptr(runCounter) }
+}
+
+// withStatusHistory sets the status history of a job, and its status to the last one.
+func withStatusHistory(statuses ...api.JobStatus) func(*api.Job) {
+	return func(job *api.Job) {
+		history := api.JobStatusHistory{}
+		for _, status := range statuses {
+			history = append(history, api.JobStatusHistoryType{Status: Ptr(status)})
+		}
+		job.StatusHistory = &history
+		job.JobStatus = Ptr(statuses[len(statuses)-1])
+	}
+}
@@ -0,0 +1,331 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var watchBatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "watch - Awaits batch completion with a live view of each test",
+	Long: `watch - Awaits batch completion like wait, showing live progress while it runs.
+
+On a terminal the view refreshes every poll with an overall progress bar and
+each test's status, conflated status, elapsed time and container states. When
+stdout is not a terminal, a line is printed each time the batch or a test
+changes state instead. Exit codes are the same as for wait.`,
+	Run: watchBatch,
+}
+
+const batchLiveKey = "live"
+
+// watchContainerStatusLimit caps the container status requests made per poll; only
+// running tests are queried, and the remainder are shown without container states.
+const watchContainerStatusLimit = 25
+
+// clearScreen moves the cursor home and clears the terminal before each frame.
+const clearScreen = "\033[H\033[2J"
+
+func init() {
+	watchBatchCmd.Flags().String(batchProjectKey, "", "The name or ID of the project the batch is associated with")
+	watchBatchCmd.MarkFlagRequired(batchProjectKey)
+	watchBatchCmd.Flags().String(batchIDKey, "", "The ID of the batch to watch.")
+	watchBatchCmd.Flags().String(batchNameKey, "", "The name of the batch to watch (e.g. rejoicing-aquamarine-starfish). If the name is not unique, this watches the most recent batch with that name.")
+	watchBatchCmd.MarkFlagsMutuallyExclusive(batchIDKey, batchNameKey)
+	watchBatchCmd.Flags().String(batchWaitTimeoutKey, "1h", "Amount of time to wait for a batch to finish, expressed in Golang duration string.")
+	watchBatchCmd.Flags().String(batchWaitPollKey, "10s", "Interval between refreshes, expressed in Golang duration string.")
+	watchBatchCmd.Flags().String(batchFailOnStatesKey, "", "(Optional) Comma-separated list of conflated states that should fail the command (WARNING, ERROR, BLOCKER). When set, the exit code is derived from Batch.ConflatedStatus filtered to these states (new codes: 7=BLOCKER, 8=WARNING). When unset, the legacy Batch.Status-based exit codes are used.")
+	watchBatchCmd.Flags().Bool(batchQuietKey, false, "Suppress informational log lines (conflated status summaries).")
+	watchBatchCmd.Flags().String(batchBaselineKey, "", baselineFlagDescription)
+	watchBatchCmd.Flags().String(batchJUnitKey, "", junitFlagDescription)
+	watchBatchCmd.Flags().String(batchMetricRulesKey, "", metricRulesFlagDescription)
+	watchBatchCmd.Flags().Bool(batchAllowMissingBaselineKey, false, allowMissingBaselineFlagDescription)
+	batchCmd.AddCommand(watchBatchCmd)
+
+	waitBatchCmd.Flags().Bool(batchLiveKey, false, "If set, show live per-test progress while waiting (the same view as `batches watch`).")
+}
+
+// isTerminal reports whether w is a terminal, in which case the watch view redraws
+// in place instead of printing transitions.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// isFinalJobStatus reports whether a test has stopped running.
+func isFinalJobStatus(status *api.JobStatus) bool {
+	if status == nil {
+		return false
+	}
+	switch *status {
+	case api.JobStatusSUCCEEDED, api.JobStatusERROR, api.JobStatusCANCELLED:
+		return true
+	}
+	return false
+}
+
+// batchWatcher renders successive polls of a batch, either as a redrawn frame (live)
+// or as one line per batch or test state transition.
+type batchWatcher struct {
+	out         io.Writer
+	live        bool
+	now         func() time.Time
+	batchState  string
+	jobStates   map[uuid.UUID]string
+	lastDone    int
+	initialized bool
+}
+
+func newBatchWatcher(out io.Writer, live bool) *batchWatcher {
+	return &batchWatcher{
+		out:       out,
+		live:      live,
+		now:       time.Now,
+		jobStates: map[uuid.UUID]string{},
+	}
+}
+
+func watchJobName(job api.Job) string {
+	if job.ExperienceName != nil && *job.ExperienceName != "" {
+		return *job.ExperienceName
+	}
+	if job.JobID != nil {
+		return job.JobID.String()
+	}
+	return "<unknown test>"
+}
+
+// watchJobState is the state shown for a test, e.g. EXPERIENCE_RUNNING/RUNNING.
+func watchJobState(job api.Job) string {
+	status, conflated := "-", "-"
+	if job.JobStatus != nil {
+		status = string(*job.JobStatus)
+	}
+	if job.ConflatedStatus != nil {
+		conflated = string(*job.ConflatedStatus)
+	}
+	return status + "/" + conflated
+}
+
+func watchBatchName(batch *api.Batch) string {
+	if batch.FriendlyName != nil && *batch.FriendlyName != "" {
+		return *batch.FriendlyName
+	}
+	if batch.BatchID != nil {
+		return batch.BatchID.String()
+	}
+	return "<unknown batch>"
+}
+
+// watchJobElapsed is how long a test has been running, or ran for once finished.
+func (w *batchWatcher) watchJobElapsed(job api.Job) string {
+	if job.CreationTimestamp == nil {
+		return "-"
+	}
+	end := w.now()
+	if isFinalJobStatus(job.JobStatus) && job.LastUpdatedTimestamp != nil {
+		end = *job.LastUpdatedTimestamp
+	}
+	return end.Sub(*job.CreationTimestamp).Round(time.Second).String()
+}
+
+// formatContainerStatuses summarizes container states, e.g. "planner=running, metrics=terminated (exit 1)".
+func formatContainerStatuses(statuses []api.ContainerStatusLine) string {
+	if len(statuses) == 0 {
+		return "-"
+	}
+	var parts []string
+	for _, status := range statuses {
+		part := status.ContainerName + "=" + status.Status
+		if status.ExitCode != nil {
+			part += fmt.Sprintf(" (exit %d)", *status.ExitCode)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// watchProgressBar renders the overall progress bar without writing it anywhere.
+func watchProgressBar(done int, total int) string {
+	bar := progressbar.NewOptions(total,
+		progressbar.OptionSetWriter(io.Discard),
+		progressbar.OptionSetWidth(30),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetDescription("tests finished"),
+		progressbar.OptionSetPredictTime(false),
+		progressbar.OptionSetElapsedTime(false),
+	)
+	bar.Set(done)
+	return strings.TrimSpace(strings.Trim(bar.String(), "\r"))
+}
+
+// update renders one poll of the batch.
+func (w *batchWatcher) update(batch *api.Batch, jobs []api.Job, containers map[uuid.UUID][]api.ContainerStatusLine) {
+	done := 0
+	for _, job := range jobs {
+		if isFinalJobStatus(job.JobStatus) {
+			done++
+		}
+	}
+	if w.live {
+		w.renderFrame(batch, jobs, containers, done)
+	} else {
+		w.renderTransitions(batch, jobs, done)
+	}
+	w.initialized = true
+}
+
+func (w *batchWatcher) renderFrame(batch *api.Batch, jobs []api.Job, containers map[uuid.UUID][]api.ContainerStatusLine, done int) {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	status := "-"
+	if batch.Status != nil {
+		status = string(*batch.Status)
+	}
+	fmt.Fprintf(&b, "Batch %s: %s (updated %s)\n", watchBatchName(batch), status, w.now().Format(time.TimeOnly))
+	if len(jobs) > 0 {
+		fmt.Fprintf(&b, "%s\n", watchProgressBar(done, len(jobs)))
+	}
+	b.WriteString("\n")
+
+	// Tests still running first, so stuck or crashing tests stay at the top.
+	sorted := append([]api.Job(nil), jobs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		iDone, jDone := isFinalJobStatus(sorted[i].JobStatus), isFinalJobStatus(sorted[j].JobStatus)
+		if iDone != jDone {
+			return !iDone
+		}
+		return watchJobName(sorted[i]) < watchJobName(sorted[j])
+	})
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "EXPERIENCE\tSTATUS\tCONFLATED\tELAPSED\tCONTAINERS")
+	for _, job := range sorted {
+		jobStatus, conflated := "-", "-"
+		if job.JobStatus != nil {
+			jobStatus = string(*job.JobStatus)
+		}
+		if job.ConflatedStatus != nil {
+			conflated = string(*job.ConflatedStatus)
+		}
+		var statuses []api.ContainerStatusLine
+		if job.JobID != nil {
+			statuses = containers[*job.JobID]
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", watchJobName(job), jobStatus, conflated, w.watchJobElapsed(job), formatContainerStatuses(statuses))
+	}
+	tw.Flush()
+	fmt.Fprint(w.out, b.String())
+}
+
+func (w *batchWatcher) renderTransitions(batch *api.Batch, jobs []api.Job, done int) {
+	stamp := w.now().Format(time.TimeOnly)
+	batchState := "-"
+	if batch.Status != nil {
+		batchState = string(*batch.Status)
+	}
+	if batchState != w.batchState {
+		if w.batchState == "" {
+			fmt.Fprintf(w.out, "%s batch %s: %s\n", stamp, watchBatchName(batch), batchState)
+		} else {
+			fmt.Fprintf(w.out, "%s batch %s: %s -> %s\n", stamp, watchBatchName(batch), w.batchState, batchState)
+		}
+		w.batchState = batchState
+	}
+
+	for _, job := range jobs {
+		if job.JobID == nil {
+			continue
+		}
+		state := watchJobState(job)
+		previous, seen := w.jobStates[*job.JobID]
+		if seen && previous == state {
+			continue
+		}
+		if seen {
+			fmt.Fprintf(w.out, "%s %s: %s -> %s\n", stamp, watchJobName(job), previous, state)
+		} else {
+			fmt.Fprintf(w.out, "%s %s: %s\n", stamp, watchJobName(job), state)
+		}
+		w.jobStates[*job.JobID] = state
+	}
+	if len(jobs) > 0 && (!w.initialized || done != w.lastDone) {
+		fmt.Fprintf(w.out, "%s %d/%d tests finished\n", stamp, done, len(jobs))
+	}
+	w.lastDone = done
+}
+
+// getContainerStatuses fetches container states for the running tests of a batch.
+// Failures are ignored, since container states are only informational.
+func getContainerStatuses(projectID uuid.UUID, batchID uuid.UUID, jobs []api.Job) map[uuid.UUID][]api.ContainerStatusLine {
+	statuses := map[uuid.UUID][]api.ContainerStatusLine{}
+	requested := 0
+	for _, job := range jobs {
+		if requested >= watchContainerStatusLimit {
+			break
+		}
+		if job.JobID == nil || job.JobStatus == nil || *job.JobStatus != api.JobStatusEXPERIENCERUNNING {
+			continue
+		}
+		requested++
+		response, err := Client.GetContainerStatusWithResponse(context.Background(), projectID, batchID, *job.JobID)
+		if err != nil || response.HTTPResponse == nil || response.HTTPResponse.StatusCode != http.StatusOK || response.JSON200 == nil {
+			continue
+		}
+		statuses[*job.JobID] = response.JSON200.ContainerStatuses
+	}
+	return statuses
+}
+
+// watchForBatchCompletion is waitForBatchCompletion with a watcher rendering every poll.
+func watchForBatchCompletion(projectID uuid.UUID, batchID string, batchName string, timeout time.Duration, pollInterval time.Duration, watcher *batchWatcher) (*api.Batch, error) {
+	startTime := time.Now()
+
+	for {
+		batch := actualGetBatch(projectID, batchID, batchName)
+		if batch.Status == nil {
+			return nil, fmt.Errorf("no status returned")
+		}
+		// Follow the resolved ID from here on, in case a newer batch takes the name.
+		batchID, batchName = batch.BatchID.String(), ""
+
+		jobs := getAllJobs(projectID, *batch.BatchID)
+		var containers map[uuid.UUID][]api.ContainerStatusLine
+		if watcher.live {
+			containers = getContainerStatuses(projectID, *batch.BatchID, jobs)
+		}
+		watcher.update(batch, jobs, containers)
+
+		switch *batch.Status {
+		case api.BatchStatusSUCCEEDED, api.BatchStatusERROR, api.BatchStatusCANCELLED:
+			return batch, nil
+		case api.BatchStatusSUBMITTED, api.BatchStatusEXPERIENCESRUNNING, api.BatchStatusBATCHMETRICSQUEUED, api.BatchStatusBATCHMETRICSRUNNING:
+			// Continue waiting
+		default:
+			return nil, fmt.Errorf("unknown batch status: %s", *batch.Status)
+		}
+
+		if time.Now().After(startTime.Add(timeout)) {
+			return batch, &TimeoutError{message: fmt.Sprintf("timeout after %v, last state %s", timeout, *batch.Status)}
+		}
+
+		time.Sleep(pollInterval)
+	}
+}
+
+func watchBatch(ccmd *cobra.Command, args []string) {
+	actualWaitBatch(true)
+}
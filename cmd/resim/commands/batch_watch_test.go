@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+)
+
+func fixedWatcher(out *bytes.Buffer, live bool) *batchWatcher {
+	watcher := newBatchWatcher(out, live)
+	watcher.now = func() time.Time { return time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC) }
+	return watcher
+}
+
+func (s *CommandsSuite) TestBatchWatcherTransitions() {
+	var out bytes.Buffer
+	watcher := fixedWatcher(&out, false)
+	batch := &api.Batch{FriendlyName: Ptr("nightly"), Status: Ptr(api.BatchStatusSUBMITTED)}
+	laneChange, merge := uuid.New(), uuid.New()
+
+	watcher.update(batch, []api.Job{
+		testJob("lane-change", api.ConflatedJobStatusQUEUED, withJobID(laneChange), withJobStatus(api.JobStatusSUBMITTED)),
+		testJob("merge", api.ConflatedJobStatusQUEUED, withJobID(merge), withJobStatus(api.JobStatusSUBMITTED)),
+	}, nil)
+	s.Equal(strings.Join([]string{
+		"15:04:05 batch nightly: SUBMITTED",
+		"15:04:05 lane-change: SUBMITTED/QUEUED",
+		"15:04:05 merge: SUBMITTED/QUEUED",
+		"15:04:05 0/2 tests finished",
+		"",
+	}, "\n"), out.String())
+
+	// Nothing changed: nothing is printed.
+	out.Reset()
+	watcher.update(batch, []api.Job{
+		testJob("lane-change", api.ConflatedJobStatusQUEUED, withJobID(laneChange), withJobStatus(api.JobStatusSUBMITTED)),
+		testJob("merge", api.ConflatedJobStatusQUEUED, withJobID(merge), withJobStatus(api.JobStatusSUBMITTED)),
+	}, nil)
+	s.Empty(out.String())
+
+	out.Reset()
+	batch.Status = Ptr(api.BatchStatusEXPERIENCESRUNNING)
+	watcher.update(batch, []api.Job{
+		testJob("lane-change", api.ConflatedJobStatusERROR, withJobID(laneChange), withJobStatus(api.JobStatusERROR)),
+		testJob("merge", api.ConflatedJobStatusRUNNING, withJobID(merge), withJobStatus(api.JobStatusEXPERIENCERUNNING)),
+	}, nil)
+	s.Equal(strings.Join([]string{
+		"15:04:05 batch nightly: SUBMITTED -> EXPERIENCES_RUNNING",
+		"15:04:05 lane-change: SUBMITTED/QUEUED -> ERROR/ERROR",
+		"15:04:05 merge: SUBMITTED/QUEUED -> EXPERIENCE_RUNNING/RUNNING",
+		"15:04:05 1/2 tests finished",
+		"",
+	}, "\n"), out.String())
+}
+
+func (s *CommandsSuite) TestBatchWatcherFrame() {
+	var out bytes.Buffer
+	watcher := fixedWatcher(&out, true)
+	batch := &api.Batch{FriendlyName: Ptr("nightly"), Status: Ptr(api.BatchStatusEXPERIENCESRUNNING)}
+	running := testJob("zebra-crossing", api.ConflatedJobStatusRUNNING, withJobStatus(api.JobStatusEXPERIENCERUNNING))
+	running.CreationTimestamp = Ptr(watcher.now().Add(-90 * time.Second))
+	finished := testJob("alpha", api.ConflatedJobStatusPASSED, withJobStatus(api.JobStatusSUCCEEDED))
+	finished.CreationTimestamp = Ptr(watcher.now().Add(-time.Hour))
+	finished.LastUpdatedTimestamp = Ptr(watcher.now().Add(-50 * time.Minute))
+
+	watcher.update(batch, []api.Job{finished, running}, map[uuid.UUID][]api.ContainerStatusLine{
+		*running.JobID: {
+			{ContainerName: "planner", Status: "running"},
+			{ContainerName: "sidecar", Status: "terminated", ExitCode: Ptr(137)},
+		},
+	})
+
+	frame := out.String()
+	s.True(strings.HasPrefix(frame, clearScreen))
+	s.Contains(frame, "Batch nightly: EXPERIENCES_RUNNING (updated 15:04:05)")
+	s.Contains(frame, "tests finished  50%")
+	s.Contains(frame, "(1/2)")
+	runningRow := lineWith(frame, "zebra-crossing")
+	s.Contains(runningRow, "1m30s")
+	s.Contains(runningRow, "planner=running, sidecar=terminated (exit 137)")
+	s.Contains(lineWith(frame, "alpha"), "10m0s")
+	// Running tests are listed before finished ones.
+	s.Less(strings.Index(frame, "zebra-crossing"), strings.Index(frame, "alpha"))
+}
+
+func (s *CommandsSuite) TestGetContainerStatusesOnlyQueriesRunningTests() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	running := testJob("running", api.ConflatedJobStatusRUNNING, withJobStatus(api.JobStatusEXPERIENCERUNNING))
+	finished := testJob("finished", api.ConflatedJobStatusPASSED, withJobStatus(api.JobStatusSUCCEEDED))
+	failing := testJob("failing", api.ConflatedJobStatusRUNNING, withJobStatus(api.JobStatusEXPERIENCERUNNING))
+
+	s.mockClient.On("GetContainerStatusWithResponse", matchContext, projectID, batchID, *running.JobID).Return(
+		&api.GetContainerStatusResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.GetContainerStatusOutput{ContainerStatuses: []api.ContainerStatusLine{{ContainerName: "planner", Status: "running"}}},
+		}, nil)
+	s.mockClient.On("GetContainerStatusWithResponse", matchContext, projectID, batchID, *failing.JobID).Return(
+		&api.GetContainerStatusResponse{HTTPResponse: &http.Response{StatusCode: http.StatusInternalServerError}}, nil)
+
+	statuses := getContainerStatuses(projectID, batchID, []api.Job{running, finished, failing})
+	s.Len(statuses, 1)
+	s.Equal("planner", statuses[*running.JobID][0].ContainerName)
+	s.mockClient.AssertNumberOfCalls(s.T(), "GetContainerStatusWithResponse", 2)
+}
@@ -0,0 +1,387 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/bits"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/cmd/resim/commands/utils"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var bisectCmd = &cobra.Command{
+	Use:   "bisect",
+	Short: "bisect - Finds the first build on a branch that fails a test suite",
+	Long: `Binary-searches the builds of a branch between a known good and a known bad build.
+Each step runs the test suite (or just --experiences) on the midpoint build and waits for the batch.
+A batch passes when it succeeds without tests in --fail-on-states.
+
+Progress is saved to --state-file after every batch is created and finished. Running the same
+command again resumes the bisect, waiting on a batch that was still running rather than creating a new one.`,
+	Run: bisect,
+}
+
+const (
+	bisectProjectKey      = "project"
+	bisectTestSuiteKey    = "test-suite"
+	bisectGoodKey         = "good"
+	bisectBadKey          = "bad"
+	bisectExperiencesKey  = "experiences"
+	bisectPoolLabelsKey   = "pool-labels"
+	bisectAccountKey      = "account"
+	bisectFailOnStatesKey = "fail-on-states"
+	bisectStateFileKey    = "state-file"
+	bisectWaitTimeoutKey  = "wait-timeout"
+	bisectWaitPollKey     = "poll-every"
+)
+
+func init() {
+	bisectCmd.Flags().String(bisectProjectKey, "", "The name or ID of the project to bisect in")
+	bisectCmd.MarkFlagRequired(bisectProjectKey)
+	bisectCmd.Flags().String(bisectTestSuiteKey, "", "The name or ID of the test suite to run on each build")
+	bisectCmd.MarkFlagRequired(bisectTestSuiteKey)
+	bisectCmd.Flags().String(bisectGoodKey, "", "The ID of a build known to pass the test suite")
+	bisectCmd.MarkFlagRequired(bisectGoodKey)
+	bisectCmd.Flags().String(bisectBadKey, "", "The ID of a later build on the same branch known to fail the test suite")
+	bisectCmd.MarkFlagRequired(bisectBadKey)
+	bisectCmd.Flags().String(bisectExperiencesKey, "", "(Optional) Comma-separated experience names or IDs to run instead of the whole test suite")
+	bisectCmd.Flags().StringSlice(bisectPoolLabelsKey, []string{}, "Pool labels to determine where to run the batches. Pool labels are interpreted as a logical AND. Accepts repeated labels or comma-separated labels.")
+	bisectCmd.Flags().String(bisectAccountKey, "", "Specify a username for a CI/CD platform account to associate with the batches.")
+	bisectCmd.Flags().String(bisectFailOnStatesKey, "BLOCKER,ERROR", "Comma-separated list of conflated states that make a build fail (WARNING, ERROR, BLOCKER)")
+	bisectCmd.Flags().String(bisectStateFileKey, ".resim-bisect.json", "The file to save progress to and resume from")
+	bisectCmd.Flags().String(bisectWaitTimeoutKey, "1h", "Amount of time to wait for each batch to finish, expressed in Golang duration string.")
+	bisectCmd.Flags().String(bisectWaitPollKey, "30s", "Interval between checking batch status, expressed in Golang duration string.")
+	bisectCmd.Flags().SetNormalizeFunc(aliasProjectNameFunc)
+	rootCmd.AddCommand(bisectCmd)
+}
+
+// bisectState is the progress of a bisect, saved between runs of the command.
+type bisectState struct {
+	ProjectID         uuid.UUID `json:"projectID"`
+	TestSuiteID       uuid.UUID `json:"testSuiteID"`
+	TestSuiteRevision int32     `json:"testSuiteRevision"`
+	GoodBuildID       uuid.UUID `json:"goodBuildID"`
+	BadBuildID        uuid.UUID `json:"badBuildID"`
+	Experiences       string    `json:"experiences,omitempty"`
+	// FailOnStates judged the finished runs, sorted so that the order they were
+	// given in doesn't matter.
+	FailOnStates []api.ConflatedBatchStatus `json:"failOnStates"`
+	// Builds are the candidate builds, oldest first, from the good build to the
+	// bad one.
+	Builds []bisectBuild `json:"builds"`
+	Runs   []bisectRun   `json:"runs"`
+}
+
+type bisectBuild struct {
+	BuildID uuid.UUID `json:"buildID"`
+	Name    string    `json:"name"`
+	Version string    `json:"version"`
+}
+
+// bisectRun is the batch run on one build. Passed is unset while the batch is
+// still running.
+type bisectRun struct {
+	BuildID uuid.UUID `json:"buildID"`
+	BatchID uuid.UUID `json:"batchID"`
+	Passed  *bool     `json:"passed,omitempty"`
+}
+
+// bisectOptions are the settings for running and judging each step's batch.
+type bisectOptions struct {
+	TestSuite         *api.TestSuite
+	PoolLabels        []api.PoolLabel
+	AssociatedAccount string
+	FailOnStates      []api.ConflatedBatchStatus
+	Timeout           time.Duration
+	PollInterval      time.Duration
+}
+
+func (s *bisectState) matches(other *bisectState) bool {
+	return s.ProjectID == other.ProjectID &&
+		s.TestSuiteID == other.TestSuiteID &&
+		s.GoodBuildID == other.GoodBuildID &&
+		s.BadBuildID == other.BadBuildID &&
+		s.Experiences == other.Experiences &&
+		slices.Equal(s.FailOnStates, other.FailOnStates)
+}
+
+func (s *bisectState) run(buildID uuid.UUID) *bisectRun {
+	for i := range s.Runs {
+		if s.Runs[i].BuildID == buildID {
+			return &s.Runs[i]
+		}
+	}
+	return nil
+}
+
+// loadBisectState reads a state file, returning nil when it does not exist.
+func loadBisectState(path string) (*bisectState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bisect state: %w", err)
+	}
+	var state bisectState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse bisect state %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+func saveBisectState(path string, state *bisectState) {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		log.Fatal("failed to encode bisect state: ", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		log.Fatal("failed to write bisect state: ", err)
+	}
+}
+
+func bisect(ccmd *cobra.Command, args []string) {
+	projectID := getProjectID(Client, viper.GetString(bisectProjectKey))
+	testSuite := actualGetTestSuite(projectID, viper.GetString(bisectTestSuiteKey), nil, false)
+	goodBuildID, err := uuid.Parse(viper.GetString(bisectGoodKey))
+	if err != nil {
+		log.Fatal("failed to parse good build ID: ", err)
+	}
+	badBuildID, err := uuid.Parse(viper.GetString(bisectBadKey))
+	if err != nil {
+		log.Fatal("failed to parse bad build ID: ", err)
+	}
+
+	failOnStates := parseConflatedBatchStates(viper.GetString(bisectFailOnStatesKey))
+	if len(failOnStates) == 0 {
+		log.Fatalf("--%s must list at least one state", bisectFailOnStatesKey)
+	}
+	failOnStates = slices.Compact(slices.Sorted(slices.Values(failOnStates)))
+	timeout, err := time.ParseDuration(viper.GetString(bisectWaitTimeoutKey))
+	if err != nil {
+		log.Fatalf("failed to parse timeout: %v", err)
+	}
+	pollInterval, err := time.ParseDuration(viper.GetString(bisectWaitPollKey))
+	if err != nil {
+		log.Fatalf("failed to parse poll interval: %v", err)
+	}
+	associatedAccount := GetCIEnvironmentVariableAccount()
+	if viper.IsSet(bisectAccountKey) {
+		associatedAccount = viper.GetString(bisectAccountKey)
+	}
+
+	statePath := viper.GetString(bisectStateFileKey)
+	wanted := &bisectState{
+		ProjectID:         projectID,
+		TestSuiteID:       testSuite.TestSuiteID,
+		TestSuiteRevision: testSuite.TestSuiteRevision,
+		GoodBuildID:       goodBuildID,
+		BadBuildID:        badBuildID,
+		Experiences:       viper.GetString(bisectExperiencesKey),
+		FailOnStates:      failOnStates,
+	}
+	state, err := loadBisectState(statePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if state != nil {
+		if !state.matches(wanted) {
+			log.Fatalf("state file %s belongs to a different bisect (other builds, test suite, --%s or --%s); remove it or choose another --%s", statePath, bisectExperiencesKey, bisectFailOnStatesKey, bisectStateFileKey)
+		}
+		fmt.Printf("Resuming bisect from %s\n", statePath)
+		// Keep running the revision the bisect started with.
+		if state.TestSuiteRevision != testSuite.TestSuiteRevision {
+			testSuite = actualGetTestSuite(projectID, testSuite.TestSuiteID.String(), Ptr(state.TestSuiteRevision), false)
+		}
+	} else {
+		state = wanted
+		state.Builds = bisectCandidateBuilds(projectID, goodBuildID, badBuildID)
+		saveBisectState(statePath, state)
+	}
+
+	lastGood, firstBad := runBisect(projectID, state, statePath, bisectOptions{
+		TestSuite:         testSuite,
+		PoolLabels:        getAndValidatePoolLabels(bisectPoolLabelsKey),
+		AssociatedAccount: associatedAccount,
+		FailOnStates:      failOnStates,
+		Timeout:           timeout,
+		PollInterval:      pollInterval,
+	})
+
+	fmt.Println()
+	fmt.Printf("First failing build: %s (%s)\n", firstBad.Name, firstBad.BuildID)
+	fmt.Printf("Version: %s\n", firstBad.Version)
+	fmt.Printf("Last passing build: %s (%s), version %s\n", lastGood.Name, lastGood.BuildID, lastGood.Version)
+}
+
+// bisectCandidateBuilds lists the builds from the good build to the bad one,
+// oldest first. Only builds of the bad build's branch and system are included.
+func bisectCandidateBuilds(projectID uuid.UUID, goodBuildID uuid.UUID, badBuildID uuid.UUID) []bisectBuild {
+	good := getBisectBuild(projectID, goodBuildID)
+	bad := getBisectBuild(projectID, badBuildID)
+	if good.BranchID != bad.BranchID {
+		log.Fatal("the good and bad builds must be on the same branch")
+	}
+
+	var builds []api.Build
+	for _, build := range listBuildsByBranch(projectID, bad.BranchID) {
+		if build.SystemID == bad.SystemID {
+			builds = append(builds, build)
+		}
+	}
+	sort.SliceStable(builds, func(i, j int) bool {
+		return builds[i].CreationTimestamp.Before(builds[j].CreationTimestamp)
+	})
+
+	goodIndex, badIndex := -1, -1
+	for i, build := range builds {
+		switch build.BuildID {
+		case goodBuildID:
+			goodIndex = i
+		case badBuildID:
+			badIndex = i
+		}
+	}
+	if goodIndex < 0 || badIndex < 0 {
+		log.Fatal("the good and bad builds must belong to the same system")
+	}
+	if goodIndex >= badIndex {
+		log.Fatal("the good build must be older than the bad build")
+	}
+
+	candidates := make([]bisectBuild, 0, badIndex-goodIndex+1)
+	for _, build := range builds[goodIndex : badIndex+1] {
+		candidates = append(candidates, bisectBuild{BuildID: build.BuildID, Name: build.Name, Version: build.Version})
+	}
+	return candidates
+}
+
+func getBisectBuild(projectID uuid.UUID, buildID uuid.UUID) *api.Build {
+	response, err := Client.GetBuildWithResponse(context.Background(), projectID, buildID)
+	if err != nil {
+		log.Fatal("unable to retrieve build:", err)
+	}
+	ValidateResponse(http.StatusOK, fmt.Sprintf("unable to retrieve build %s", buildID), response.HTTPResponse, response.Body)
+	if response.JSON200 == nil {
+		log.Fatal("empty response")
+	}
+	return response.JSON200
+}
+
+// runBisect binary-searches the candidate builds, whose first build is known to
+// pass and last build is known to fail, and returns the last passing and first
+// failing builds. Builds already judged in the state are not run again.
+func runBisect(projectID uuid.UUID, state *bisectState, statePath string, opts bisectOptions) (bisectBuild, bisectBuild) {
+	lo, hi := 0, len(state.Builds)-1
+	fmt.Printf("Bisecting %s between the good and bad builds (about %s)\n",
+		pluralize(hi-lo-1, "build"), pluralize(bits.Len(uint(hi-lo-1)), "step"))
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		build := state.Builds[mid]
+		passed := bisectTestBuild(projectID, state, statePath, build, opts)
+		result := "failed"
+		if passed {
+			result = "passed"
+			lo = mid
+		} else {
+			hi = mid
+		}
+		fmt.Printf("Build %s (%s) %s; %s left\n", build.Name, build.Version, result, pluralize(hi-lo-1, "build"))
+	}
+	return state.Builds[lo], state.Builds[hi]
+}
+
+// bisectTestBuild runs the test suite on a build, or resumes waiting on the
+// batch a previous run created, and reports whether it passed.
+func bisectTestBuild(projectID uuid.UUID, state *bisectState, statePath string, build bisectBuild, opts bisectOptions) bool {
+	run := state.run(build.BuildID)
+	if run != nil && run.Passed != nil {
+		return *run.Passed
+	}
+	if run == nil {
+		batch := createBisectBatch(projectID, state, build.BuildID, opts)
+		state.Runs = append(state.Runs, bisectRun{BuildID: build.BuildID, BatchID: *batch.BatchID})
+		saveBisectState(statePath, state)
+		run = state.run(build.BuildID)
+		fmt.Printf("Testing build %s (%s) in batch %s\n", build.Name, build.Version, *batch.FriendlyName)
+	} else {
+		fmt.Printf("Waiting for batch %s of build %s (%s)\n", run.BatchID, build.Name, build.Version)
+	}
+
+	batch, err := waitForBatchCompletion(projectID, run.BatchID.String(), "", opts.Timeout, opts.PollInterval)
+	if err != nil {
+		log.Fatalf("batch %s of build %s did not finish: %v; run the command again to keep waiting", run.BatchID, build.BuildID, err)
+	}
+	if *batch.Status == api.BatchStatusCANCELLED {
+		// Forget the batch so that the next run tests the build again.
+		for i := range state.Runs {
+			if state.Runs[i].BuildID == build.BuildID {
+				state.Runs = append(state.Runs[:i], state.Runs[i+1:]...)
+				break
+			}
+		}
+		saveBisectState(statePath, state)
+		log.Fatalf("batch %s of build %s was cancelled; run the command again to retry the build", run.BatchID, build.BuildID)
+	}
+
+	passed := computeExitCode([]*SuperviseResult{{Batch: batch}}, exitCodeOptions{failOnStates: opts.FailOnStates}) == exitCodeSucceeded
+	run.Passed = &passed
+	saveBisectState(statePath, state)
+	return passed
+}
+
+// createBisectBatch runs the test suite revision on a build, or an adhoc batch
+// of the suite's metrics configuration when only some experiences are run.
+func createBisectBatch(projectID uuid.UUID, state *bisectState, buildID uuid.UUID, opts bisectOptions) api.Batch {
+	var batch *api.Batch
+	if state.Experiences == "" {
+		body := api.TestSuiteBatchInput{
+			BuildID:           buildID,
+			AssociatedAccount: &opts.AssociatedAccount,
+		}
+		if len(opts.PoolLabels) > 0 {
+			body.PoolLabels = &opts.PoolLabels
+		}
+		response, err := Client.CreateBatchForTestSuiteRevisionWithResponse(context.Background(), projectID, state.TestSuiteID, state.TestSuiteRevision, body)
+		if err != nil {
+			log.Fatal("failed to run test suite:", err)
+		}
+		ValidateResponse(http.StatusCreated, "failed to run test suite", response.HTTPResponse, response.Body)
+		batch = response.JSON201
+	} else {
+		experienceIDs, experienceNames := parseUUIDsAndNames(state.Experiences)
+		body := api.BatchInput{
+			BuildID:           &buildID,
+			ExperienceIDs:     &experienceIDs,
+			ExperienceNames:   &experienceNames,
+			MetricsBuildID:    opts.TestSuite.MetricsBuildID,
+			MetricsSetName:    NormalizeMetricsSetName(opts.TestSuite.MetricsSetName),
+			AssociatedAccount: &opts.AssociatedAccount,
+		}
+		if len(opts.PoolLabels) > 0 {
+			body.PoolLabels = &opts.PoolLabels
+		}
+		response, err := Client.CreateBatchWithResponse(context.Background(), projectID, body)
+		if err != nil {
+			log.Fatal("failed to create batch:", err)
+		}
+		ValidateResponse(http.StatusCreated, "failed to create batch", response.HTTPResponse, response.Body)
+		batch = response.JSON201
+	}
+	if batch == nil || batch.BatchID == nil || batch.FriendlyName == nil {
+		log.Fatal("empty response")
+	}
+	return *batch
+}
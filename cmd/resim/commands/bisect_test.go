@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+)
+
+// setupBisectMocks mocks a branch of seven builds, listed newest first, plus a
+// build of another system. Builds from index firstBad on fail the test suite.
+func (s *CommandsSuite) setupBisectMocks(firstBad int) ([]api.Build, map[uuid.UUID]uuid.UUID) {
+	viper.Reset()
+	projectID := uuid.New()
+	testSuiteID := uuid.New()
+	branchID := uuid.New()
+	systemID := uuid.New()
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	builds := make([]api.Build, 7)
+	for i := range builds {
+		builds[i] = api.Build{
+			BuildID:           uuid.New(),
+			BranchID:          branchID,
+			SystemID:          systemID,
+			Name:              "nightly-" + string(rune('a'+i)),
+			Version:           "sha" + string(rune('a'+i)),
+			CreationTimestamp: start.Add(time.Duration(i) * time.Hour),
+		}
+	}
+	listed := []api.Build{{BuildID: uuid.New(), BranchID: branchID, SystemID: uuid.New(), CreationTimestamp: start.Add(90 * time.Minute)}}
+	for i := len(builds) - 1; i >= 0; i-- {
+		listed = append(listed, builds[i])
+	}
+
+	viper.Set(bisectProjectKey, projectID.String())
+	viper.Set(bisectTestSuiteKey, testSuiteID.String())
+	viper.Set(bisectGoodKey, builds[0].BuildID.String())
+	viper.Set(bisectBadKey, builds[6].BuildID.String())
+	viper.Set(bisectFailOnStatesKey, "BLOCKER,ERROR")
+	viper.Set(bisectStateFileKey, filepath.Join(s.T().TempDir(), "bisect.json"))
+	viper.Set(bisectWaitTimeoutKey, "1m")
+	viper.Set(bisectWaitPollKey, "1ms")
+
+	s.mockClient.On("GetProjectWithResponse", matchContext, projectID).Return(&api.GetProjectResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Project{ProjectID: projectID, Name: "test-project"},
+	}, nil)
+	s.mockClient.On("GetTestSuiteWithResponse", matchContext, projectID, testSuiteID).Return(&api.GetTestSuiteResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.TestSuite{TestSuiteID: testSuiteID, TestSuiteRevision: 3},
+	}, nil)
+	for _, i := range []int{0, 6} {
+		s.mockClient.On("GetBuildWithResponse", matchContext, projectID, builds[i].BuildID).Return(&api.GetBuildResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &builds[i],
+		}, nil).Maybe()
+	}
+	s.mockClient.On("ListBuildsForBranchesWithResponse", matchContext, projectID, []api.BranchID{branchID}, mock.Anything).Return(&api.ListBuildsForBranchesResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListBuildsOutput{Builds: listed},
+	}, nil).Maybe()
+
+	batchBuilds := map[uuid.UUID]uuid.UUID{}
+	for i, build := range builds {
+		batchID := uuid.New()
+		batchBuilds[batchID] = build.BuildID
+		conflated := api.ConflatedBatchStatusCOMPLETE
+		if i >= firstBad {
+			conflated = api.ConflatedBatchStatusBLOCKER
+		}
+		s.mockClient.On("CreateBatchForTestSuiteRevisionWithResponse", matchContext, projectID, testSuiteID, int32(3), mock.MatchedBy(func(body api.TestSuiteBatchInput) bool {
+			return body.BuildID == build.BuildID
+		})).Return(&api.CreateBatchForTestSuiteRevisionResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusCreated},
+			JSON201:      &api.Batch{BatchID: Ptr(batchID), FriendlyName: Ptr("bisect-batch")},
+		}, nil).Maybe()
+		s.mockClient.On("GetBatchWithResponse", matchContext, projectID, batchID).Return(&api.GetBatchResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200: &api.Batch{
+				BatchID:         Ptr(batchID),
+				Status:          Ptr(api.BatchStatusSUCCEEDED),
+				ConflatedStatus: Ptr(conflated),
+			},
+		}, nil).Maybe()
+	}
+	return builds, batchBuilds
+}
+
+func (s *CommandsSuite) TestBisectFindsFirstFailingBuild() {
+	builds, batchBuilds := s.setupBisectMocks(4)
+
+	output := captureStdout(s, func() { bisect(nil, nil) })
+
+	s.Contains(output, "Bisecting 5 builds between the good and bad builds (about 3 steps)")
+	s.Contains(output, "Build nightly-d (shad) passed; 2 builds left")
+	s.Contains(output, "First failing build: nightly-e ("+builds[4].BuildID.String()+")")
+	s.Contains(output, "Version: shae")
+	s.Contains(output, "Last passing build: nightly-d")
+
+	// Builds d and e were tested; the build of the other system was skipped.
+	state, err := loadBisectState(viper.GetString(bisectStateFileKey))
+	s.Require().NoError(err)
+	s.Len(state.Builds, 7)
+	var tested []uuid.UUID
+	for _, run := range state.Runs {
+		s.Equal(run.BuildID, batchBuilds[run.BatchID])
+		s.NotNil(run.Passed)
+		tested = append(tested, run.BuildID)
+	}
+	s.Equal([]uuid.UUID{builds[3].BuildID, builds[4].BuildID}, tested)
+}
+
+func (s *CommandsSuite) TestBisectResumesFromStateFile() {
+	builds, batchBuilds := s.setupBisectMocks(1)
+	statePath := viper.GetString(bisectStateFileKey)
+
+	// A previous run judged build d and created a batch for build b, which is
+	// still unfinished.
+	var batchB uuid.UUID
+	for batchID, buildID := range batchBuilds {
+		if buildID == builds[1].BuildID {
+			batchB = batchID
+		}
+	}
+	state := &bisectState{
+		ProjectID:         uuid.MustParse(viper.GetString(bisectProjectKey)),
+		TestSuiteID:       uuid.MustParse(viper.GetString(bisectTestSuiteKey)),
+		TestSuiteRevision: 3,
+		GoodBuildID:       builds[0].BuildID,
+		BadBuildID:        builds[6].BuildID,
+		FailOnStates:      []api.ConflatedBatchStatus{api.ConflatedBatchStatusBLOCKER, api.ConflatedBatchStatusERROR},
+		Runs: []bisectRun{
+			{BuildID: builds[3].BuildID, BatchID: uuid.New(), Passed: Ptr(false)},
+			{BuildID: builds[1].BuildID, BatchID: batchB},
+		},
+	}
+	for _, build := range builds {
+		state.Builds = append(state.Builds, bisectBuild{BuildID: build.BuildID, Name: build.Name, Version: build.Version})
+	}
+	saveBisectState(statePath, state)
+
+	output := captureStdout(s, func() { bisect(nil, nil) })
+
+	s.Contains(output, "Resuming bisect from "+statePath)
+	s.Contains(output, "Waiting for batch "+batchB.String())
+	s.Contains(output, "First failing build: nightly-b")
+	s.mockClient.AssertNotCalled(s.T(), "ListBuildsForBranchesWithResponse", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	s.mockClient.AssertNumberOfCalls(s.T(), "CreateBatchForTestSuiteRevisionWithResponse", 0)
+}
+
+func (s *CommandsSuite) TestBisectStateMatchesFailOnStates() {
+	state := &bisectState{
+		ProjectID:    uuid.New(),
+		TestSuiteID:  uuid.New(),
+		GoodBuildID:  uuid.New(),
+		BadBuildID:   uuid.New(),
+		FailOnStates: []api.ConflatedBatchStatus{api.ConflatedBatchStatusBLOCKER, api.ConflatedBatchStatusERROR},
+	}
+	same := *state
+	s.True(state.matches(&same))
+
+	// The saved runs were judged with other states, so they can't be reused.
+	other := *state
+	other.FailOnStates = []api.ConflatedBatchStatus{api.ConflatedBatchStatusBLOCKER}
+	s.False(state.matches(&other))
+}
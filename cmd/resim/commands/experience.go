@@ -7,6 +7,7 @@ import (
 	"log"
 	"math"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -106,6 +107,12 @@ const (
 	experiencesCloneKey               = "clone"
 	experiencesSyncNoArchiveKey       = "no-archive"
 	experiencesUpdateConfigKey        = "update-config"
+	experiencesPlanKey                = "plan"
+	experiencesPlanOutKey             = "plan-out"
+	experiencesApplyPlanKey           = "apply-plan"
+	experiencesCheckKey               = "check"
+	experiencesCreateMissingKey       = "create-missing"
+	experiencesDeleteUnusedTagsKey    = "delete-unused-tags"
 	experienceIDKey                   = "id"
 	experienceDescriptionKey          = "description"
 	experienceLocationKey             = "location"
@@ -204,8 +211,7 @@ func init() {
 	// Sync command
 	syncExperienceCmd.Flags().String(experienceProjectKey, "", "The name or ID of the project to update the experiences within")
 	syncExperienceCmd.MarkFlagRequired(experienceProjectKey)
-	syncExperienceCmd.Flags().String(experiencesConfigKey, "", "The path of the experiences config file to sync")
-	syncExperienceCmd.MarkFlagRequired(experiencesConfigKey)
+	syncExperienceCmd.Flags().String(experiencesConfigKey, "", "The path of the experiences config file to sync, or a glob matching several files. A file can include others with an `include` list of paths or globs relative to it")
 	syncExperienceCmd.Flags().Bool(experiencesUpdateConfigKey, false, "Whether to update the passed-in config in-place")
 	syncExperienceCmd.Flags().Bool(experiencesSyncNoArchiveKey, false, "Whether to archive experiences not listed in the config file")
 
@@ -213,6 +219,31 @@ func init() {
 	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesUpdateConfigKey, experiencesCloneKey)
 	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesSyncNoArchiveKey, experiencesCloneKey)
 
+	syncExperienceCmd.Flags().Bool(experiencesPlanKey, false, "Print the changes the sync would make without making them")
+	syncExperienceCmd.Flags().String(experiencesPlanOutKey, "", "(Optional) With --plan, a file to save the plan to for --apply-plan")
+	syncExperienceCmd.Flags().String(experiencesApplyPlanKey, "", "Apply the changes of a plan saved with --plan-out. Fails if the experiences, tags, systems or test suites have changed since the plan was made")
+	syncExperienceCmd.MarkFlagsOneRequired(experiencesConfigKey, experiencesApplyPlanKey)
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesApplyPlanKey, experiencesConfigKey)
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesApplyPlanKey, experiencesSyncNoArchiveKey)
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesApplyPlanKey, experiencesUpdateConfigKey)
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesApplyPlanKey, experiencesCloneKey)
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesPlanKey, experiencesApplyPlanKey)
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesPlanKey, experiencesUpdateConfigKey)
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesPlanKey, experiencesCloneKey)
+
+	syncExperienceCmd.Flags().Bool(experiencesCheckKey, false, fmt.Sprintf("Check that the experiences, tags, systems and test suites match the config without changing them. Prints the differences and exits with code %d if they don't", exitCodeSyncDrift))
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesCheckKey, experiencesUpdateConfigKey)
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesCheckKey, experiencesCloneKey)
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesCheckKey, experiencesPlanKey)
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesCheckKey, experiencesApplyPlanKey)
+
+	syncExperienceCmd.Flags().Bool(experiencesCreateMissingKey, false, "Create the experience tags and test suites in the config that don't exist. Test suites to create need a system, and optionally a description and metricsBuildID, in the config")
+	syncExperienceCmd.Flags().Bool(experiencesDeleteUnusedTagsKey, false, "Delete managed experience tags that no unarchived experience has after the sync")
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesCreateMissingKey, experiencesCloneKey)
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesCreateMissingKey, experiencesApplyPlanKey)
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesDeleteUnusedTagsKey, experiencesCloneKey)
+	syncExperienceCmd.MarkFlagsMutuallyExclusive(experiencesDeleteUnusedTagsKey, experiencesApplyPlanKey)
+
 	experienceCmd.AddCommand(syncExperienceCmd)
 
 	// Systems-related sub-commands:
@@ -713,17 +744,41 @@ pageLoop:
 	return experienceID
 }
 
+// exitCodeSyncDrift is the exit code of `experiences sync --check` when the
+// experiences differ from the config. Errors exit with code 1.
+const exitCodeSyncDrift = 2
+
 func syncExperience(ccmd *cobra.Command, args []string) {
 	projectID := getProjectID(Client, viper.GetString(experienceProjectKey))
 	configPath := viper.GetString(experiencesConfigKey)
 	updateConfig := viper.GetBool(experiencesUpdateConfigKey)
-	shouldArchive := !viper.GetBool(experiencesSyncNoArchiveKey)
+	options := experience_sync.SyncOptions{
+		ShouldArchive:    !viper.GetBool(experiencesSyncNoArchiveKey),
+		CreateMissing:    viper.GetBool(experiencesCreateMissingKey),
+		DeleteUnusedTags: viper.GetBool(experiencesDeleteUnusedTagsKey),
+	}
 	clone := viper.GetBool(experiencesCloneKey)
 
-	if !clone {
-		experience_sync.SyncExperiences(Client, projectID, configPath, updateConfig, shouldArchive)
-	} else {
+	planPath := viper.GetString(experiencesPlanOutKey)
+	applyPlanPath := viper.GetString(experiencesApplyPlanKey)
+
+	if planPath != "" && !viper.GetBool(experiencesPlanKey) {
+		log.Fatalf("--%s requires --%s", experiencesPlanOutKey, experiencesPlanKey)
+	}
+
+	switch {
+	case applyPlanPath != "":
+		experience_sync.ApplyExperienceSyncPlan(Client, projectID, applyPlanPath)
+	case viper.GetBool(experiencesCheckKey):
+		if !experience_sync.CheckExperienceSync(Client, projectID, configPath, options) {
+			os.Exit(exitCodeSyncDrift)
+		}
+	case viper.GetBool(experiencesPlanKey):
+		experience_sync.PlanExperienceSync(Client, projectID, configPath, options, planPath)
+	case clone:
 		experience_sync.CloneExperiences(Client, projectID, configPath)
+	default:
+		experience_sync.SyncExperiences(Client, projectID, configPath, updateConfig, options)
 	}
 }
 
@@ -0,0 +1,378 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/cmd/resim/commands/utils"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var historyExperienceCmd = &cobra.Command{
+	Use:   "history",
+	Short: "history - Lists the tests that ran an experience, most recent first",
+	Long: `Lists the tests that ran an experience across batches, most recent first, with their batch,
+build version, status, conflated status and duration.
+
+The API can't list the tests of one experience, so this is a client-side scan: the project's tests
+are fetched most recent first and filtered locally until --limit matching tests are found, the
+scan reaches tests created before --since, or 5,000 tests have been scanned.
+
+PASSED and WARNING tests count as passing and BLOCKER and ERROR tests as failing. The PASS RATE
+column is the pass rate over the --window most recent tests up to that row. A summary gives the
+overall pass rate, the median duration and, when the latest tests fail, the test the failures
+started with.`,
+	Run: listExperienceHistory,
+}
+
+const (
+	experienceBranchKey = "branch"
+	experienceLimitKey  = "limit"
+	experienceWindowKey = "window"
+	experienceOutputKey = "output"
+	experienceSinceKey  = "since"
+)
+
+// experienceHistoryPageSize is the page size requested when scanning the project's tests.
+const experienceHistoryPageSize = 100
+
+// experienceHistoryMaxPages bounds the scan of the project's tests, whatever --since and
+// --limit allow.
+const experienceHistoryMaxPages = 50
+
+func init() {
+	historyExperienceCmd.Flags().String(experienceProjectKey, "", "The name or ID of the project the experience belongs to")
+	historyExperienceCmd.MarkFlagRequired(experienceProjectKey)
+	historyExperienceCmd.Flags().String(experienceKey, "", "The name or ID of the experience")
+	historyExperienceCmd.MarkFlagRequired(experienceKey)
+	historyExperienceCmd.Flags().String(experienceSystemKey, "", "(Optional) Only list tests of builds for this system (name or ID)")
+	historyExperienceCmd.Flags().String(experienceBranchKey, "", "(Optional) Only list tests of builds on this branch (name or ID)")
+	historyExperienceCmd.Flags().Int(experienceLimitKey, 50, "The maximum number of tests to list. 0 lists every test found by the scan.")
+	historyExperienceCmd.Flags().String(experienceSinceKey, "720h", "Stop scanning at tests created before this time: an RFC 3339 timestamp, a date (YYYY-MM-DD) or a duration before now (e.g. 168h). Empty scans back to the oldest test, up to the scan's cap.")
+	historyExperienceCmd.Flags().Int(experienceWindowKey, 10, "The number of tests the rolling pass rate is computed over")
+	historyExperienceCmd.Flags().String(experienceOutputKey, batchOutputTable, "Output format: table or json")
+	historyExperienceCmd.Flags().SetNormalizeFunc(aliasProjectNameFunc)
+	experienceCmd.AddCommand(historyExperienceCmd)
+}
+
+// experienceRun is one test of an experience.
+type experienceRun struct {
+	JobID           uuid.UUID  `json:"jobID"`
+	BatchID         uuid.UUID  `json:"batchID"`
+	BuildID         uuid.UUID  `json:"buildID"`
+	BuildVersion    string     `json:"buildVersion"`
+	Status          string     `json:"status"`
+	ConflatedStatus string     `json:"conflatedStatus"`
+	Created         *time.Time `json:"created,omitempty"`
+	// DurationSeconds is unset until the test has finished.
+	DurationSeconds *float64 `json:"durationSeconds,omitempty"`
+	// RollingPassRate is the pass rate over the window of tests ending with this
+	// one, unset when none of them passed or failed.
+	RollingPassRate *float64 `json:"rollingPassRate,omitempty"`
+}
+
+type experienceHistoryStats struct {
+	Runs                  int      `json:"runs"`
+	Passed                int      `json:"passed"`
+	Failed                int      `json:"failed"`
+	PassRate              *float64 `json:"passRate,omitempty"`
+	MedianDurationSeconds *float64 `json:"medianDurationSeconds,omitempty"`
+	// FailingSince is the oldest test of the current streak of failures.
+	FailingSince *experienceRun `json:"failingSince,omitempty"`
+}
+
+type experienceHistory struct {
+	ExperienceID uuid.UUID              `json:"experienceID"`
+	Name         string                 `json:"name"`
+	Runs         []experienceRun        `json:"runs"`
+	Stats        experienceHistoryStats `json:"stats"`
+}
+
+// experienceHistoryFilter selects the tests of an experience. Zero IDs match
+// every system or branch, and a zero Since scans every test.
+type experienceHistoryFilter struct {
+	ExperienceID uuid.UUID
+	SystemID     uuid.UUID
+	BranchID     uuid.UUID
+	Since        time.Time
+}
+
+func (f experienceHistoryFilter) matches(job api.Job) bool {
+	if job.ExperienceID == nil || *job.ExperienceID != f.ExperienceID {
+		return false
+	}
+	if f.SystemID != uuid.Nil && (job.SystemID == nil || *job.SystemID != f.SystemID) {
+		return false
+	}
+	if f.BranchID != uuid.Nil && (job.BranchID == nil || *job.BranchID != f.BranchID) {
+		return false
+	}
+	return true
+}
+
+func listExperienceHistory(ccmd *cobra.Command, args []string) {
+	projectID := getProjectID(Client, viper.GetString(experienceProjectKey))
+	output := viper.GetString(experienceOutputKey)
+	if output != batchOutputTable && output != batchOutputJSON {
+		log.Fatalf("Unsupported output: %s. Valid outputs are: %s, %s", output, batchOutputTable, batchOutputJSON)
+	}
+	window := viper.GetInt(experienceWindowKey)
+	if window <= 0 {
+		log.Fatalf("--%s must be at least 1", experienceWindowKey)
+	}
+
+	experienceID := getExperienceID(Client, projectID, viper.GetString(experienceKey), true, false)
+	filter := experienceHistoryFilter{ExperienceID: experienceID}
+	if viper.IsSet(experienceSystemKey) {
+		filter.SystemID = getSystemID(Client, projectID, viper.GetString(experienceSystemKey), true)
+	}
+	if viper.IsSet(experienceBranchKey) {
+		filter.BranchID = getBranchID(Client, projectID, viper.GetString(experienceBranchKey), true)
+	}
+	if raw := viper.GetString(experienceSinceKey); raw != "" {
+		since, err := parseBatchListTime(raw, time.Now())
+		if err != nil {
+			log.Fatalf("invalid --%s: %v", experienceSinceKey, err)
+		}
+		filter.Since = since
+	}
+
+	jobs := listExperienceJobs(projectID, filter, viper.GetInt(experienceLimitKey))
+	history := buildExperienceHistory(projectID, experienceID, jobs, window)
+
+	if output == batchOutputJSON {
+		OutputJson(history)
+	} else {
+		fmt.Print(formatExperienceHistory(history, window))
+	}
+}
+
+// listExperienceJobs pages through the project's tests, most recent first, and
+// returns those matching the filter, up to limit (0 means no limit). The scan
+// stops at the first test created before filter.Since and after
+// experienceHistoryMaxPages pages.
+func listExperienceJobs(projectID uuid.UUID, filter experienceHistoryFilter, limit int) []api.Job {
+	var jobs []api.Job
+	var pageToken *string = nil
+	for page := 0; ; page++ {
+		if page == experienceHistoryMaxPages {
+			log.Printf("Stopped after scanning the %d most recent tests of the project; use --%s to scan a shorter period\n", experienceHistoryMaxPages*experienceHistoryPageSize, experienceSinceKey)
+			return jobs
+		}
+		response, err := Client.ListAllJobsWithResponse(context.Background(), projectID, &api.ListAllJobsParams{
+			PageSize:  Ptr(experienceHistoryPageSize),
+			PageToken: pageToken,
+			OrderBy:   Ptr("timestamp"),
+		})
+		if err != nil {
+			log.Fatal("unable to list tests:", err)
+		}
+		ValidateResponse(http.StatusOK, "unable to list tests", response.HTTPResponse, response.Body)
+		if response.JSON200 == nil {
+			log.Fatal("empty response")
+		}
+		if response.JSON200.Jobs != nil {
+			for _, job := range *response.JSON200.Jobs {
+				if !filter.Since.IsZero() && job.CreationTimestamp != nil && job.CreationTimestamp.Before(filter.Since) {
+					return jobs
+				}
+				if !filter.matches(job) {
+					continue
+				}
+				jobs = append(jobs, job)
+				if limit > 0 && len(jobs) >= limit {
+					return jobs
+				}
+			}
+		}
+		if response.JSON200.NextPageToken == nil || *response.JSON200.NextPageToken == "" {
+			return jobs
+		}
+		pageToken = response.JSON200.NextPageToken
+	}
+}
+
+// buildExperienceHistory resolves the build versions of the tests, most recent
+// first, and computes the rolling and overall stats.
+func buildExperienceHistory(projectID uuid.UUID, experienceID uuid.UUID, jobs []api.Job, window int) experienceHistory {
+	history := experienceHistory{ExperienceID: experienceID, Name: experienceID.String(), Runs: []experienceRun{}}
+	versions := map[uuid.UUID]string{}
+	for _, job := range jobs {
+		if job.ExperienceName != nil {
+			history.Name = *job.ExperienceName
+		}
+		run := experienceRun{Status: "-", ConflatedStatus: "-", Created: job.CreationTimestamp}
+		if job.JobID != nil {
+			run.JobID = *job.JobID
+		}
+		if job.BatchID != nil {
+			run.BatchID = *job.BatchID
+		}
+		if job.BuildID != nil {
+			run.BuildID = *job.BuildID
+			version, ok := versions[run.BuildID]
+			if !ok {
+				version = getBuildVersion(projectID, run.BuildID)
+				versions[run.BuildID] = version
+			}
+			run.BuildVersion = version
+		}
+		if job.JobStatus != nil {
+			run.Status = string(*job.JobStatus)
+		}
+		if job.ConflatedStatus != nil {
+			run.ConflatedStatus = string(*job.ConflatedStatus)
+		}
+		if isFinalJobStatus(job.JobStatus) && job.CreationTimestamp != nil && job.LastUpdatedTimestamp != nil {
+			run.DurationSeconds = Ptr(job.LastUpdatedTimestamp.Sub(*job.CreationTimestamp).Seconds())
+		}
+		history.Runs = append(history.Runs, run)
+	}
+	history.Stats = computeExperienceHistoryStats(history.Runs, window)
+	return history
+}
+
+func getBuildVersion(projectID uuid.UUID, buildID uuid.UUID) string {
+	response, err := Client.GetBuildWithResponse(context.Background(), projectID, buildID)
+	if err != nil {
+		log.Fatal("unable to retrieve build:", err)
+	}
+	ValidateResponse(http.StatusOK, "unable to retrieve build", response.HTTPResponse, response.Body)
+	if response.JSON200 == nil {
+		log.Fatal("empty response")
+	}
+	return response.JSON200.Version
+}
+
+// experienceRunPassed reports whether a test passed, and false for ok when it
+// neither passed nor failed, e.g. because it is still running or was cancelled.
+func experienceRunPassed(run experienceRun) (passed bool, ok bool) {
+	switch api.ConflatedJobStatus(run.ConflatedStatus) {
+	case api.ConflatedJobStatusPASSED, api.ConflatedJobStatusWARNING:
+		return true, true
+	case api.ConflatedJobStatusBLOCKER, api.ConflatedJobStatusERROR:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// computeExperienceHistoryStats sets the rolling pass rate of each run, most
+// recent first, and returns the overall stats.
+func computeExperienceHistoryStats(runs []experienceRun, window int) experienceHistoryStats {
+	stats := experienceHistoryStats{Runs: len(runs)}
+	var durations []float64
+	for i := range runs {
+		if passed, ok := experienceRunPassed(runs[i]); ok {
+			if passed {
+				stats.Passed++
+			} else {
+				stats.Failed++
+			}
+		}
+		if runs[i].DurationSeconds != nil {
+			durations = append(durations, *runs[i].DurationSeconds)
+		}
+
+		// The window is this run and the ones before it, which follow it in the list.
+		passedInWindow, judgedInWindow := 0, 0
+		for _, run := range runs[i:min(i+window, len(runs))] {
+			if passed, ok := experienceRunPassed(run); ok {
+				judgedInWindow++
+				if passed {
+					passedInWindow++
+				}
+			}
+		}
+		if judgedInWindow > 0 {
+			runs[i].RollingPassRate = Ptr(float64(passedInWindow) / float64(judgedInWindow))
+		}
+	}
+
+	if judged := stats.Passed + stats.Failed; judged > 0 {
+		stats.PassRate = Ptr(float64(stats.Passed) / float64(judged))
+	}
+	if len(durations) > 0 {
+		sort.Float64s(durations)
+		median := durations[len(durations)/2]
+		if len(durations)%2 == 0 {
+			median = (durations[len(durations)/2-1] + median) / 2
+		}
+		stats.MedianDurationSeconds = Ptr(median)
+	}
+
+	// Walk back from the most recent judged run while the runs keep failing.
+	for i := range runs {
+		passed, ok := experienceRunPassed(runs[i])
+		if !ok {
+			continue
+		}
+		if passed {
+			break
+		}
+		stats.FailingSince = &runs[i]
+	}
+	return stats
+}
+
+func formatExperienceHistory(history experienceHistory, window int) string {
+	var b strings.Builder
+	if len(history.Runs) == 0 {
+		fmt.Fprintf(&b, "No tests found for experience %s.\n", history.Name)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Experience %s: %s\n\n", history.Name, pluralize(len(history.Runs), "test"))
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CREATED\tBATCH ID\tBUILD VERSION\tSTATUS\tCONFLATED\tDURATION\tPASS RATE")
+	for _, run := range history.Runs {
+		created := "-"
+		if run.Created != nil {
+			created = run.Created.UTC().Format(time.RFC3339)
+		}
+		version := run.BuildVersion
+		if version == "" {
+			version = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", created, run.BatchID, version, run.Status, run.ConflatedStatus,
+			formatHistorySeconds(run.DurationSeconds), formatHistoryRate(run.RollingPassRate))
+	}
+	w.Flush()
+
+	stats := history.Stats
+	fmt.Fprintf(&b, "\nPass rate: %s (%d passed, %d failed)\n", formatHistoryRate(stats.PassRate), stats.Passed, stats.Failed)
+	fmt.Fprintf(&b, "Median duration: %s\n", formatHistorySeconds(stats.MedianDurationSeconds))
+	fmt.Fprintf(&b, "Rolling pass rate over the last %s per row.\n", pluralize(window, "test"))
+	if since := stats.FailingSince; since != nil {
+		created := "-"
+		if since.Created != nil {
+			created = since.Created.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(&b, "Failing since %s: batch %s, build version %s\n", created, since.BatchID, since.BuildVersion)
+	}
+	return b.String()
+}
+
+func formatHistorySeconds(seconds *float64) string {
+	if seconds == nil {
+		return "-"
+	}
+	return time.Duration(*seconds * float64(time.Second)).Round(time.Second).String()
+}
+
+func formatHistoryRate(rate *float64) string {
+	if rate == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", *rate*100)
+}
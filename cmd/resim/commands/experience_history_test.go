@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+)
+
+func historyRun(status api.ConflatedJobStatus, seconds float64) experienceRun {
+	return experienceRun{ConflatedStatus: string(status), DurationSeconds: Ptr(seconds)}
+}
+
+func (s *CommandsSuite) TestComputeExperienceHistoryStats() {
+	// Most recent first: failing for the last three judged runs, ignoring the
+	// running and cancelled ones.
+	runs := []experienceRun{
+		{ConflatedStatus: string(api.ConflatedJobStatusRUNNING)},
+		historyRun(api.ConflatedJobStatusBLOCKER, 40),
+		historyRun(api.ConflatedJobStatusCANCELLED, 5),
+		historyRun(api.ConflatedJobStatusERROR, 30),
+		historyRun(api.ConflatedJobStatusBLOCKER, 20),
+		historyRun(api.ConflatedJobStatusWARNING, 12),
+		historyRun(api.ConflatedJobStatusPASSED, 10),
+	}
+
+	stats := computeExperienceHistoryStats(runs, 3)
+
+	s.Equal(7, stats.Runs)
+	s.Equal(2, stats.Passed)
+	s.Equal(3, stats.Failed)
+	s.Require().NotNil(stats.PassRate)
+	s.InDelta(0.4, *stats.PassRate, 1e-9)
+	s.Require().NotNil(stats.MedianDurationSeconds)
+	s.Equal(16.0, *stats.MedianDurationSeconds)
+	s.Same(&runs[4], stats.FailingSince)
+
+	// Windows of three runs, ending with each run.
+	s.Require().NotNil(runs[0].RollingPassRate)
+	s.Equal(0.0, *runs[0].RollingPassRate)
+	s.Require().NotNil(runs[3].RollingPassRate)
+	s.InDelta(1.0/3, *runs[3].RollingPassRate, 1e-9)
+	s.Require().NotNil(runs[5].RollingPassRate)
+	s.Equal(1.0, *runs[5].RollingPassRate)
+}
+
+func (s *CommandsSuite) TestComputeExperienceHistoryStatsPassing() {
+	runs := []experienceRun{
+		historyRun(api.ConflatedJobStatusPASSED, 10),
+		historyRun(api.ConflatedJobStatusBLOCKER, 20),
+	}
+
+	stats := computeExperienceHistoryStats(runs, 10)
+
+	s.Nil(stats.FailingSince)
+	s.Equal(15.0, *stats.MedianDurationSeconds)
+	s.Equal(0.5, *runs[0].RollingPassRate)
+	s.Equal(0.0, *runs[1].RollingPassRate)
+}
+
+func (s *CommandsSuite) TestExperienceHistory() {
+	viper.Reset()
+	projectID := uuid.New()
+	experienceID := uuid.New()
+	systemID := uuid.New()
+	buildA, buildB := uuid.New(), uuid.New()
+	batchIDs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	job := func(batchID uuid.UUID, buildID uuid.UUID, experience uuid.UUID, system uuid.UUID, status api.ConflatedJobStatus, created time.Time, seconds int) api.Job {
+		return api.Job{
+			JobID:                Ptr(uuid.New()),
+			BatchID:              Ptr(batchID),
+			BuildID:              Ptr(buildID),
+			SystemID:             Ptr(system),
+			ExperienceID:         Ptr(experience),
+			ExperienceName:       Ptr("lane-change"),
+			JobStatus:            Ptr(api.JobStatusSUCCEEDED),
+			ConflatedStatus:      Ptr(status),
+			CreationTimestamp:    Ptr(created),
+			LastUpdatedTimestamp: Ptr(created.Add(time.Duration(seconds) * time.Second)),
+		}
+	}
+	// Two pages, most recent first, with tests of another experience and system.
+	firstPage := []api.Job{
+		job(batchIDs[0], buildB, experienceID, systemID, api.ConflatedJobStatusBLOCKER, start.Add(3*time.Hour), 90),
+		job(uuid.New(), buildB, uuid.New(), systemID, api.ConflatedJobStatusPASSED, start.Add(3*time.Hour), 10),
+		job(uuid.New(), buildB, experienceID, uuid.New(), api.ConflatedJobStatusPASSED, start.Add(3*time.Hour), 10),
+		job(batchIDs[1], buildB, experienceID, systemID, api.ConflatedJobStatusERROR, start.Add(2*time.Hour), 60),
+	}
+	secondPage := []api.Job{
+		job(batchIDs[2], buildA, experienceID, systemID, api.ConflatedJobStatusPASSED, start.Add(time.Hour), 30),
+	}
+
+	viper.Set(experienceProjectKey, projectID.String())
+	viper.Set(experienceKey, experienceID.String())
+	viper.Set(experienceSystemKey, systemID.String())
+	viper.Set(experienceLimitKey, 50)
+	viper.Set(experienceWindowKey, 10)
+	viper.Set(experienceOutputKey, batchOutputTable)
+
+	s.mockClient.On("GetProjectWithResponse", matchContext, projectID).Return(&api.GetProjectResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Project{ProjectID: projectID, Name: "test-project"},
+	}, nil)
+	s.mockClient.On("GetExperienceWithResponse", matchContext, projectID, experienceID).Return(&api.GetExperienceResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Experience{ExperienceID: experienceID, Name: "lane-change"},
+	}, nil).Maybe()
+	s.mockClient.On("GetSystemWithResponse", matchContext, projectID, systemID).Return(&api.GetSystemResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.System{SystemID: systemID, Name: "planner"},
+	}, nil).Maybe()
+	s.mockClient.On("ListAllJobsWithResponse", matchContext, projectID, mock.MatchedBy(func(params *api.ListAllJobsParams) bool {
+		return params.PageToken == nil
+	})).Return(&api.ListAllJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListAllJobsOutput{Jobs: &firstPage, NextPageToken: Ptr("page-2")},
+	}, nil).Once()
+	s.mockClient.On("ListAllJobsWithResponse", matchContext, projectID, mock.MatchedBy(func(params *api.ListAllJobsParams) bool {
+		return params.PageToken != nil && *params.PageToken == "page-2"
+	})).Return(&api.ListAllJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListAllJobsOutput{Jobs: &secondPage, NextPageToken: Ptr("")},
+	}, nil).Once()
+	for buildID, version := range map[uuid.UUID]string{buildA: "sha-a", buildB: "sha-b"} {
+		s.mockClient.On("GetBuildWithResponse", matchContext, projectID, buildID).Return(&api.GetBuildResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &api.Build{BuildID: buildID, Version: version},
+		}, nil).Once()
+	}
+
+	output := captureStdout(s, func() { listExperienceHistory(nil, nil) })
+
+	s.Contains(output, "Experience lane-change: 3 tests")
+	s.Equal([]string{"2026-10-01T03:00:00Z", batchIDs[0].String(), "sha-b", "SUCCEEDED", "BLOCKER", "1m30s", "33%"},
+		strings.Fields(lineWith(output, batchIDs[0].String())))
+	s.Equal([]string{"2026-10-01T01:00:00Z", batchIDs[2].String(), "sha-a", "SUCCEEDED", "PASSED", "30s", "100%"},
+		strings.Fields(lineWith(output, batchIDs[2].String())))
+	s.Contains(output, "Pass rate: 33% (1 passed, 2 failed)")
+	s.Contains(output, "Median duration: 1m0s")
+	s.Contains(output, "Failing since 2026-10-01T02:00:00Z: batch "+batchIDs[1].String()+", build version sha-b")
+}
+
+func (s *CommandsSuite) TestListExperienceJobsStopsAtSince() {
+	projectID := uuid.New()
+	experienceID := uuid.New()
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	job := func(created time.Time) api.Job {
+		return api.Job{JobID: Ptr(uuid.New()), ExperienceID: Ptr(experienceID), CreationTimestamp: Ptr(created)}
+	}
+	page := []api.Job{job(since.Add(time.Hour)), job(since.Add(-time.Hour)), job(since.Add(-2 * time.Hour))}
+	s.mockClient.On("ListAllJobsWithResponse", matchContext, projectID, mock.Anything).Return(&api.ListAllJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListAllJobsOutput{Jobs: &page, NextPageToken: Ptr("page-2")},
+	}, nil).Once()
+
+	jobs := listExperienceJobs(projectID, experienceHistoryFilter{ExperienceID: experienceID, Since: since}, 0)
+
+	// The scan stops at the first older test instead of fetching the next page.
+	s.Len(jobs, 1)
+	s.mockClient.AssertNumberOfCalls(s.T(), "ListAllJobsWithResponse", 1)
+}
+
+func (s *CommandsSuite) TestListExperienceJobsCapsPages() {
+	projectID := uuid.New()
+	page := []api.Job{{JobID: Ptr(uuid.New()), ExperienceID: Ptr(uuid.New())}}
+	s.mockClient.On("ListAllJobsWithResponse", matchContext, projectID, mock.Anything).Return(&api.ListAllJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListAllJobsOutput{Jobs: &page, NextPageToken: Ptr("next")},
+	}, nil)
+
+	jobs := listExperienceJobs(projectID, experienceHistoryFilter{ExperienceID: uuid.New()}, 0)
+
+	s.Empty(jobs)
+	s.mockClient.AssertNumberOfCalls(s.T(), "ListAllJobsWithResponse", experienceHistoryMaxPages)
+}
@@ -44,6 +44,7 @@ const (
 	ingestPoolLabelsKey         = "pool-labels"
 	ingestPriorityKey           = "priority"
 	ingestReingestKey           = "reingest"
+	ingestLightKey              = "light"
 
 	ingestMetricsSetKey           = "metrics-set"
 	ingestSyncMetricsConfigKey    = "sync-metrics-config"
@@ -99,6 +100,11 @@ func init() {
 	rootCmd.AddCommand(ingestLogCmd)
 	// Re-ingestion
 	ingestLogCmd.Flags().Bool(ingestReingestKey, false, "Whether to re-ingest the logs if its experiences already exist. If not provided, the log will not be ingested again.")
+	// Light batches
+	ingestLogCmd.Flags().Bool(ingestLightKey, false, "If set, ingest the logs with a light batch, which does not run the log ingest build. Each log becomes a test that is closed as succeeded, and the batch is then closed.")
+	ingestLogCmd.MarkFlagsMutuallyExclusive(ingestLightKey, ingestBuildKey)
+	ingestLogCmd.MarkFlagsMutuallyExclusive(ingestLightKey, ingestMetricsBuildKey)
+	ingestLogCmd.MarkFlagsMutuallyExclusive(ingestLightKey, ingestPoolLabelsKey)
 }
 
 type logPair struct {
@@ -121,7 +127,10 @@ func ingestLog(ccmd *cobra.Command, args []string) {
 		fmt.Println("Ingesting a log...")
 	}
 
-	var buildID uuid.UUID
+	// A light batch needs no build: the API finds or creates one from the
+	// branch, system and version.
+	light := viper.GetBool(ingestLightKey)
+	var buildID, branchID, systemID uuid.UUID
 	var err error
 	if viper.IsSet(ingestBuildKey) {
 		buildID, err = uuid.Parse(viper.GetString(ingestBuildKey))
@@ -130,10 +139,12 @@ func ingestLog(ccmd *cobra.Command, args []string) {
 		}
 	} else {
 		// Create a build using the ReSim standard log ingest build:
-		systemID := getSystemID(Client, projectID, viper.GetString(ingestSystemKey), true)
+		systemID = getSystemID(Client, projectID, viper.GetString(ingestSystemKey), true)
 		// Check the branch exists:
-		branchID := getOrCreateBranchID(Client, projectID, viper.GetString(ingestBranchKey), logIngestGithub)
-		buildID = getOrCreateBuild(Client, projectID, branchID, systemID, LogIngestURI, viper.GetString(ingestVersionKey))
+		branchID = getOrCreateBranchID(Client, projectID, viper.GetString(ingestBranchKey), logIngestGithub)
+		if !light {
+			buildID = getOrCreateBuild(Client, projectID, branchID, systemID, LogIngestURI, viper.GetString(ingestVersionKey))
+		}
 	}
 
 	var logsToProcess []LogConfig
@@ -262,19 +273,70 @@ func ingestLog(ccmd *cobra.Command, args []string) {
 	metricsSet := ProcessMetricsSet(ingestMetricsSetKey, &poolLabels)
 
 	if HasMetricsSetName(metricsSet) {
-		build, err := Client.GetBuildWithResponse(context.Background(), projectID, buildID)
-		if err != nil {
-			log.Fatal("unable to retrieve build:", err)
+		if err := validateMetricsSetExists(ingestBranchID(projectID, buildID, branchID), metricsSet); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Sync metrics2.0 config
+	if viper.GetBool(ingestSyncMetricsConfigKey) {
+		metricsConfigPaths := viper.GetStringSlice(ingestMetricsConfigPathKey)
+		metricsTemplatesPath := viper.GetString(ingestMetricsTemplatesPathKey)
+		if err := SyncMetricsConfig(projectID, ingestBranchID(projectID, buildID, branchID), metricsConfigPaths, metricsTemplatesPath, false); err != nil {
+			log.Fatalf("failed to sync metrics before ingest: %v", err)
 		}
-		if build.JSON200 == nil || build.JSON200.BranchID == uuid.Nil {
-			log.Fatal("build has no branch associated with it")
+	}
+
+	var batch api.Batch
+	if light {
+		lightBatchBody := api.LightBatchInput{
+			BranchID:       branchID,
+			SystemID:       Ptr(systemID),
+			Version:        Ptr(viper.GetString(ingestVersionKey)),
+			MetricsSetName: metricsSet,
+			Priority:       getRequestPriority(ingestPriorityKey),
 		}
-		if err := validateMetricsSetExists(build.JSON200.BranchID, metricsSet); err != nil {
-			log.Fatal(err)
+		if viper.IsSet(ingestBatchNameKey) {
+			lightBatchBody.BatchName = Ptr(viper.GetString(ingestBatchNameKey))
 		}
+		var jobs []api.Job
+		batch, jobs = createLightBatch(projectID, lightBatchBody, experienceIDs)
+		closeLightBatch(projectID, *batch.BatchID, jobs)
+	} else {
+		batch = createIngestBatch(projectID, buildID, experienceIDs, associatedAccount, metricsSet, poolLabels)
+	}
+
+	// Report the results back to the user
+	if logIngestGithub {
+		fmt.Printf("batch_id=%s\n", batch.BatchID.String())
+	} else {
+		fmt.Println("Ingested logs successfully!")
+		fmt.Printf("Batch ID: %s\n", batch.BatchID.String())
+		if resimURL := maybeGenerateResimURL(projectID, *batch.BatchID); resimURL != "" {
+			fmt.Printf("View the results at %s\n", resimURL)
+		}
+	}
+}
+
+// ingestBranchID returns the branch of the ingestion: the branch flag's branch
+// when it was resolved, otherwise the branch of the given build.
+func ingestBranchID(projectID uuid.UUID, buildID uuid.UUID, branchID uuid.UUID) uuid.UUID {
+	if branchID != uuid.Nil {
+		return branchID
+	}
+	build, err := Client.GetBuildWithResponse(context.Background(), projectID, buildID)
+	if err != nil {
+		log.Fatal("unable to retrieve build:", err)
 	}
+	if build.JSON200 == nil || build.JSON200.BranchID == uuid.Nil {
+		log.Fatal("build has no branch associated with it")
+	}
+	return build.JSON200.BranchID
+}
 
-	// Finally, create a batch to process the log(s)
+// createIngestBatch creates the batch that runs the ingest build over the
+// ingested experiences.
+func createIngestBatch(projectID uuid.UUID, buildID uuid.UUID, experienceIDs []uuid.UUID, associatedAccount string, metricsSet *string, poolLabels []api.PoolLabel) api.Batch {
 	batchBody := api.BatchInput{
 		ExperienceIDs:     Ptr(experienceIDs),
 		BuildID:           Ptr(buildID),
@@ -303,21 +365,6 @@ func ingestLog(ccmd *cobra.Command, args []string) {
 		batchBody.Priority = priority
 	}
 
-	// Sync metrics2.0 config
-	if viper.GetBool(ingestSyncMetricsConfigKey) {
-		build, err := Client.GetBuildWithResponse(context.Background(), projectID, buildID)
-		if err != nil {
-			log.Fatal("unable to retrieve build:", err)
-		}
-		branchID := build.JSON200.BranchID
-
-		metricsConfigPaths := viper.GetStringSlice(ingestMetricsConfigPathKey)
-		metricsTemplatesPath := viper.GetString(ingestMetricsTemplatesPathKey)
-		if err := SyncMetricsConfig(projectID, branchID, metricsConfigPaths, metricsTemplatesPath, false); err != nil {
-			log.Fatalf("failed to sync metrics before ingest: %v", err)
-		}
-	}
-
 	batchResponse, err := Client.CreateBatchWithResponse(context.Background(), projectID, batchBody)
 	if err != nil {
 		log.Fatal("unable to create batch:", err)
@@ -330,16 +377,7 @@ func ingestLog(ccmd *cobra.Command, args []string) {
 	if batch.BatchID == nil {
 		log.Fatal("no batch ID")
 	}
-	// Report the results back to the user
-	if logIngestGithub {
-		fmt.Printf("batch_id=%s\n", batch.BatchID.String())
-	} else {
-		fmt.Println("Ingested logs successfully!")
-		fmt.Printf("Batch ID: %s\n", batch.BatchID.String())
-		if resimURL := maybeGenerateResimURL(projectID, *batch.BatchID); resimURL != "" {
-			fmt.Printf("View the results at %s\n", resimURL)
-		}
-	}
+	return batch
 }
 
 // Index over the imageURI and the version to determine whether or not we want to create a new build:
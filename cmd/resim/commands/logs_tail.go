@@ -0,0 +1,382 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var tailLogsCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "tail - Streams the live logs of a running batch or test",
+	Long: `Follows the log streams of a batch while its tests run, printing new lines as they arrive.
+When more than one stream can match, each line is prefixed with [<experience>/<log name>].
+Dropped connections are retried, and the command exits once the followed test (or the whole batch) has finished.
+
+Each stream is polled with a Range request for the bytes after those already printed. If the
+server ignores the range, the whole stream is downloaded again and the printed part discarded.
+Streams with no new lines are polled less often, backing off from --poll-every up to 30s.`,
+	Run: tailLogs,
+}
+
+const (
+	logNameKey      = "log"
+	logPollEveryKey = "poll-every"
+)
+
+const (
+	// logStreamMaxRetries is the number of consecutive transient failures after which a stream is abandoned.
+	logStreamMaxRetries = 10
+	logStreamMaxBackoff = 30 * time.Second
+	// logStreamMaxIdleInterval caps how far the poll interval of a quiet stream backs off.
+	logStreamMaxIdleInterval = 30 * time.Second
+)
+
+func init() {
+	tailLogsCmd.Flags().String(logProjectKey, "", "The name or ID of the project the batch belongs to")
+	tailLogsCmd.MarkFlagRequired(logProjectKey)
+	tailLogsCmd.Flags().String(logBatchIDKey, "", "The UUID of the batch to tail logs for")
+	tailLogsCmd.MarkFlagRequired(logBatchIDKey)
+	tailLogsCmd.Flags().String(logTestIDKey, "", "(Optional) The UUID of the test in the batch to tail logs for. If not provided, every test is followed")
+	tailLogsCmd.Flags().String(logNameKey, "", "(Optional) Only follow log streams with this name")
+	tailLogsCmd.Flags().String(logPollEveryKey, "5s", "Interval between checks for new log streams and finished tests, specified as a Golang duration string")
+	tailLogsCmd.Flags().SetNormalizeFunc(aliasProjectNameFunc)
+	logsCmd.AddCommand(tailLogsCmd)
+}
+
+// rawLogStreamClient is implemented by the generated client through its embedded
+// ClientInterface. Reading the raw response lets tail print lines as they arrive rather
+// than once the stream closes.
+type rawLogStreamClient interface {
+	GetLogStream(ctx context.Context, projectID api.ProjectID, batchID api.BatchID, jobID api.JobID, logName api.FileName, reqEditors ...api.RequestEditorFn) (*http.Response, error)
+}
+
+// logStreamStatusError is returned when the log stream endpoint answers with a non-200 status.
+type logStreamStatusError struct {
+	status int
+}
+
+func (e *logStreamStatusError) Error() string {
+	return fmt.Sprintf("log stream returned status %d", e.status)
+}
+
+// isTransientLogStreamError reports whether a failed read is worth retrying: network
+// errors, throttling and server errors are; any other status is not.
+func isTransientLogStreamError(err error) bool {
+	var statusErr *logStreamStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status == http.StatusTooManyRequests || statusErr.status >= http.StatusInternalServerError
+	}
+	return true
+}
+
+func isLogStreamNotFound(err error) bool {
+	var statusErr *logStreamStatusError
+	return errors.As(err, &statusErr) && statusErr.status == http.StatusNotFound
+}
+
+// logStreamBackoff doubles from one second per consecutive failure, up to logStreamMaxBackoff.
+func logStreamBackoff(failures int) time.Duration {
+	backoff := time.Second
+	for i := 1; i < failures && backoff < logStreamMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, logStreamMaxBackoff)
+}
+
+// logStreamIdleInterval doubles the poll interval for each consecutive poll of a stream that
+// found no new lines, up to logStreamMaxIdleInterval, so quiet streams are polled less often.
+func logStreamIdleInterval(pollEvery time.Duration, idle int) time.Duration {
+	interval := pollEvery
+	for i := 0; i < idle && interval < logStreamMaxIdleInterval; i++ {
+		interval *= 2
+	}
+	return max(pollEvery, min(interval, logStreamMaxIdleInterval))
+}
+
+// rangeFrom requests the bytes of the stream from offset onwards.
+func rangeFrom(offset int64) api.RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		return nil
+	}
+}
+
+// openLogStream returns the stream from offset onwards. A range is requested when offset is
+// past the start; if the server ignores it and sends the whole stream, the first offset
+// bytes are discarded here.
+func openLogStream(projectID uuid.UUID, batchID uuid.UUID, jobID uuid.UUID, logName string, offset int64) (io.ReadCloser, error) {
+	var reqEditors []api.RequestEditorFn
+	if offset > 0 {
+		reqEditors = append(reqEditors, rangeFrom(offset))
+	}
+	var status int
+	var body io.ReadCloser
+	if raw, ok := Client.(rawLogStreamClient); ok {
+		response, err := raw.GetLogStream(context.Background(), projectID, batchID, jobID, logName, reqEditors...)
+		if err != nil {
+			return nil, err
+		}
+		status, body = response.StatusCode, response.Body
+	} else {
+		response, err := Client.GetLogStreamWithResponse(context.Background(), projectID, batchID, jobID, logName, reqEditors...)
+		if err != nil {
+			return nil, err
+		}
+		status, body = response.StatusCode(), io.NopCloser(bytes.NewReader(response.Body))
+	}
+
+	switch {
+	case status == http.StatusPartialContent && offset > 0:
+		return body, nil
+	case status == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// Nothing has been written since the last read.
+		body.Close()
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	case status == http.StatusOK:
+		if _, err := io.CopyN(io.Discard, body, offset); err != nil && err != io.EOF {
+			body.Close()
+			return nil, err
+		}
+		return body, nil
+	}
+	body.Close()
+	return nil, &logStreamStatusError{status: status}
+}
+
+// logTailer follows the log streams of a batch, optionally narrowed to one test and one
+// log name, until the followed tests finish.
+type logTailer struct {
+	projectID uuid.UUID
+	batchID   uuid.UUID
+	testID    *uuid.UUID
+	logName   string
+	pollEvery time.Duration
+	sleep     func(time.Duration)
+
+	mu  sync.Mutex // guards out
+	out io.Writer
+}
+
+func newLogTailer(out io.Writer, projectID uuid.UUID, batchID uuid.UUID, testID *uuid.UUID, logName string, pollEvery time.Duration) *logTailer {
+	return &logTailer{
+		projectID: projectID,
+		batchID:   batchID,
+		testID:    testID,
+		logName:   logName,
+		pollEvery: pollEvery,
+		sleep:     time.Sleep,
+		out:       out,
+	}
+}
+
+// prefixLines is true unless a single test and log name were requested, in which case
+// at most one stream can match.
+func (t *logTailer) prefixLines() bool {
+	return t.testID == nil || t.logName == ""
+}
+
+func (t *logTailer) matches(stream api.LogStream) bool {
+	if stream.JobID == nil {
+		return false
+	}
+	if t.testID != nil && *stream.JobID != *t.testID {
+		return false
+	}
+	return t.logName == "" || stream.LogName == t.logName
+}
+
+func (t *logTailer) printLine(prefix string, line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.out, "%s%s\n", prefix, line)
+}
+
+// getJob returns nil if the test could not be fetched; callers treat that as still running.
+func (t *logTailer) getJob(jobID uuid.UUID) *api.Job {
+	response, err := Client.GetJobWithResponse(context.Background(), t.projectID, t.batchID, jobID)
+	if err != nil || response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+		return nil
+	}
+	return response.JSON200
+}
+
+// finished reports whether everything being followed is done: the test if one was
+// requested, otherwise the batch.
+func (t *logTailer) finished() bool {
+	if t.testID != nil {
+		job := t.getJob(*t.testID)
+		return job != nil && isFinalJobStatus(job.JobStatus)
+	}
+	response, err := Client.GetBatchWithResponse(context.Background(), t.projectID, t.batchID)
+	if err != nil || response.StatusCode() != http.StatusOK || response.JSON200 == nil || response.JSON200.Status == nil {
+		return false
+	}
+	switch *response.JSON200.Status {
+	case api.BatchStatusSUCCEEDED, api.BatchStatusERROR, api.BatchStatusCANCELLED:
+		return true
+	}
+	return false
+}
+
+func (t *logTailer) listStreams() ([]api.LogStream, error) {
+	response, err := Client.ListBatchLogStreamsWithResponse(context.Background(), t.projectID, t.batchID)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+		return nil, fmt.Errorf("listing log streams returned status %d", response.StatusCode())
+	}
+	return response.JSON200.LogStreams, nil
+}
+
+// run polls for log streams, following each new one in its own goroutine, and returns
+// once the followed tests have finished and every stream has been drained. Streams are
+// listed one last time after the tests finish so short-lived streams are not missed.
+func (t *logTailer) run() {
+	following := map[string]bool{}
+	var wg sync.WaitGroup
+	for {
+		done := t.finished()
+		streams, err := t.listStreams()
+		if err != nil {
+			log.Printf("unable to list log streams: %v\n", err)
+		}
+		for _, stream := range streams {
+			if !t.matches(stream) {
+				continue
+			}
+			key := stream.JobID.String() + "/" + stream.LogName
+			if following[key] {
+				continue
+			}
+			following[key] = true
+			wg.Add(1)
+			go func(stream api.LogStream) {
+				defer wg.Done()
+				t.follow(stream)
+			}(stream)
+		}
+		if done {
+			break
+		}
+		t.sleep(t.pollEvery)
+	}
+	wg.Wait()
+	if len(following) == 0 {
+		log.Println("no matching log streams found")
+	}
+}
+
+// follow prints one stream until its test has finished. Each read resumes from the end of
+// the last line printed, including after a disconnect.
+func (t *logTailer) follow(stream api.LogStream) {
+	jobID := *stream.JobID
+	prefix := ""
+	if t.prefixLines() {
+		name := jobID.String()
+		if job := t.getJob(jobID); job != nil && job.ExperienceName != nil {
+			name = *job.ExperienceName
+		}
+		prefix = fmt.Sprintf("[%s/%s] ", name, stream.LogName)
+	}
+
+	var offset int64
+	failures := 0
+	idle := 0
+	for {
+		// Checked before reading so that a read which starts after the test finished
+		// is known to see the complete log.
+		job := t.getJob(jobID)
+		final := job != nil && isFinalJobStatus(job.JobStatus)
+
+		read, err := t.readStream(jobID, stream.LogName, prefix, offset, final)
+		if read > offset {
+			idle = 0
+		} else {
+			idle++
+		}
+		offset = read
+		switch {
+		case err == nil || isLogStreamNotFound(err):
+			failures = 0
+			if final {
+				return
+			}
+			t.sleep(logStreamIdleInterval(t.pollEvery, idle))
+		case isTransientLogStreamError(err):
+			failures++
+			if failures > logStreamMaxRetries {
+				log.Printf("giving up on log stream %s after %d failed attempts: %v\n", strings.TrimSpace(prefix+stream.LogName), failures, err)
+				return
+			}
+			t.sleep(logStreamBackoff(failures))
+		default:
+			log.Printf("unable to read log stream %s: %v\n", stream.LogName, err)
+			return
+		}
+	}
+}
+
+// readStream reads the stream from offset once, printing its complete lines. A trailing
+// line without a newline is only printed once the test is final, since it may still be
+// being written. It returns the offset just past the last line printed.
+func (t *logTailer) readStream(jobID uuid.UUID, logName string, prefix string, offset int64, final bool) (int64, error) {
+	body, err := openLogStream(t.projectID, t.batchID, jobID, logName, offset)
+	if err != nil {
+		return offset, err
+	}
+	defer body.Close()
+
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadString('\n')
+		complete := strings.HasSuffix(line, "\n")
+		if complete || (final && line != "" && err == io.EOF) {
+			t.printLine(prefix, strings.TrimRight(line, "\r\n"))
+			offset += int64(len(line))
+		}
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+	}
+}
+
+func tailLogs(ccmd *cobra.Command, args []string) {
+	projectID := getProjectID(Client, viper.GetString(logProjectKey))
+	batchID, err := uuid.Parse(viper.GetString(logBatchIDKey))
+	if err != nil || batchID == uuid.Nil {
+		log.Fatal("unable to parse batch ID: ", err)
+	}
+	var testID *uuid.UUID
+	if viper.GetString(logTestIDKey) != "" {
+		id, err := uuid.Parse(viper.GetString(logTestIDKey))
+		if err != nil || id == uuid.Nil {
+			log.Fatal("unable to parse test ID: ", err)
+		}
+		testID = &id
+	}
+	pollEvery, err := time.ParseDuration(viper.GetString(logPollEveryKey))
+	if err != nil || pollEvery <= 0 {
+		log.Fatal("invalid poll interval: ", viper.GetString(logPollEveryKey))
+	}
+
+	newLogTailer(os.Stdout, projectID, batchID, testID, viper.GetString(logNameKey), pollEvery).run()
+}
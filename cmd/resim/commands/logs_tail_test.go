@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/stretchr/testify/mock"
+)
+
+func logStreamResponse(status int, body string) *api.GetLogStreamResponse {
+	return &api.GetLogStreamResponse{
+		HTTPResponse: &http.Response{StatusCode: status},
+		Body:         []byte(body),
+	}
+}
+
+// requestsRange matches a request editor that sets the Range header to start at offset.
+func requestsRange(offset int64) interface{} {
+	return mock.MatchedBy(func(editor api.RequestEditorFn) bool {
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+		return editor(context.Background(), req) == nil && req.Header.Get("Range") == fmt.Sprintf("bytes=%d-", offset)
+	})
+}
+
+func getJobResponse(name string, status api.JobStatus) *api.GetJobResponse {
+	return &api.GetJobResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Job{ExperienceName: Ptr(name), JobStatus: Ptr(status)},
+	}
+}
+
+func (s *CommandsSuite) TestLogTailerFollowResumesFromOffset() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	jobID := uuid.New()
+	var out bytes.Buffer
+	tailer := newLogTailer(&out, projectID, batchID, Ptr(jobID), "stdout", 5*time.Second)
+	var sleeps []time.Duration
+	tailer.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+
+	s.mockClient.On("GetJobWithResponse", matchContext, projectID, batchID, jobID).Return(
+		getJobResponse("lane-change", api.JobStatusEXPERIENCERUNNING), nil).Times(3)
+	s.mockClient.On("GetJobWithResponse", matchContext, projectID, batchID, jobID).Return(
+		getJobResponse("lane-change", api.JobStatusSUCCEEDED), nil).Once()
+
+	// The partial last line is held back until the test has finished, and every later read,
+	// including after a disconnect, starts right after the last line printed.
+	s.mockClient.On("GetLogStreamWithResponse", matchContext, projectID, batchID, jobID, "stdout").Return(
+		logStreamResponse(http.StatusOK, "a\nb\npart"), nil).Once()
+	s.mockClient.On("GetLogStreamWithResponse", matchContext, projectID, batchID, jobID, "stdout", requestsRange(4)).Return(
+		nil, errors.New("connection reset by peer")).Once()
+	s.mockClient.On("GetLogStreamWithResponse", matchContext, projectID, batchID, jobID, "stdout", requestsRange(4)).Return(
+		logStreamResponse(http.StatusServiceUnavailable, ""), nil).Once()
+	s.mockClient.On("GetLogStreamWithResponse", matchContext, projectID, batchID, jobID, "stdout", requestsRange(4)).Return(
+		logStreamResponse(http.StatusPartialContent, "part-done\nc"), nil).Once()
+
+	tailer.follow(api.LogStream{BatchID: batchID, JobID: Ptr(jobID), LogName: "stdout"})
+
+	s.Equal("a\nb\npart-done\nc\n", out.String())
+	s.Equal([]time.Duration{5 * time.Second, time.Second, 2 * time.Second}, sleeps)
+}
+
+func (s *CommandsSuite) TestLogTailerFollowWithoutRangeSupport() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	jobID := uuid.New()
+	var out bytes.Buffer
+	tailer := newLogTailer(&out, projectID, batchID, Ptr(jobID), "stdout", 5*time.Second)
+	var sleeps []time.Duration
+	tailer.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+
+	s.mockClient.On("GetJobWithResponse", matchContext, projectID, batchID, jobID).Return(
+		getJobResponse("lane-change", api.JobStatusEXPERIENCERUNNING), nil).Times(4)
+	s.mockClient.On("GetJobWithResponse", matchContext, projectID, batchID, jobID).Return(
+		getJobResponse("lane-change", api.JobStatusSUCCEEDED), nil).Once()
+
+	s.mockClient.On("GetLogStreamWithResponse", matchContext, projectID, batchID, jobID, "stdout").Return(
+		logStreamResponse(http.StatusOK, "a\n"), nil).Once()
+	// A server that ignores the range sends the whole stream, and the printed part is skipped.
+	s.mockClient.On("GetLogStreamWithResponse", matchContext, projectID, batchID, jobID, "stdout", requestsRange(2)).Return(
+		logStreamResponse(http.StatusOK, "a\n"), nil).Twice()
+	// Nothing new is also reported as an unsatisfiable range.
+	s.mockClient.On("GetLogStreamWithResponse", matchContext, projectID, batchID, jobID, "stdout", requestsRange(2)).Return(
+		logStreamResponse(http.StatusRequestedRangeNotSatisfiable, ""), nil).Once()
+	s.mockClient.On("GetLogStreamWithResponse", matchContext, projectID, batchID, jobID, "stdout", requestsRange(2)).Return(
+		logStreamResponse(http.StatusOK, "a\nb\n"), nil).Once()
+
+	tailer.follow(api.LogStream{BatchID: batchID, JobID: Ptr(jobID), LogName: "stdout"})
+
+	s.Equal("a\nb\n", out.String())
+	// Polls that find nothing new back off.
+	s.Equal([]time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 30 * time.Second}, sleeps)
+}
+
+func (s *CommandsSuite) TestLogTailerFollowStopsOnClientError() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	jobID := uuid.New()
+	var out bytes.Buffer
+	tailer := newLogTailer(&out, projectID, batchID, Ptr(jobID), "stdout", time.Second)
+	tailer.sleep = func(time.Duration) { s.Fail("should not sleep") }
+
+	s.mockClient.On("GetJobWithResponse", matchContext, projectID, batchID, jobID).Return(
+		getJobResponse("lane-change", api.JobStatusEXPERIENCERUNNING), nil).Once()
+	s.mockClient.On("GetLogStreamWithResponse", matchContext, projectID, batchID, jobID, "stdout").Return(
+		logStreamResponse(http.StatusForbidden, ""), nil).Once()
+
+	tailer.follow(api.LogStream{BatchID: batchID, JobID: Ptr(jobID), LogName: "stdout"})
+	s.Empty(out.String())
+}
+
+func (s *CommandsSuite) TestLogTailerRunFollowsNewStreamsWithPrefixes() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	laneChange := uuid.New()
+	merge := uuid.New()
+	var out bytes.Buffer
+	tailer := newLogTailer(&out, projectID, batchID, nil, "", time.Second)
+	tailer.sleep = func(time.Duration) {}
+
+	s.mockClient.On("GetBatchWithResponse", matchContext, projectID, batchID).Return(&api.GetBatchResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Batch{Status: Ptr(api.BatchStatusEXPERIENCESRUNNING)},
+	}, nil).Once()
+	s.mockClient.On("GetBatchWithResponse", matchContext, projectID, batchID).Return(&api.GetBatchResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Batch{Status: Ptr(api.BatchStatusSUCCEEDED)},
+	}, nil).Once()
+
+	first := []api.LogStream{{BatchID: batchID, JobID: Ptr(laneChange), LogName: "stdout"}}
+	second := append(first,
+		api.LogStream{BatchID: batchID, JobID: Ptr(merge), LogName: "stderr"},
+		// Batch-level streams have no test to read them through and are skipped.
+		api.LogStream{BatchID: batchID, LogName: "batch.log"},
+	)
+	s.mockClient.On("ListBatchLogStreamsWithResponse", matchContext, projectID, batchID).Return(&api.ListBatchLogStreamsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListBatchLogStreamsOutput{LogStreams: first},
+	}, nil).Once()
+	s.mockClient.On("ListBatchLogStreamsWithResponse", matchContext, projectID, batchID).Return(&api.ListBatchLogStreamsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListBatchLogStreamsOutput{LogStreams: second},
+	}, nil).Once()
+
+	s.mockClient.On("GetJobWithResponse", matchContext, projectID, batchID, laneChange).Return(
+		getJobResponse("lane-change", api.JobStatusSUCCEEDED), nil)
+	s.mockClient.On("GetJobWithResponse", matchContext, projectID, batchID, merge).Return(
+		getJobResponse("merge", api.JobStatusERROR), nil)
+	s.mockClient.On("GetLogStreamWithResponse", matchContext, projectID, batchID, laneChange, "stdout").Return(
+		logStreamResponse(http.StatusOK, "planning\ndone\n"), nil)
+	s.mockClient.On("GetLogStreamWithResponse", matchContext, projectID, batchID, merge, "stderr").Return(
+		logStreamResponse(http.StatusOK, "segfault\n"), nil)
+
+	tailer.run()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	s.ElementsMatch([]string{
+		"[lane-change/stdout] planning",
+		"[lane-change/stdout] done",
+		"[merge/stderr] segfault",
+	}, lines)
+	// Each stream is followed once even though it is listed on every poll.
+	s.mockClient.AssertNumberOfCalls(s.T(), "GetLogStreamWithResponse", 2)
+}
+
+func (s *CommandsSuite) TestLogStreamErrorsAndBackoff() {
+	s.True(isTransientLogStreamError(errors.New("EOF")))
+	s.True(isTransientLogStreamError(&logStreamStatusError{status: http.StatusBadGateway}))
+	s.True(isTransientLogStreamError(&logStreamStatusError{status: http.StatusTooManyRequests}))
+	s.False(isTransientLogStreamError(&logStreamStatusError{status: http.StatusUnauthorized}))
+	s.True(isLogStreamNotFound(&logStreamStatusError{status: http.StatusNotFound}))
+
+	s.Equal(time.Second, logStreamBackoff(1))
+	s.Equal(8*time.Second, logStreamBackoff(4))
+	s.Equal(logStreamMaxBackoff, logStreamBackoff(20))
+
+	s.Equal(5*time.Second, logStreamIdleInterval(5*time.Second, 0))
+	s.Equal(20*time.Second, logStreamIdleInterval(5*time.Second, 2))
+	s.Equal(logStreamMaxIdleInterval, logStreamIdleInterval(5*time.Second, 10))
+	// A poll interval above the cap is kept as it is.
+	s.Equal(time.Minute, logStreamIdleInterval(time.Minute, 3))
+}
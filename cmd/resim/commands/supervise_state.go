@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// batchStateFileKey is the --state-file flag shared by batch supervise and
+// workflow runs supervise.
+const batchStateFileKey = "state-file"
+
+const stateFileFlagDescription = "(Optional) Path to a file to save supervision progress to: rerun attempts, submitted rerun batches and their tests. If supervision is interrupted, running the same command again with this file resumes from the latest rerun batch instead of starting over at attempt 0."
+
+// superviseState is the progress saved to --state-file. Batches are keyed by
+// the batch supervision started with: its ID, or its name if it was given by
+// name.
+type superviseState struct {
+	ProjectID uuid.UUID                   `json:"projectID"`
+	Batches   map[string]*supervisedBatch `json:"batches"`
+}
+
+type supervisedBatch struct {
+	// Attempts is the number of reruns submitted so far.
+	Attempts int `json:"attempts"`
+	// CurrentBatchID is the batch to wait on next: the latest rerun batch.
+	CurrentBatchID uuid.UUID         `json:"currentBatchID"`
+	Reruns         []supervisedRerun `json:"reruns"`
+	UpdatedAt      time.Time         `json:"updatedAt"`
+}
+
+type supervisedRerun struct {
+	Attempt     int         `json:"attempt"`
+	FromBatchID uuid.UUID   `json:"fromBatchID"`
+	BatchID     uuid.UUID   `json:"batchID"`
+	JobIDs      []uuid.UUID `json:"jobIDs"`
+	SubmittedAt time.Time   `json:"submittedAt"`
+}
+
+// superviseStateFile is a superviseState and the file it is saved to. Its
+// methods are safe to call from the goroutines supervising batches in parallel,
+// and do nothing on a nil file, i.e. when --state-file is not set.
+type superviseStateFile struct {
+	path  string
+	mu    sync.Mutex
+	state superviseState
+}
+
+// loadSuperviseStateFile reads the state file at path, starting a new one if it
+// does not exist. It returns nil if path is empty.
+func loadSuperviseStateFile(path string, projectID uuid.UUID) (*superviseStateFile, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file := &superviseStateFile{
+		path:  path,
+		state: superviseState{ProjectID: projectID, Batches: map[string]*supervisedBatch{}},
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read supervise state: %w", err)
+	}
+	if err := json.Unmarshal(data, &file.state); err != nil {
+		return nil, fmt.Errorf("failed to parse supervise state %s: %w", path, err)
+	}
+	if file.state.ProjectID != projectID {
+		return nil, fmt.Errorf("supervise state %s belongs to project %s; remove it or choose another --%s", path, file.state.ProjectID, batchStateFileKey)
+	}
+	if file.state.Batches == nil {
+		file.state.Batches = map[string]*supervisedBatch{}
+	}
+	return file, nil
+}
+
+// resume returns the attempt to continue supervising the batch from and the
+// batch to wait on, which is batchID itself unless reruns were already
+// submitted.
+func (f *superviseStateFile) resume(key string, batchID string) (int, string) {
+	if f == nil {
+		return 0, batchID
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	batch, ok := f.state.Batches[key]
+	if !ok || batch.Attempts == 0 {
+		return 0, batchID
+	}
+	infoLog("Resuming supervision of batch %s from %s at rerun attempt %d: waiting for batch %s\n", key, f.path, batch.Attempts, batch.CurrentBatchID)
+	return batch.Attempts, batch.CurrentBatchID.String()
+}
+
+// recordRerun saves a submitted rerun, so that a resumed supervision waits on
+// the rerun batch and counts the attempt.
+func (f *superviseStateFile) recordRerun(key string, attempt int, fromBatchID uuid.UUID, rerunBatchID uuid.UUID, jobIDs []uuid.UUID) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	batch, ok := f.state.Batches[key]
+	if !ok {
+		batch = &supervisedBatch{}
+		f.state.Batches[key] = batch
+	}
+	now := time.Now().UTC()
+	batch.Attempts = attempt + 1
+	batch.CurrentBatchID = rerunBatchID
+	batch.Reruns = append(batch.Reruns, supervisedRerun{
+		Attempt:     attempt + 1,
+		FromBatchID: fromBatchID,
+		BatchID:     rerunBatchID,
+		JobIDs:      jobIDs,
+		SubmittedAt: now,
+	})
+	batch.UpdatedAt = now
+
+	data, err := json.MarshalIndent(f.state, "", "  ")
+	if err != nil {
+		log.Fatal("failed to encode supervise state: ", err)
+	}
+	if err := os.WriteFile(f.path, append(data, '\n'), 0o644); err != nil {
+		log.Fatal("failed to write supervise state: ", err)
+	}
+}
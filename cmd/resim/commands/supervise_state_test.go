@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *CommandsSuite) setupSuperviseStateMocks(projectID uuid.UUID, batchID uuid.UUID, statePath string, maxRerunAttempts int) {
+	viper.Reset()
+	viper.Set(batchProjectKey, projectID.String())
+	viper.Set(batchMaxRerunAttemptsKey, maxRerunAttempts)
+	viper.Set(batchRerunMaxFailurePercentKey, 50.0)
+	viper.Set(batchRerunOnStatesKey, "Error")
+	viper.Set(batchWaitTimeoutKey, "1m")
+	viper.Set(batchWaitPollKey, "1ms")
+	viper.Set(batchIDKey, batchID.String())
+	viper.Set(batchStateFileKey, statePath)
+
+	s.mockClient.On("GetProjectWithResponse", matchContext, projectID).Return(&api.GetProjectResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Project{ProjectID: projectID, Name: "test-project"},
+	}, nil)
+}
+
+func (s *CommandsSuite) mockFinishedBatch(projectID uuid.UUID, batchID uuid.UUID, status api.BatchStatus) {
+	s.mockClient.On("GetBatchWithResponse", matchContext, projectID, batchID).Return(&api.GetBatchResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Batch{BatchID: Ptr(batchID), Status: Ptr(status)},
+	}, nil).Once()
+}
+
+func (s *CommandsSuite) TestSuperviseBatch_StateFileRecordsReruns() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	rerunBatchID := uuid.New()
+	failedJobID := uuid.New()
+	statePath := filepath.Join(s.T().TempDir(), "supervise.json")
+	s.setupSuperviseStateMocks(projectID, batchID, statePath, 2)
+
+	s.mockFinishedBatch(projectID, batchID, api.BatchStatusERROR)
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, batchID, mock.Anything).Return(&api.ListJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.ListJobsOutput{Jobs: &[]api.Job{
+			{JobID: Ptr(failedJobID), ConflatedStatus: Ptr(api.ConflatedJobStatusERROR)},
+			{JobID: Ptr(uuid.New()), ConflatedStatus: Ptr(api.ConflatedJobStatusPASSED)},
+		}},
+	}, nil).Once()
+	s.mockClient.On("RerunBatchWithResponse", matchContext, projectID, batchID, mock.Anything).Return(&api.RerunBatchResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.RerunBatchOutput{BatchID: Ptr(rerunBatchID)},
+	}, nil).Once()
+	s.mockFinishedBatch(projectID, rerunBatchID, api.BatchStatusSUCCEEDED)
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, rerunBatchID, mock.Anything).Return(&api.ListJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.ListJobsOutput{Jobs: &[]api.Job{
+			{JobID: Ptr(failedJobID), ConflatedStatus: Ptr(api.ConflatedJobStatusPASSED)},
+		}},
+	}, nil).Once()
+
+	result := actualSuperviseBatch(nil, nil)
+
+	s.Require().NoError(result.Error)
+	s.Equal(rerunBatchID, *result.Batch.BatchID)
+	file, err := loadSuperviseStateFile(statePath, projectID)
+	s.Require().NoError(err)
+	batch := file.state.Batches[batchID.String()]
+	s.Require().NotNil(batch)
+	s.Equal(1, batch.Attempts)
+	s.Equal(rerunBatchID, batch.CurrentBatchID)
+	s.Require().Len(batch.Reruns, 1)
+	s.Equal(batchID, batch.Reruns[0].FromBatchID)
+	s.Equal(rerunBatchID, batch.Reruns[0].BatchID)
+	s.Equal([]uuid.UUID{failedJobID}, batch.Reruns[0].JobIDs)
+	s.False(batch.Reruns[0].SubmittedAt.IsZero())
+}
+
+func (s *CommandsSuite) TestSuperviseBatch_ResumesFromStateFile() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	firstRerunID := uuid.New()
+	secondRerunID := uuid.New()
+	statePath := filepath.Join(s.T().TempDir(), "supervise.json")
+	s.setupSuperviseStateMocks(projectID, batchID, statePath, 2)
+
+	// A previous supervision submitted both reruns before it was interrupted.
+	file, err := loadSuperviseStateFile(statePath, projectID)
+	s.Require().NoError(err)
+	file.recordRerun(batchID.String(), 0, batchID, firstRerunID, []uuid.UUID{uuid.New()})
+	file.recordRerun(batchID.String(), 1, firstRerunID, secondRerunID, []uuid.UUID{uuid.New()})
+
+	// Only the latest rerun batch is waited on, and no further rerun is
+	// submitted since the attempts are used up.
+	s.mockFinishedBatch(projectID, secondRerunID, api.BatchStatusERROR)
+
+	result := actualSuperviseBatch(nil, nil)
+
+	s.Require().NoError(result.Error)
+	s.Equal(secondRerunID, *result.Batch.BatchID)
+	s.mockClient.AssertNotCalled(s.T(), "GetBatchWithResponse", matchContext, projectID, batchID)
+	s.mockClient.AssertNotCalled(s.T(), "RerunBatchWithResponse", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *CommandsSuite) TestLoadSuperviseStateFile() {
+	projectID := uuid.New()
+
+	file, err := loadSuperviseStateFile("", projectID)
+	s.NoError(err)
+	s.Nil(file)
+	// A nil file resumes from the start.
+	attempt, batchID := file.resume("key", "batch")
+	s.Equal(0, attempt)
+	s.Equal("batch", batchID)
+
+	statePath := filepath.Join(s.T().TempDir(), "supervise.json")
+	file, err = loadSuperviseStateFile(statePath, projectID)
+	s.Require().NoError(err)
+	file.recordRerun("key", 0, uuid.New(), uuid.New(), nil)
+
+	_, err = loadSuperviseStateFile(statePath, uuid.New())
+	s.ErrorContains(err, "belongs to project "+projectID.String())
+}
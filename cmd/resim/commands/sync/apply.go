@@ -22,7 +22,26 @@ func applyUpdates(
 	experienceUpdates ExperienceUpdates) error {
 
 	numWorkers := 16
-	err := runConcurrentUpdates("Create/Update Experiences", slices.Collect(maps.Values(experienceUpdates.MatchedExperiencesByNewName)),
+	tagsToCreate := []*TagUpdates{}
+	tagsToDelete := []*TagUpdates{}
+	for _, update := range experienceUpdates.TagUpdatesByName {
+		if update.Create {
+			tagsToCreate = append(tagsToCreate, update)
+		}
+		if update.Delete {
+			tagsToDelete = append(tagsToDelete, update)
+		}
+	}
+	err := runConcurrentUpdates("Create Tags", tagsToCreate,
+		numWorkers,
+		func(update *TagUpdates) error {
+			return createTag(client, projectID, update)
+		})
+	if err != nil {
+		return err
+	}
+
+	err = runConcurrentUpdates("Create/Update Experiences", slices.Collect(maps.Values(experienceUpdates.MatchedExperiencesByNewName)),
 		numWorkers,
 		func(update ExperienceMatch) error {
 			return updateSingleExperience(client, projectID, update)
@@ -61,6 +80,15 @@ func applyUpdates(
 	// We archive experiences *after* everything else so that we don't end up inadvertently
 	// revising test suites more than necessary.
 	err = maybeArchiveExperiences(client, projectID, slices.Collect(maps.Values(experienceUpdates.MatchedExperiencesByNewName)))
+	if err != nil {
+		return err
+	}
+
+	err = runConcurrentUpdates("Delete Unused Tags", tagsToDelete,
+		numWorkers,
+		func(update *TagUpdates) error {
+			return deleteTag(client, projectID, *update)
+		})
 
 	return err
 }
@@ -322,6 +350,41 @@ func updateSingleTag(
 	return firstErr
 }
 
+// Create a tag that doesn't exist yet, recording its ID in the updates.
+func createTag(
+	client api.ClientWithResponsesInterface,
+	projectID uuid.UUID,
+	updates *TagUpdates) error {
+	body := api.CreateExperienceTagInput{
+		Name:        updates.Name,
+		Description: updates.Name,
+	}
+	response, err := client.CreateExperienceTagWithResponse(context.Background(), projectID, body)
+	if err != nil {
+		return fmt.Errorf("failed to create tag: %s", err)
+	}
+	err = utils.ValidateResponseSafe(http.StatusCreated, "failed to create tag", response.HTTPResponse, response.Body)
+	if err != nil {
+		return err
+	}
+	if response.JSON201 == nil {
+		return fmt.Errorf("failed to create tag: empty response")
+	}
+	updates.TagID = response.JSON201.ExperienceTagID
+	return nil
+}
+
+func deleteTag(
+	client api.ClientWithResponsesInterface,
+	projectID uuid.UUID,
+	updates TagUpdates) error {
+	response, err := client.DeleteExperienceTagWithResponse(context.Background(), projectID, updates.TagID)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %s", err)
+	}
+	return utils.ValidateResponseSafe(http.StatusNoContent, "failed to delete tag", response.HTTPResponse, response.Body)
+}
+
 func updateSingleSystem(
 	client api.ClientWithResponsesInterface,
 	projectID uuid.UUID,
@@ -361,6 +424,21 @@ func updateSingleTestSuite(
 		experiences = append(experiences, *exp.ExperienceID)
 	}
 
+	if update.Create {
+		body := api.CreateTestSuiteInput{
+			Name:           update.Name,
+			Description:    update.Description,
+			SystemID:       update.SystemID,
+			MetricsBuildID: update.MetricsBuildID,
+			Experiences:    experiences,
+		}
+		response, err := client.CreateTestSuiteWithResponse(context.Background(), projectID, body)
+		if err != nil {
+			return fmt.Errorf("failed to create test suite: %s", err)
+		}
+		return utils.ValidateResponseSafe(http.StatusCreated, "failed to create test suite", response.HTTPResponse, response.Body)
+	}
+
 	body := api.ReviseTestSuiteInput{
 		Experiences: &experiences,
 	}
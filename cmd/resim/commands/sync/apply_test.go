@@ -483,3 +483,127 @@ containerTimeoutSeconds: 7200
 	// VERIFICATION
 	client.AssertNumberOfCalls(t, "ReviseTestSuiteWithResponse", 1)
 }
+
+func TestCreateAndDeleteTags(t *testing.T) {
+	// SETUP
+	var client mockapiclient.ClientWithResponsesInterface
+	var expectedProjectID = uuid.New()
+	var createdTagID = uuid.New()
+	var unusedTagID = uuid.New()
+
+	experienceToTag := &Experience{Name: "Test Experience", ExperienceID: Ptr(uuid.New())}
+
+	client.On("CreateExperienceTagWithResponse",
+		context.Background(),
+		expectedProjectID,
+		mock.Anything,
+	).Return(func(ctx context.Context, projectID api.ProjectID, body api.CreateExperienceTagInput,
+		reqEditors ...api.RequestEditorFn) (*api.CreateExperienceTagResponse, error) {
+		assert.Equal(t, "new-tag", body.Name)
+		return &api.CreateExperienceTagResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusCreated},
+			JSON201: &api.ExperienceTag{
+				Name:            body.Name,
+				ExperienceTagID: createdTagID,
+			},
+		}, nil
+	})
+	client.On("AddTagsToExperiencesWithResponse",
+		context.Background(),
+		expectedProjectID,
+		mock.Anything,
+		mock.Anything,
+	).Return(func(ctx context.Context, projectID api.ProjectID, body api.AddTagsToExperiencesInput,
+		reqEditors ...api.RequestEditorFn) (*api.AddTagsToExperiencesResponse, error) {
+		// The new tag's ID is used to tag the experience.
+		assert.Equal(t, []TagID{createdTagID}, body.ExperienceTagIDs)
+		assert.Equal(t, []ExperienceID{*experienceToTag.ExperienceID}, *body.Experiences)
+		return &api.AddTagsToExperiencesResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusCreated},
+		}, nil
+	})
+	client.On("DeleteExperienceTagWithResponse",
+		context.Background(),
+		expectedProjectID,
+		unusedTagID,
+	).Return(&api.DeleteExperienceTagResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusNoContent},
+	}, nil)
+
+	updates := ExperienceUpdates{
+		MatchedExperiencesByNewName: map[string]ExperienceMatch{},
+		TagUpdatesByName: map[string]*TagUpdates{
+			"new-tag": {
+				Name:      "new-tag",
+				Additions: []*Experience{experienceToTag},
+				Removals:  []*Experience{},
+				Create:    true,
+			},
+			"unused-tag": {
+				Name:      "unused-tag",
+				TagID:     unusedTagID,
+				Additions: []*Experience{},
+				Removals:  []*Experience{},
+				Delete:    true,
+			},
+		},
+		SystemUpdatesByName: make(map[string]*SystemUpdates),
+	}
+
+	// ACTION
+	err := applyUpdates(&client, expectedProjectID, updates)
+
+	// VERIFICATION
+	assert.NoError(t, err)
+	client.AssertNumberOfCalls(t, "CreateExperienceTagWithResponse", 1)
+	client.AssertNumberOfCalls(t, "AddTagsToExperiencesWithResponse", 1)
+	client.AssertNumberOfCalls(t, "DeleteExperienceTagWithResponse", 1)
+}
+
+func TestCreateTestSuiteApply(t *testing.T) {
+	// SETUP
+	var client mockapiclient.ClientWithResponsesInterface
+	var expectedProjectID = uuid.New()
+	var expectedSystemID = uuid.New()
+	var expectedMetricsBuildID = uuid.New()
+	var expectedExperienceID = uuid.New()
+
+	client.On("CreateTestSuiteWithResponse",
+		context.Background(),
+		expectedProjectID,
+		mock.Anything,
+	).Return(func(ctx context.Context, projectID api.ProjectID, body api.CreateTestSuiteInput,
+		reqEditors ...api.RequestEditorFn) (*api.CreateTestSuiteResponse, error) {
+		// VERIFICATION
+		assert.Equal(t, "Nightly CI", body.Name)
+		assert.Equal(t, "Runs every night", body.Description)
+		assert.Equal(t, expectedSystemID, body.SystemID)
+		assert.Equal(t, &expectedMetricsBuildID, body.MetricsBuildID)
+		assert.Equal(t, []ExperienceID{expectedExperienceID}, body.Experiences)
+		return &api.CreateTestSuiteResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusCreated},
+		}, nil
+	})
+
+	updates := ExperienceUpdates{
+		MatchedExperiencesByNewName: map[string]ExperienceMatch{},
+		TagUpdatesByName:            make(map[string]*TagUpdates),
+		SystemUpdatesByName:         make(map[string]*SystemUpdates),
+		TestSuiteUpdates: []TestSuiteUpdate{{
+			Name:           "Nightly CI",
+			Experiences:    []*Experience{{Name: "Test Experience", ExperienceID: &expectedExperienceID}},
+			Create:         true,
+			Description:    "Runs every night",
+			SystemID:       expectedSystemID,
+			MetricsBuildID: &expectedMetricsBuildID,
+		}},
+	}
+
+	// ACTION
+	err := applyUpdates(&client, expectedProjectID, updates)
+
+	// VERIFICATION
+	assert.NoError(t, err)
+	client.AssertNumberOfCalls(t, "CreateTestSuiteWithResponse", 1)
+	client.AssertNotCalled(t, "ReviseTestSuiteWithResponse", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -14,12 +14,12 @@ func SyncExperiences(client api.ClientWithResponsesInterface,
 	projectID uuid.UUID,
 	configPath string,
 	updateConfig bool,
-	shouldArchive bool,
+	options SyncOptions,
 ) {
 	if configPath == "" {
 		log.Fatal("experiences-config not set")
 	}
-	config, err := loadExperienceSyncConfig(configPath, false)
+	config, sources, err := loadExperienceSyncConfig(configPath, false)
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
@@ -27,7 +27,7 @@ func SyncExperiences(client api.ClientWithResponsesInterface,
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
-	experienceUpdates, err := computeExperienceUpdates(config, *currentState, shouldArchive)
+	experienceUpdates, err := computeExperienceUpdates(config, *currentState, options)
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
@@ -37,7 +37,7 @@ func SyncExperiences(client api.ClientWithResponsesInterface,
 	}
 
 	if updateConfig {
-		writeConfigToFile(config, configPath)
+		writeConfigToFiles(config, sources)
 	}
 }
 
@@ -47,7 +47,7 @@ func CloneExperiences(client api.ClientWithResponsesInterface,
 	if configPath == "" {
 		log.Fatal("experiences-config not set")
 	}
-	config, err := loadExperienceSyncConfig(configPath, true)
+	config, sources, err := loadExperienceSyncConfig(configPath, true)
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
@@ -55,16 +55,40 @@ func CloneExperiences(client api.ClientWithResponsesInterface,
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
-	config.Experiences = []Experience{}
+	experiences := []Experience{}
 	for _, experience := range currentState.ExperiencesByName {
 		if !experience.Archived {
-			config.Experiences = append(config.Experiences, *experience)
+			experiences = append(experiences, *experience)
 		}
 	}
-	writeConfigToFile(config, configPath)
+	sources.replaceExperiences(config.Experiences, experiences)
+	config.Experiences = experiences
+	writeConfigToFiles(config, sources)
 }
 
-func writeConfigToFile(config *ExperienceSyncConfig, path string) {
+// Write the config back to the files it was loaded from, each experience to the file it came from.
+// Each file keeps its own includes, managed tags, test suites and generators. Generated experiences
+// are left to their generators.
+func writeConfigToFiles(config *ExperienceSyncConfig, sources *configSources) {
+	experiencesByPath := map[string][]Experience{}
+	for ii, experience := range config.Experiences {
+		if _, generated := sources.generatedNames[experience.Name]; generated {
+			continue
+		}
+		path := sources.experiencePaths[ii]
+		experiencesByPath[path] = append(experiencesByPath[path], experience)
+	}
+	for _, path := range sources.paths {
+		file := *sources.filesByPath[path]
+		file.Experiences = experiencesByPath[path]
+		if file.Experiences == nil {
+			file.Experiences = []Experience{}
+		}
+		writeConfigToFile(&file, path)
+	}
+}
+
+func writeConfigToFile(config *experienceSyncConfigFile, path string) {
 	data, err := yaml.Marshal(config)
 	if err != nil {
 		log.Fatal("Failed to marshal updated config:", err)
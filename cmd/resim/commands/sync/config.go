@@ -2,37 +2,252 @@ package sync
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
 	"github.com/resim-ai/api-client/api"
 	"gopkg.in/yaml.v3"
-	"os"
 )
 
 type Experience = api.ExperienceSyncExperience
-type TestSuite = api.ExperienceSyncTestSuite
-type ExperienceSyncConfig = api.ExperienceSyncConfig
 
-func loadExperienceSyncConfig(path string, allowNew bool) (*ExperienceSyncConfig, error) {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		if allowNew {
-			return &ExperienceSyncConfig{}, nil
+// The sync config. It mirrors api.ExperienceSyncConfig, with test suites that sync can create.
+type ExperienceSyncConfig struct {
+	Experiences           []Experience `json:"experiences" yaml:"experiences"`
+	ManagedExperienceTags []string     `json:"managedExperienceTags" yaml:"managedExperienceTags"`
+	ManagedTestSuites     []TestSuite  `json:"managedTestSuites" yaml:"managedTestSuites"`
+}
+
+// A managed test suite. Its description, system and metrics build are only used to create it with
+// SyncOptions.CreateMissing when it doesn't exist.
+type TestSuite struct {
+	Name           string     `json:"name" yaml:"name"`
+	Experiences    []string   `json:"experiences" yaml:"experiences"`
+	Description    string     `json:"description,omitempty" yaml:"description,omitempty"`
+	System         string     `json:"system,omitempty" yaml:"system,omitempty"`
+	MetricsBuildID *uuid.UUID `json:"metricsBuildID,omitempty" yaml:"metricsBuildID,omitempty"`
+}
+
+// Options for how a sync reconciles the current state with the config.
+type SyncOptions struct {
+	// Archive current experiences that aren't in the config.
+	ShouldArchive bool `json:"shouldArchive"`
+	// Create the experience tags and test suites in the config that don't exist, rather than
+	// failing.
+	CreateMissing bool `json:"createMissing"`
+	// Delete managed experience tags that no unarchived experience has after the sync.
+	DeleteUnusedTags bool `json:"deleteUnusedTags"`
+}
+
+// A single file of an experience sync config. Besides experiences, tags and test suites, it can
+// include other files, given as paths or globs relative to the file, and generate experiences. See
+// experienceGenerator.
+type experienceSyncConfigFile struct {
+	Include              []string `yaml:"include,omitempty"`
+	ExperienceSyncConfig `yaml:",inline"`
+	Generators           []experienceGenerator `yaml:"generators,omitempty"`
+}
+
+// Where the parts of a config loaded from several files came from, so that they can be written back
+// to the same files.
+type configSources struct {
+	// The files in the order they were loaded. The first file is the one new experiences are
+	// written to.
+	paths []string
+	// The contents of each file as loaded.
+	filesByPath map[string]*experienceSyncConfigFile
+	// The file each of the config's experiences came from, by index.
+	experiencePaths []string
+	// The names of the experiences made by generators. They aren't written back to the files,
+	// which keep the generators instead.
+	generatedNames map[string]struct{}
+}
+
+// Replace the experiences of the config, placing each new experience in the file that had the
+// previous experience with its ID or, failing that, its name. Any others go to the first file.
+func (s *configSources) replaceExperiences(previous []Experience, experiences []Experience) {
+	pathsByID := map[ExperienceID]string{}
+	pathsByName := map[string]string{}
+	for ii, experience := range previous {
+		if experience.ExperienceID != nil {
+			pathsByID[*experience.ExperienceID] = s.experiencePaths[ii]
 		}
-		return nil, fmt.Errorf("config file does not exist: %s", path)
+		pathsByName[experience.Name] = s.experiencePaths[ii]
 	}
-	// Read file
-	data, err := os.ReadFile(path)
+	s.experiencePaths = make([]string, 0, len(experiences))
+	for _, experience := range experiences {
+		path, ok := "", false
+		if experience.ExperienceID != nil {
+			path, ok = pathsByID[*experience.ExperienceID]
+		}
+		if !ok {
+			path, ok = pathsByName[experience.Name]
+		}
+		if !ok {
+			path = s.paths[0]
+		}
+		s.experiencePaths = append(s.experiencePaths, path)
+	}
+}
 
+// Load the experience sync config at path, which can be a glob matching several files. The files and
+// the files they include are merged into one config. Fails if an experience or test suite is defined
+// more than once. If allowNew is set and path doesn't exist, returns an empty config to be written
+// to path.
+func loadExperienceSyncConfig(path string, allowNew bool) (*ExperienceSyncConfig, *configSources, error) {
+	paths, err := resolveConfigPaths(path)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to load config file: %s", err)
+		return nil, nil, err
 	}
-	var cfg ExperienceSyncConfig
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %s", err)
+	if len(paths) == 0 {
+		if allowNew && !isGlob(path) {
+			return &ExperienceSyncConfig{}, &configSources{
+				paths:       []string{path},
+				filesByPath: map[string]*experienceSyncConfigFile{path: {}},
+			}, nil
+		}
+		return nil, nil, fmt.Errorf("config file does not exist: %s", path)
+	}
+
+	loader := configLoader{
+		config:            &ExperienceSyncConfig{},
+		sources:           &configSources{filesByPath: map[string]*experienceSyncConfigFile{}, generatedNames: map[string]struct{}{}},
+		experiencesByName: map[string]string{},
+		experiencesByID:   map[ExperienceID]string{},
+		testSuitesByName:  map[string]string{},
+		managedTagsByName: map[string]struct{}{},
+	}
+	for _, path := range paths {
+		if err := loader.load(path); err != nil {
+			return nil, nil, err
+		}
 	}
 	// Do some normalization and validation
-	if err := NormalizeExperiences(cfg.Experiences); err != nil {
-		return nil, err
+	if err := NormalizeExperiences(loader.config.Experiences); err != nil {
+		return nil, nil, err
+	}
+	return loader.config, loader.sources, nil
+}
+
+// The state of loading a config from several files. Experiences and test suites are mapped to the
+// file that defines them, to report conflicts.
+type configLoader struct {
+	config            *ExperienceSyncConfig
+	sources           *configSources
+	experiencesByName map[string]string
+	experiencesByID   map[ExperienceID]string
+	testSuitesByName  map[string]string
+	managedTagsByName map[string]struct{}
+}
+
+func (l *configLoader) load(path string) error {
+	// A file included more than once, e.g. by overlapping globs, is only loaded the first time.
+	if _, loaded := l.sources.filesByPath[path]; loaded {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Failed to load config file: %s", err)
 	}
-	return &cfg, nil
+	var file experienceSyncConfigFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to unmarshal config %s: %s", path, err)
+	}
+	l.sources.paths = append(l.sources.paths, path)
+	l.sources.filesByPath[path] = &file
+
+	for _, experience := range file.Experiences {
+		if err := l.addExperience(experience, path); err != nil {
+			return err
+		}
+	}
+	for ii, generator := range file.Generators {
+		experiences, err := generator.expand(path)
+		if err != nil {
+			return fmt.Errorf("generator %d in %s: %w", ii+1, path, err)
+		}
+		for _, experience := range experiences {
+			if err := l.addExperience(experience, path); err != nil {
+				return err
+			}
+			l.sources.generatedNames[experience.Name] = struct{}{}
+		}
+	}
+	for _, testSuite := range file.ManagedTestSuites {
+		if err := checkedInsert(l.testSuitesByName, testSuite.Name, path); err != nil {
+			return conflictError("test suite", testSuite.Name, l.testSuitesByName[testSuite.Name], path)
+		}
+		l.config.ManagedTestSuites = append(l.config.ManagedTestSuites, testSuite)
+	}
+	// Several teams may manage the same tag.
+	for _, tag := range file.ManagedExperienceTags {
+		if checkedInsert(l.managedTagsByName, tag, struct{}{}) == nil {
+			l.config.ManagedExperienceTags = append(l.config.ManagedExperienceTags, tag)
+		}
+	}
+
+	for _, include := range file.Include {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		includedPaths, err := resolveConfigPaths(include)
+		if err != nil {
+			return err
+		}
+		if len(includedPaths) == 0 {
+			return fmt.Errorf("%s includes %s, which does not exist", path, include)
+		}
+		for _, includedPath := range includedPaths {
+			if err := l.load(includedPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *configLoader) addExperience(experience Experience, path string) error {
+	if err := checkedInsert(l.experiencesByName, experience.Name, path); err != nil {
+		return conflictError("experience", experience.Name, l.experiencesByName[experience.Name], path)
+	}
+	if experience.ExperienceID != nil {
+		if err := checkedInsert(l.experiencesByID, *experience.ExperienceID, path); err != nil {
+			return conflictError("experience ID", experience.ExperienceID.String(), l.experiencesByID[*experience.ExperienceID], path)
+		}
+	}
+	l.config.Experiences = append(l.config.Experiences, experience)
+	l.sources.experiencePaths = append(l.sources.experiencePaths, path)
+	return nil
+}
+
+func conflictError(kind string, name string, firstPath string, secondPath string) error {
+	if firstPath == secondPath {
+		return fmt.Errorf("%s %s is defined more than once in %s", kind, name, firstPath)
+	}
+	return fmt.Errorf("%s %s is defined in both %s and %s", kind, name, firstPath, secondPath)
+}
+
+// Resolve a config path or glob to the files it matches, in lexical order.
+func resolveConfigPaths(path string) ([]string, error) {
+	if !isGlob(path) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, nil
+		}
+		return []string{filepath.Clean(path)}, nil
+	}
+	paths, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config glob %s: %s", path, err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
 }
 
 func normalizeExperience(experience *Experience) error {
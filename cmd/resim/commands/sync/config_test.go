@@ -0,0 +1,268 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+// Write the given files, keyed by path relative to a temporary directory, and return the directory.
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for path, data := range files {
+		path = filepath.Join(dir, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0644))
+	}
+	return dir
+}
+
+func readConfigFile(t *testing.T, path string) experienceSyncConfigFile {
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var file experienceSyncConfigFile
+	assert.NoError(t, yaml.Unmarshal(data, &file))
+	return file
+}
+
+var rootConfigData = `
+include:
+  - teams/*.yaml
+managedExperienceTags: [regression]
+experiences:
+  - name: root-experience
+    description: Owned by the root config
+    locations: ["s3://bucket/root"]
+`
+
+var perceptionConfigData = `
+managedExperienceTags: [regression, perception]
+managedTestSuites:
+  - name: Perception Nightly
+    experiences: [perception-experience]
+experiences:
+  - name: perception-experience
+    experienceID: "0f6a3c4e-6c1b-4f4e-8d0a-7a9b3f2d1c01"
+    description: Owned by perception
+    locations: ["s3://bucket/perception"]
+`
+
+var planningConfigData = `
+include:
+  - ../shared/common.yaml
+experiences:
+  - name: planning-experience
+    description: Owned by planning
+    locations: ["s3://bucket/planning"]
+`
+
+var commonConfigData = `
+experiences:
+  - name: common-experience
+    description: Shared
+    locations: ["s3://bucket/common"]
+`
+
+func TestLoadConfigWithIncludes(t *testing.T) {
+	// SETUP
+	dir := writeConfigFiles(t, map[string]string{
+		"experiences.yaml":       rootConfigData,
+		"teams/perception.yaml":  perceptionConfigData,
+		"teams/planning.yaml":    planningConfigData,
+		"shared/common.yaml":     commonConfigData,
+		"teams/notes/other.yaml": "not: a config",
+	})
+
+	// ACTION
+	config, sources, err := loadExperienceSyncConfig(filepath.Join(dir, "experiences.yaml"), false)
+
+	// VERIFICATION
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"root-experience",
+		"perception-experience",
+		"planning-experience",
+		"common-experience",
+	}, experienceNamesInOrder(config.Experiences))
+	assert.Equal(t, []string{
+		filepath.Join(dir, "experiences.yaml"),
+		filepath.Join(dir, "teams/perception.yaml"),
+		filepath.Join(dir, "teams/planning.yaml"),
+		filepath.Join(dir, "shared/common.yaml"),
+	}, sources.experiencePaths)
+	assert.Equal(t, []string{"regression", "perception"}, config.ManagedExperienceTags)
+	assert.Len(t, config.ManagedTestSuites, 1)
+}
+
+func TestLoadConfigFromGlob(t *testing.T) {
+	// SETUP
+	dir := writeConfigFiles(t, map[string]string{
+		"teams/perception.yaml": perceptionConfigData,
+		"teams/planning.yaml":   planningConfigData,
+		"shared/common.yaml":    commonConfigData,
+	})
+
+	// ACTION
+	config, sources, err := loadExperienceSyncConfig(filepath.Join(dir, "teams/*.yaml"), false)
+
+	// VERIFICATION
+	assert.NoError(t, err)
+	assert.Len(t, config.Experiences, 3)
+	assert.Equal(t, filepath.Join(dir, "teams/perception.yaml"), sources.paths[0])
+}
+
+func TestLoadConfigFailsOnEmptyGlob(t *testing.T) {
+	// SETUP
+	dir := t.TempDir()
+
+	// ACTION
+	_, _, err := loadExperienceSyncConfig(filepath.Join(dir, "*.yaml"), true)
+
+	// VERIFICATION
+	assert.ErrorContains(t, err, "does not exist")
+}
+
+func TestLoadConfigFailsOnMissingInclude(t *testing.T) {
+	// SETUP
+	dir := writeConfigFiles(t, map[string]string{
+		"experiences.yaml": "include: [missing.yaml]\n",
+	})
+
+	// ACTION
+	_, _, err := loadExperienceSyncConfig(filepath.Join(dir, "experiences.yaml"), false)
+
+	// VERIFICATION
+	assert.ErrorContains(t, err, "missing.yaml, which does not exist")
+}
+
+func TestLoadConfigFailsOnConflicts(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		conflict string
+		expected string
+	}{
+		{
+			name: "name",
+			conflict: `
+experiences:
+  - name: perception-experience
+    description: Also claimed by planning
+    locations: ["s3://bucket/planning"]
+`,
+			expected: "experience perception-experience is defined in both",
+		},
+		{
+			name: "ID",
+			conflict: `
+experiences:
+  - name: renamed-by-planning
+    experienceID: "0f6a3c4e-6c1b-4f4e-8d0a-7a9b3f2d1c01"
+    description: Also claimed by planning
+    locations: ["s3://bucket/planning"]
+`,
+			expected: "experience ID 0f6a3c4e-6c1b-4f4e-8d0a-7a9b3f2d1c01 is defined in both",
+		},
+		{
+			name: "test suite",
+			conflict: `
+managedTestSuites:
+  - name: Perception Nightly
+    experiences: []
+`,
+			expected: "test suite Perception Nightly is defined in both",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			dir := writeConfigFiles(t, map[string]string{
+				"teams/perception.yaml": perceptionConfigData,
+				"teams/planning.yaml":   tc.conflict,
+			})
+
+			// ACTION
+			_, _, err := loadExperienceSyncConfig(filepath.Join(dir, "teams/*.yaml"), false)
+
+			// VERIFICATION
+			assert.ErrorContains(t, err, tc.expected)
+			assert.ErrorContains(t, err, filepath.Join(dir, "teams/perception.yaml"))
+			assert.ErrorContains(t, err, filepath.Join(dir, "teams/planning.yaml"))
+		})
+	}
+}
+
+func TestWriteConfigToFiles(t *testing.T) {
+	// SETUP
+	dir := writeConfigFiles(t, map[string]string{
+		"experiences.yaml":      rootConfigData,
+		"teams/perception.yaml": perceptionConfigData,
+		"teams/planning.yaml":   planningConfigData,
+		"shared/common.yaml":    commonConfigData,
+	})
+	config, sources, err := loadExperienceSyncConfig(filepath.Join(dir, "experiences.yaml"), false)
+	assert.NoError(t, err)
+	// As if syncing had matched the experience by name.
+	planningID := uuid.New()
+	config.Experiences[2].ExperienceID = &planningID
+
+	// ACTION
+	writeConfigToFiles(config, sources)
+
+	// VERIFICATION
+	root := readConfigFile(t, filepath.Join(dir, "experiences.yaml"))
+	assert.Equal(t, []string{"teams/*.yaml"}, root.Include)
+	assert.Equal(t, []string{"root-experience"}, experienceNamesInOrder(root.Experiences))
+	assert.Equal(t, []string{"regression"}, root.ManagedExperienceTags)
+
+	perception := readConfigFile(t, filepath.Join(dir, "teams/perception.yaml"))
+	assert.Equal(t, []string{"perception-experience"}, experienceNamesInOrder(perception.Experiences))
+	assert.Len(t, perception.ManagedTestSuites, 1)
+
+	planning := readConfigFile(t, filepath.Join(dir, "teams/planning.yaml"))
+	assert.Equal(t, []string{"../shared/common.yaml"}, planning.Include)
+	assert.Equal(t, []string{"planning-experience"}, experienceNamesInOrder(planning.Experiences))
+	assert.Equal(t, &planningID, planning.Experiences[0].ExperienceID)
+}
+
+func TestReplaceExperiencesForClone(t *testing.T) {
+	// SETUP
+	dir := writeConfigFiles(t, map[string]string{
+		"experiences.yaml":      rootConfigData,
+		"teams/perception.yaml": perceptionConfigData,
+		"teams/planning.yaml":   planningConfigData,
+		"shared/common.yaml":    commonConfigData,
+	})
+	config, sources, err := loadExperienceSyncConfig(filepath.Join(dir, "experiences.yaml"), false)
+	assert.NoError(t, err)
+	perceptionID := *config.Experiences[1].ExperienceID
+	cloned := []Experience{
+		// Renamed in the app, so matched by ID.
+		{Name: "perception-experience-v2", ExperienceID: &perceptionID},
+		// Matched by name.
+		{Name: "common-experience", ExperienceID: Ptr(uuid.New())},
+		// Created in the app.
+		{Name: "new-in-the-app", ExperienceID: Ptr(uuid.New())},
+	}
+
+	// ACTION
+	sources.replaceExperiences(config.Experiences, cloned)
+
+	// VERIFICATION
+	assert.Equal(t, []string{
+		filepath.Join(dir, "teams/perception.yaml"),
+		filepath.Join(dir, "shared/common.yaml"),
+		filepath.Join(dir, "experiences.yaml"),
+	}, sources.experiencePaths)
+}
+
+func experienceNamesInOrder(experiences []Experience) []string {
+	names := []string{}
+	for _, experience := range experiences {
+		names = append(names, experience.Name)
+	}
+	return names
+}
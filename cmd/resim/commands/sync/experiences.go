@@ -18,13 +18,13 @@ type ExperienceUpdates struct {
 func computeExperienceUpdates(
 	config *ExperienceSyncConfig,
 	currentState DatabaseState,
-	shouldArchive bool) (*ExperienceUpdates, error) {
-	matchedExperiencesByNewName, err := matchExperiences(config, currentState.ExperiencesByName, shouldArchive)
+	options SyncOptions) (*ExperienceUpdates, error) {
+	matchedExperiencesByNewName, err := matchExperiences(config, currentState.ExperiencesByName, options.ShouldArchive)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to compute experience updates: %w", err)
 	}
 
-	tagUpdates, err := getTagUpdates(matchedExperiencesByNewName, currentState.TagSetsByName, config.ManagedExperienceTags)
+	tagUpdates, err := getTagUpdates(matchedExperiencesByNewName, currentState.TagSetsByName, config.ManagedExperienceTags, options)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to compute tag updates: %w", err)
 	}
@@ -35,7 +35,7 @@ func computeExperienceUpdates(
 	}
 
 	testSuiteUpdates, err := getTestSuiteUpdates(matchedExperiencesByNewName, config.ManagedTestSuites,
-		currentState.TestSuiteIDsByName)
+		currentState.TestSuiteIDsByName, currentState.SystemSetsByName, options.CreateMissing)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to compute test suite updates: %w", err)
 	}
@@ -65,7 +65,7 @@ func TestUpdateExperiencesEmpty(t *testing.T) {
 	currentState := DatabaseState{}
 
 	// ACTION
-	experienceUpdates, err := computeExperienceUpdates(&config, currentState, true)
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 
 	// VERIFICATION
 	assert.NoError(t, err)
@@ -94,7 +94,7 @@ managedTestSuites:
 	currentState, config := loaderHelper(t, currentStateData, configData, nil, nil)
 
 	// ACTION
-	experienceUpdates, err := computeExperienceUpdates(&config, currentState, true)
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 
 	// VERIFICATION
 	assert.NoError(t, err)
@@ -128,7 +128,7 @@ experiences:
 	currentState, config := loaderHelper(t, currentStateData, configData, nil, nil)
 
 	// ACTION
-	experienceUpdates, err := computeExperienceUpdates(&config, currentState, true)
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 
 	// VERIFICATION
 	assert.NoError(t, err)
@@ -202,7 +202,7 @@ experiences:
 	currentState, config := loaderHelper(t, currentStateData, configData, nil, nil)
 
 	// ACTION
-	experienceUpdates, err := computeExperienceUpdates(&config, currentState, true)
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 
 	// VERIFICATION
 	assert.NoError(t, err)
@@ -244,7 +244,7 @@ experiences:
 	currentState, config := loaderHelper(t, currentStateData, configData, nil, nil)
 
 	// ACTION / VERIFICATION
-	_, err := computeExperienceUpdates(&config, currentState, true)
+	_, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 	assert.Error(t, err)
 
 	// SETUP
@@ -267,7 +267,7 @@ experiences:
 	currentState, config = loaderHelper(t, currentStateData, configData, nil, nil)
 
 	// ACTION / VERIFICATION
-	_, err = computeExperienceUpdates(&config, currentState, true)
+	_, err = computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 	assert.Error(t, err)
 }
 
@@ -289,7 +289,7 @@ experiences:
 	currentState, config := loaderHelper(t, currentStateData, configData, nil, nil)
 
 	// ACTION / VERIFICATION
-	_, err := computeExperienceUpdates(&config, currentState, true)
+	_, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 	assert.Error(t, err)
 }
 
@@ -315,7 +315,7 @@ experiences:
 	currentState, config := loaderHelper(t, currentStateData, configData, nil, nil)
 
 	// ACTION / VERIFICATION
-	_, err := computeExperienceUpdates(&config, currentState, true)
+	_, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 	assert.Error(t, err)
 }
 
@@ -350,7 +350,7 @@ experiences:
 	currentState, config := loaderHelper(t, currentStateData, configData, nil, nil)
 
 	// ACTION / VERIFICATION
-	_, err := computeExperienceUpdates(&config, currentState, true)
+	_, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 	assert.Error(t, err)
 }
 
@@ -378,7 +378,7 @@ experiences:
 	currentState, config := loaderHelper(t, currentStateData, configData, nil, nil)
 
 	// ACTION / VERIFICATION
-	_, err := computeExperienceUpdates(&config, currentState, true)
+	_, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 	assert.Error(t, err)
 }
 
@@ -422,7 +422,7 @@ experiences:
 	currentState, config := loaderHelper(t, currentStateData, configData, []string{"regression", "my-special-tag"}, nil)
 
 	// ACTION
-	experienceUpdates, err := computeExperienceUpdates(&config, currentState, true)
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 
 	// VERIFICATION
 	assert.NoError(t, err)
@@ -467,7 +467,7 @@ experiences:
 	currentState, config := loaderHelper(t, currentStateData, configData, nil, []string{"planner"})
 
 	// ACTION
-	experienceUpdates, err := computeExperienceUpdates(&config, currentState, true)
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 
 	// VERIFICATION
 	assert.NoError(t, err)
@@ -505,7 +505,7 @@ experiences:
 	}
 
 	// ACTION
-	experienceUpdates, err := computeExperienceUpdates(&config, currentState, true)
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 
 	// VERIFICATION
 	assert.NoError(t, err)
@@ -545,7 +545,7 @@ experiences:
 	}
 
 	// ACTION
-	_, err := computeExperienceUpdates(&config, currentState, true)
+	_, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
 
 	// VERIFICATION
 	assert.Error(t, err)
@@ -572,7 +572,7 @@ experiences:
 
 	// ACTION
 	shouldArchive := false
-	experienceUpdates, err := computeExperienceUpdates(&config, currentState, shouldArchive)
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: shouldArchive})
 
 	// VERIFICATION
 	assert.NoError(t, err)
@@ -594,3 +594,202 @@ experiences:
 	assert.Equal(t, match.Original.ContainerTimeoutSeconds, match.New.ContainerTimeoutSeconds)
 	assert.False(t, match.New.Archived, "Experience should not be archived")
 }
+
+func TestCreateMissingTags(t *testing.T) {
+	// SETUP
+	currentStateData := `
+  - name: Test Experience
+    experienceID: "628eccf2-2621-4fdf-a8d8-c6b057ce2f0d"
+    tags: []
+    description: Some current experience
+    locations: ["somewhere_over_the_rainbow"]
+`
+	configData := `
+managedExperienceTags:
+  - regression
+  - unused-new-tag
+experiences:
+  - name: Test Experience
+    tags: ["regression", "new-tag"]
+    description: Some current experience
+    locations: ["somewhere_over_the_rainbow"]
+`
+	currentState, config := loaderHelper(t, currentStateData, configData, nil, nil)
+
+	// ACTION
+	_, errWithoutCreate := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{
+		ShouldArchive:    true,
+		CreateMissing:    true,
+		DeleteUnusedTags: true,
+	})
+
+	// VERIFICATION
+	assert.Error(t, errWithoutCreate)
+	assert.NoError(t, err)
+	for _, tag := range []string{"regression", "new-tag"} {
+		tagUpdates, contains := experienceUpdates.TagUpdatesByName[tag]
+		assert.True(t, contains, "Tag should be contained in TagUpdatesByName")
+		assert.True(t, tagUpdates.Create, "Tag should be created")
+		assert.Equal(t, []*Experience{&config.Experiences[0]}, tagUpdates.Additions)
+	}
+	// A managed tag nobody uses isn't created just to be deleted.
+	assert.NotContains(t, experienceUpdates.TagUpdatesByName, "unused-new-tag")
+}
+
+func TestDeleteUnusedTags(t *testing.T) {
+	// SETUP
+	currentStateData := `
+  - name: Test Experience
+    experienceID: "628eccf2-2621-4fdf-a8d8-c6b057ce2f0d"
+    tags: ["regression", "deprecated", "unmanaged"]
+    description: Some current experience
+    locations: ["somewhere_over_the_rainbow"]
+  - name: Archived Experience
+    experienceID: "62501c04-3da2-4a46-94b1-ab90e32b2059"
+    tags: ["soon-unused"]
+    description: Some current experience
+    locations: ["somewhere_over_the_rainbow"]
+`
+	configData := `
+managedExperienceTags:
+  - regression
+  - deprecated
+  - soon-unused
+experiences:
+  - name: Test Experience
+    tags: ["regression"]
+    description: Some current experience
+    locations: ["somewhere_over_the_rainbow"]
+`
+	currentState, config := loaderHelper(t, currentStateData, configData, []string{"regression", "deprecated", "soon-unused", "unmanaged"}, nil)
+
+	// ACTION
+	withoutDelete, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
+	assert.NoError(t, err)
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true, DeleteUnusedTags: true})
+
+	// VERIFICATION
+	assert.NoError(t, err)
+	for _, update := range withoutDelete.TagUpdatesByName {
+		assert.False(t, update.Delete, "Tags should only be deleted when asked to")
+	}
+	assert.False(t, experienceUpdates.TagUpdatesByName["regression"].Delete)
+	assert.True(t, experienceUpdates.TagUpdatesByName["deprecated"].Delete)
+	// Its only experience is archived.
+	assert.True(t, experienceUpdates.TagUpdatesByName["soon-unused"].Delete)
+	// Unmanaged tags are never deleted.
+	assert.False(t, experienceUpdates.TagUpdatesByName["unmanaged"].Delete)
+}
+
+func TestSyncAfterDeletingUnusedTags(t *testing.T) {
+	// SETUP
+	configData := `
+managedExperienceTags:
+  - regression
+  - deprecated
+experiences:
+  - name: Test Experience
+    tags: ["regression"]
+    description: Some current experience
+    locations: ["somewhere_over_the_rainbow"]
+`
+	currentState, config := loaderHelper(t, `
+  - name: Test Experience
+    experienceID: "628eccf2-2621-4fdf-a8d8-c6b057ce2f0d"
+    tags: ["regression", "deprecated"]
+    description: Some current experience
+    locations: ["somewhere_over_the_rainbow"]
+`, configData, []string{"regression", "deprecated"}, nil)
+	firstUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true, DeleteUnusedTags: true})
+	assert.NoError(t, err)
+	assert.True(t, firstUpdates.TagUpdatesByName["deprecated"].Delete)
+
+	// The state after the first sync: the deleted tag is gone, but the config still lists it.
+	currentState, config = loaderHelper(t, `
+  - name: Test Experience
+    experienceID: "628eccf2-2621-4fdf-a8d8-c6b057ce2f0d"
+    tags: ["regression"]
+    description: Some current experience
+    locations: ["somewhere_over_the_rainbow"]
+`, configData, []string{"regression"}, nil)
+
+	// ACTION
+	secondUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
+
+	// VERIFICATION
+	assert.NoError(t, err)
+	assert.NotContains(t, secondUpdates.TagUpdatesByName, "deprecated")
+	assert.Empty(t, describeExperienceUpdates(*secondUpdates, currentState))
+
+	// An experience with the deleted tag is still an error without CreateMissing.
+	config.Experiences[0].Tags = []string{"regression", "deprecated"}
+	_, err = computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
+	assert.ErrorContains(t, err, "Non-existent tag: deprecated")
+}
+
+func TestCreateMissingTestSuite(t *testing.T) {
+	// SETUP
+	currentStateData := `
+  - name: Test Experience
+    experienceID: "628eccf2-2621-4fdf-a8d8-c6b057ce2f0d"
+    description: Some current experience
+    locations: ["somewhere_over_the_rainbow"]
+`
+	configData := `
+managedTestSuites:
+  - name: "Nightly CI"
+    description: Runs every night
+    system: planner
+    metricsBuildID: "3dd91177-1e66-426c-bf5b-fb46fe4a0c3b"
+    experiences:
+     - Test Experience
+experiences:
+  - name: Test Experience
+    description: Some current experience
+    locations: ["somewhere_over_the_rainbow"]
+`
+	currentState, config := loaderHelper(t, currentStateData, configData, nil, []string{"planner"})
+
+	// ACTION
+	_, errWithoutCreate := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true, CreateMissing: true})
+
+	// VERIFICATION
+	assert.Error(t, errWithoutCreate)
+	assert.NoError(t, err)
+	assert.Len(t, experienceUpdates.TestSuiteUpdates, 1)
+	update := experienceUpdates.TestSuiteUpdates[0]
+	assert.True(t, update.Create)
+	assert.Equal(t, "Nightly CI", update.Name)
+	assert.Equal(t, "Runs every night", update.Description)
+	assert.Equal(t, currentState.SystemSetsByName["planner"].SystemID, update.SystemID)
+	assert.Equal(t, uuid.MustParse("3dd91177-1e66-426c-bf5b-fb46fe4a0c3b"), *update.MetricsBuildID)
+	assert.Equal(t, []*Experience{&config.Experiences[0]}, update.Experiences)
+}
+
+func TestCreateMissingTestSuiteNeedsSystem(t *testing.T) {
+	// SETUP
+	configData := `
+managedTestSuites:
+  - name: "Nightly CI"
+    experiences: []
+  - name: "Weekly CI"
+    system: no-such-system
+    experiences: []
+experiences:
+`
+	currentState, config := loaderHelper(t, "", configData, nil, []string{"planner"})
+	withoutSystem := config
+	withoutSystem.ManagedTestSuites = config.ManagedTestSuites[:1]
+	withUnknownSystem := config
+	withUnknownSystem.ManagedTestSuites = config.ManagedTestSuites[1:]
+
+	// ACTION
+	_, errWithoutSystem := computeExperienceUpdates(&withoutSystem, currentState, SyncOptions{CreateMissing: true})
+	_, errWithUnknownSystem := computeExperienceUpdates(&withUnknownSystem, currentState, SyncOptions{CreateMissing: true})
+
+	// VERIFICATION
+	assert.ErrorContains(t, errWithoutSystem, "has no system to create it with")
+	assert.ErrorContains(t, errWithUnknownSystem, "Non-existent system for test suite Weekly CI: no-such-system")
+}
@@ -0,0 +1,243 @@
+package sync
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// A generator of experiences in a sync config. Every combination of the values of the matrix
+// variables and the manifest's locations gives one experience, made by rendering the strings of the
+// experience template with text/template. For example:
+//
+//	generators:
+//	  - matrix:
+//	      weather: [sunny, rain, fog]
+//	    manifest:
+//	      path: tiles.txt
+//	      pattern: s3://maps/tiles/*/
+//	    experience:
+//	      name: "tile-{{ base .location }}-{{ .weather }}"
+//	      description: "Map tile {{ base .location }} in {{ .weather }} weather"
+//	      locations: ["{{ .location }}"]
+//	      environmentVariables:
+//	        - name: WEATHER
+//	          value: "{{ .weather }}"
+//
+// Since experiences are matched by name, the name must depend on every variable so that each
+// combination always generates the same name.
+type experienceGenerator struct {
+	Matrix     map[string][]string `yaml:"matrix,omitempty"`
+	Manifest   *generatorManifest  `yaml:"manifest,omitempty"`
+	Experience Experience          `yaml:"experience"`
+}
+
+// A local file listing location prefixes, one per line. Blank lines and lines starting with # are
+// skipped.
+type generatorManifest struct {
+	// The path of the manifest, relative to the config file.
+	Path string `yaml:"path"`
+	// If set, only the lines matching this glob are used.
+	Pattern string `yaml:"pattern,omitempty"`
+	// The variable the locations are assigned to. Defaults to "location".
+	Variable string `yaml:"variable,omitempty"`
+}
+
+const defaultManifestVariable = "location"
+
+// The functions available to generator templates, mostly for deriving names from locations.
+var generatorTemplateFuncs = template.FuncMap{
+	"base": func(s string) string {
+		return path.Base(strings.TrimSuffix(s, "/"))
+	},
+	"trimPrefix": func(prefix string, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix string, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old string, new string, s string) string { return strings.ReplaceAll(s, old, new) },
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+}
+
+// Expand the generator into its experiences, in a stable order. configPath is the file the
+// generator is defined in.
+func (g *experienceGenerator) expand(configPath string) ([]Experience, error) {
+	if g.Experience.Name == "" {
+		return nil, fmt.Errorf("the experience template has no name")
+	}
+	if g.Experience.ExperienceID != nil {
+		return nil, fmt.Errorf("the experience template can't set an experienceID")
+	}
+	variables, err := g.variables(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	experiences := []Experience{}
+	names := map[string]struct{}{}
+	for _, values := range combinations(variables) {
+		experience, err := renderExperience(g.Experience, values)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkedInsert(names, experience.Name, struct{}{}); err != nil {
+			return nil, fmt.Errorf("experience %s is generated more than once; its name must use every variable", experience.Name)
+		}
+		experiences = append(experiences, experience)
+	}
+	return experiences, nil
+}
+
+type generatorVariable struct {
+	name   string
+	values []string
+}
+
+// The generator's variables, sorted by name, with the manifest's last.
+func (g *experienceGenerator) variables(configPath string) ([]generatorVariable, error) {
+	variables := []generatorVariable{}
+	for _, name := range slices.Sorted(maps.Keys(g.Matrix)) {
+		if len(g.Matrix[name]) == 0 {
+			return nil, fmt.Errorf("matrix variable %s has no values", name)
+		}
+		variables = append(variables, generatorVariable{name: name, values: g.Matrix[name]})
+	}
+	if g.Manifest != nil {
+		name := g.Manifest.Variable
+		if name == "" {
+			name = defaultManifestVariable
+		}
+		if _, exists := g.Matrix[name]; exists {
+			return nil, fmt.Errorf("manifest variable %s is also a matrix variable", name)
+		}
+		locations, err := g.Manifest.locations(configPath)
+		if err != nil {
+			return nil, err
+		}
+		variables = append(variables, generatorVariable{name: name, values: locations})
+	}
+	if len(variables) == 0 {
+		return nil, fmt.Errorf("a generator needs a matrix or a manifest")
+	}
+	return variables, nil
+}
+
+// Read the manifest's locations matching its pattern, without duplicates.
+func (m *generatorManifest) locations(configPath string) ([]string, error) {
+	manifestPath := m.Path
+	if !filepath.IsAbs(manifestPath) {
+		manifestPath = filepath.Join(filepath.Dir(configPath), manifestPath)
+	}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	locations := []string{}
+	seen := map[string]struct{}{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m.Pattern != "" {
+			matched, err := path.Match(m.Pattern, line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid manifest pattern %s: %w", m.Pattern, err)
+			}
+			if !matched {
+				continue
+			}
+		}
+		if checkedInsert(seen, line, struct{}{}) == nil {
+			locations = append(locations, line)
+		}
+	}
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("manifest %s has no locations matching %q", manifestPath, m.Pattern)
+	}
+	return locations, nil
+}
+
+// Every combination of the values of the variables, varying the last variable fastest.
+func combinations(variables []generatorVariable) []map[string]string {
+	result := []map[string]string{{}}
+	for _, variable := range variables {
+		next := make([]map[string]string, 0, len(result)*len(variable.values))
+		for _, combination := range result {
+			for _, value := range variable.values {
+				extended := make(map[string]string, len(combination)+1)
+				for k, v := range combination {
+					extended[k] = v
+				}
+				extended[variable.name] = value
+				next = append(next, extended)
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// Render the strings of an experience template with the given values. Other fields are copied as
+// they are.
+func renderExperience(tmpl Experience, values map[string]string) (Experience, error) {
+	experience := tmpl
+	var err error
+	render := func(text string) string {
+		if err != nil {
+			return ""
+		}
+		var rendered string
+		rendered, err = renderTemplate(text, values)
+		return rendered
+	}
+	renderAll := func(texts []string) []string {
+		if texts == nil {
+			return nil
+		}
+		rendered := make([]string, 0, len(texts))
+		for _, text := range texts {
+			rendered = append(rendered, render(text))
+		}
+		return rendered
+	}
+
+	experience.Name = render(tmpl.Name)
+	experience.Description = render(tmpl.Description)
+	experience.Locations = renderAll(tmpl.Locations)
+	experience.Tags = renderAll(tmpl.Tags)
+	experience.Systems = renderAll(tmpl.Systems)
+	if tmpl.Profile != nil {
+		profile := render(*tmpl.Profile)
+		experience.Profile = &profile
+	}
+	if tmpl.EnvironmentVariables != nil {
+		environmentVariables := make([]EnvironmentVariable, 0, len(*tmpl.EnvironmentVariables))
+		for _, variable := range *tmpl.EnvironmentVariables {
+			environmentVariables = append(environmentVariables, EnvironmentVariable{
+				Name:  render(variable.Name),
+				Value: render(variable.Value),
+			})
+		}
+		experience.EnvironmentVariables = &environmentVariables
+	}
+	if err != nil {
+		return Experience{}, err
+	}
+	return experience, nil
+}
+
+func renderTemplate(text string, values map[string]string) (string, error) {
+	tmpl, err := template.New("").Funcs(generatorTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", text, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, values); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %w", text, err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,232 @@
+package sync
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/stretchr/testify/assert"
+)
+
+var generatorConfigData = `
+managedExperienceTags: [maps]
+experiences:
+  - name: hand-written
+    description: Not generated
+    locations: ["s3://bucket/hand-written"]
+generators:
+  - matrix:
+      weather: [sunny, rain]
+    manifest:
+      path: manifests/tiles.txt
+      pattern: s3://maps/tiles/*/
+    experience:
+      name: "tile-{{ base .location }}-{{ .weather }}"
+      description: "Map tile {{ base .location }} in {{ .weather }} weather"
+      locations: ["{{ .location }}"]
+      tags: [maps, "{{ .weather }}"]
+      profile: "{{ .weather }}-profile"
+      environmentVariables:
+        - name: WEATHER
+          value: "{{ upper .weather }}"
+`
+
+var tilesManifestData = `
+# Tiles to simulate
+s3://maps/tiles/001/
+s3://maps/tiles/002/
+
+s3://maps/other/003/
+s3://maps/tiles/001/
+`
+
+func TestExpandGenerators(t *testing.T) {
+	// SETUP
+	dir := writeConfigFiles(t, map[string]string{
+		"experiences.yaml":    generatorConfigData,
+		"manifests/tiles.txt": tilesManifestData,
+	})
+
+	// ACTION
+	config, sources, err := loadExperienceSyncConfig(filepath.Join(dir, "experiences.yaml"), false)
+
+	// VERIFICATION
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"hand-written",
+		"tile-001-sunny",
+		"tile-002-sunny",
+		"tile-001-rain",
+		"tile-002-rain",
+	}, experienceNamesInOrder(config.Experiences))
+
+	experience := config.Experiences[3]
+	assert.Equal(t, "Map tile 001 in rain weather", experience.Description)
+	assert.Equal(t, []string{"s3://maps/tiles/001/"}, experience.Locations)
+	assert.Equal(t, []string{"maps", "rain"}, experience.Tags)
+	assert.Equal(t, "rain-profile", *experience.Profile)
+	assert.Equal(t, []EnvironmentVariable{{Name: "WEATHER", Value: "RAIN"}}, *experience.EnvironmentVariables)
+	assert.Nil(t, experience.ExperienceID)
+
+	assert.Len(t, sources.generatedNames, 4)
+	assert.NotContains(t, sources.generatedNames, "hand-written")
+}
+
+func TestGeneratedNamesAreStable(t *testing.T) {
+	// SETUP
+	// The same tiles, listed in another order and with one more.
+	reordered := `
+s3://maps/tiles/003/
+s3://maps/tiles/002/
+s3://maps/tiles/001/
+`
+	dir := writeConfigFiles(t, map[string]string{
+		"experiences.yaml":    generatorConfigData,
+		"manifests/tiles.txt": tilesManifestData,
+	})
+	reorderedDir := writeConfigFiles(t, map[string]string{
+		"experiences.yaml":    generatorConfigData,
+		"manifests/tiles.txt": reordered,
+	})
+	config, _, err := loadExperienceSyncConfig(filepath.Join(dir, "experiences.yaml"), false)
+	assert.NoError(t, err)
+	reorderedConfig, _, err := loadExperienceSyncConfig(filepath.Join(reorderedDir, "experiences.yaml"), false)
+	assert.NoError(t, err)
+	currentState, _ := loaderHelper(t, "", "", []string{"maps", "sunny", "rain"}, nil)
+	for ii := range config.Experiences {
+		config.Experiences[ii].ExperienceID = Ptr(uuid.New())
+		currentState.ExperiencesByName[config.Experiences[ii].Name] = &config.Experiences[ii]
+	}
+
+	// ACTION
+	experienceUpdates, err := computeExperienceUpdates(reorderedConfig, currentState, SyncOptions{ShouldArchive: true})
+
+	// VERIFICATION
+	// Only the new tile is created: every other experience keeps its name, so nothing is renamed.
+	assert.NoError(t, err)
+	changes := describeExperienceUpdates(*experienceUpdates, currentState)
+	experienceChanges := []string{}
+	for _, change := range changes {
+		if change.Kind != planMembership {
+			experienceChanges = append(experienceChanges, change.Lines...)
+		}
+	}
+	assert.Equal(t, []string{
+		"+ create experience tile-003-rain",
+		"+ create experience tile-003-sunny",
+	}, experienceChanges)
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		generator string
+		expected  string
+	}{
+		{
+			name: "name doesn't use every variable",
+			generator: `
+  - matrix:
+      weather: [sunny, rain]
+      time: [day, night]
+    experience:
+      name: "tile-{{ .weather }}"
+      description: Tile
+      locations: ["s3://maps/tile"]
+`,
+			expected: "generator 1 in",
+		},
+		{
+			name: "unknown variable",
+			generator: `
+  - matrix:
+      weather: [sunny]
+    experience:
+      name: "tile-{{ .wether }}"
+      description: Tile
+      locations: ["s3://maps/tile"]
+`,
+			expected: "failed to render template",
+		},
+		{
+			name: "no variables",
+			generator: `
+  - experience:
+      name: tile
+      description: Tile
+      locations: ["s3://maps/tile"]
+`,
+			expected: "a generator needs a matrix or a manifest",
+		},
+		{
+			name: "no manifest locations",
+			generator: `
+  - manifest:
+      path: manifests/tiles.txt
+      pattern: s3://nowhere/*/
+    experience:
+      name: "{{ base .location }}"
+      description: Tile
+      locations: ["{{ .location }}"]
+`,
+			expected: "has no locations matching",
+		},
+		{
+			name: "clashes with a hand-written experience",
+			generator: `
+  - matrix:
+      tile: [hand-written]
+    experience:
+      name: "{{ .tile }}"
+      description: Tile
+      locations: ["s3://maps/tile"]
+`,
+			expected: "experience hand-written is defined more than once",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			dir := writeConfigFiles(t, map[string]string{
+				"experiences.yaml": `
+experiences:
+  - name: hand-written
+    description: Not generated
+    locations: ["s3://bucket/hand-written"]
+generators:
+` + tc.generator,
+				"manifests/tiles.txt": tilesManifestData,
+			})
+
+			// ACTION
+			_, _, err := loadExperienceSyncConfig(filepath.Join(dir, "experiences.yaml"), false)
+
+			// VERIFICATION
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+func TestWriteConfigKeepsGenerators(t *testing.T) {
+	// SETUP
+	dir := writeConfigFiles(t, map[string]string{
+		"experiences.yaml":    generatorConfigData,
+		"manifests/tiles.txt": tilesManifestData,
+	})
+	path := filepath.Join(dir, "experiences.yaml")
+	config, sources, err := loadExperienceSyncConfig(path, false)
+	assert.NoError(t, err)
+
+	// ACTION
+	writeConfigToFiles(config, sources)
+
+	// VERIFICATION
+	file := readConfigFile(t, path)
+	assert.Equal(t, []string{"hand-written"}, experienceNamesInOrder(file.Experiences))
+	assert.Len(t, file.Generators, 1)
+	assert.Equal(t, "tile-{{ base .location }}-{{ .weather }}", file.Generators[0].Experience.Name)
+
+	reloaded, _, err := loadExperienceSyncConfig(path, false)
+	assert.NoError(t, err)
+	assert.Equal(t, experienceNamesInOrder(config.Experiences), experienceNamesInOrder(reloaded.Experiences))
+}
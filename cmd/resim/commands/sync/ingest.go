@@ -35,6 +35,9 @@ type DatabaseState struct {
 	TagSetsByName      map[string]TagSet
 	SystemSetsByName   map[string]SystemSet
 	TestSuiteIDsByName map[string]TestSuiteID
+	// The experiences currently in each test suite, used to describe a plan's
+	// membership changes.
+	TestSuiteExperienceIDsByName map[string][]ExperienceID
 }
 
 type Result[T any] struct {
@@ -51,7 +54,7 @@ func getCurrentDatabaseState(client api.ClientWithResponsesInterface,
 	expCh := make(chan Result[map[string]*Experience])
 	tagCh := make(chan Result[map[string]TagSet])
 	sysCh := make(chan Result[map[string]SystemSet])
-	tsCh := make(chan Result[map[string]TestSuiteID])
+	tsCh := make(chan Result[testSuiteSets])
 
 	go func() {
 		exp, err := getCurrentExperiencesByName(client, projectID)
@@ -66,7 +69,7 @@ func getCurrentDatabaseState(client api.ClientWithResponsesInterface,
 		sysCh <- wrapResult(sys, err)
 	}()
 	go func() {
-		ts, err := getCurrentTestSuitesIDsByName(client, projectID)
+		ts, err := getCurrentTestSuitesByName(client, projectID)
 		tsCh <- wrapResult(ts, err)
 	}()
 
@@ -88,10 +91,11 @@ func getCurrentDatabaseState(client api.ClientWithResponsesInterface,
 	}
 
 	state := DatabaseState{
-		ExperiencesByName:  expRes.Val,
-		TagSetsByName:      tagRes.Val,
-		SystemSetsByName:   sysRes.Val,
-		TestSuiteIDsByName: tsRes.Val,
+		ExperiencesByName:            expRes.Val,
+		TagSetsByName:                tagRes.Val,
+		SystemSetsByName:             sysRes.Val,
+		TestSuiteIDsByName:           tsRes.Val.IDsByName,
+		TestSuiteExperienceIDsByName: tsRes.Val.ExperienceIDsByName,
 	}
 
 	// Update the tags in each experience
@@ -211,10 +215,16 @@ func getCurrentSystemSetsByName(
 	return currentSystemSets, nil
 }
 
-func getCurrentTestSuitesIDsByName(
+type testSuiteSets struct {
+	IDsByName           map[string]TestSuiteID
+	ExperienceIDsByName map[string][]ExperienceID
+}
+
+func getCurrentTestSuitesByName(
 	client api.ClientWithResponsesInterface,
-	projectID uuid.UUID) (map[string]TestSuiteID, error) {
+	projectID uuid.UUID) (testSuiteSets, error) {
 	testSuiteIDsByName := make(map[string]TestSuiteID)
+	experienceIDsByName := make(map[string][]ExperienceID)
 	var pageToken *string = nil
 
 	for {
@@ -224,11 +234,11 @@ func getCurrentTestSuitesIDsByName(
 				PageToken: pageToken,
 			})
 		if err != nil {
-			return nil, fmt.Errorf("failed to list test suites: %s", err)
+			return testSuiteSets{}, fmt.Errorf("failed to list test suites: %s", err)
 		}
 		err = utils.ValidateResponseSafe(http.StatusOK, "failed to list test suites", response.HTTPResponse, response.Body)
 		if err != nil {
-			return nil, err
+			return testSuiteSets{}, err
 		}
 
 		pageToken = &response.JSON200.NextPageToken
@@ -238,13 +248,14 @@ func getCurrentTestSuitesIDsByName(
 
 		for _, testSuite := range response.JSON200.TestSuites {
 			testSuiteIDsByName[testSuite.Name] = testSuite.TestSuiteID
+			experienceIDsByName[testSuite.Name] = testSuite.Experiences
 		}
 
 		if pageToken == nil || *pageToken == "" {
 			break
 		}
 	}
-	return testSuiteIDsByName, nil
+	return testSuiteSets{IDsByName: testSuiteIDsByName, ExperienceIDsByName: experienceIDsByName}, nil
 }
 
 func addApiExperienceToExperienceMap(experience api.Experience,
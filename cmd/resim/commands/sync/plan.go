@@ -0,0 +1,472 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+)
+
+// The version of the plan file format.
+const syncPlanVersion = 1
+
+// A sync computed by `--plan` and saved for `--apply-plan`. Rather than the updates themselves, it
+// records the config and a digest of the database state they were computed from. Since the updates
+// are a pure function of the two, applying the plan recomputes exactly the same updates, provided
+// the digest still matches the current state.
+type SyncPlan struct {
+	Version     int                  `json:"version"`
+	ProjectID   uuid.UUID            `json:"projectID"`
+	Options     SyncOptions          `json:"options"`
+	StateDigest string               `json:"stateDigest"`
+	Config      ExperienceSyncConfig `json:"config"`
+	// The human-readable changes, for reviewers reading the plan file.
+	Changes []planChange `json:"changes"`
+}
+
+// What a change in a plan does, for the plan's summary.
+type planChangeKind string
+
+const (
+	planCreateExperience  planChangeKind = "createExperience"
+	planUpdateExperience  planChangeKind = "updateExperience"
+	planRenameExperience  planChangeKind = "renameExperience"
+	planRestoreExperience planChangeKind = "restoreExperience"
+	planArchiveExperience planChangeKind = "archiveExperience"
+	planCreateTag         planChangeKind = "createTag"
+	planDeleteTag         planChangeKind = "deleteTag"
+	planCreateTestSuite   planChangeKind = "createTestSuite"
+	// Experiences added to or removed from a tag, system or test suite.
+	planMembership planChangeKind = "membership"
+)
+
+// A single change in a plan: its kind and the lines describing it, a header line possibly
+// followed by indented detail lines.
+type planChange struct {
+	Kind  planChangeKind `json:"kind"`
+	Lines []string       `json:"lines"`
+}
+
+func newPlanChange(kind planChangeKind, format string, args ...any) planChange {
+	return planChange{Kind: kind, Lines: []string{fmt.Sprintf(format, args...)}}
+}
+
+// Compute the updates for the given config and print them as a plan, without applying them. If
+// planPath is set, the plan is also saved there for ApplyExperienceSyncPlan.
+func PlanExperienceSync(client api.ClientWithResponsesInterface,
+	projectID uuid.UUID,
+	configPath string,
+	options SyncOptions,
+	planPath string,
+) {
+	plan, err := computeSyncPlan(client, projectID, configPath, options)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	fmt.Print(formatPlanChanges(plan.Changes))
+
+	if planPath != "" {
+		if err := saveSyncPlan(planPath, plan); err != nil {
+			log.Fatalf("%v", err)
+		}
+		fmt.Printf("Plan saved to %s. Apply it with --apply-plan %s\n", planPath, planPath)
+	}
+}
+
+// Check whether the experiences, tags, systems and test suites match the given config, without
+// changing anything. Prints the differences, if any, and returns whether there were none.
+func CheckExperienceSync(client api.ClientWithResponsesInterface,
+	projectID uuid.UUID,
+	configPath string,
+	options SyncOptions,
+) bool {
+	plan, err := computeSyncPlan(client, projectID, configPath, options)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if len(plan.Changes) == 0 {
+		fmt.Printf("The experiences match %s.\n", configPath)
+		return true
+	}
+	fmt.Printf("The experiences have drifted from %s. Syncing would make these changes:\n\n", configPath)
+	fmt.Print(formatPlanChanges(plan.Changes))
+	return false
+}
+
+func computeSyncPlan(client api.ClientWithResponsesInterface,
+	projectID uuid.UUID,
+	configPath string,
+	options SyncOptions,
+) (*SyncPlan, error) {
+	if configPath == "" {
+		return nil, fmt.Errorf("experiences-config not set")
+	}
+	config, _, err := loadExperienceSyncConfig(configPath, false)
+	if err != nil {
+		return nil, err
+	}
+	currentState, err := getCurrentDatabaseState(client, projectID)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := digestDatabaseState(*currentState)
+	if err != nil {
+		return nil, err
+	}
+	// Computing the updates fills in the config's experience IDs, so keep a copy of it as it
+	// was loaded.
+	plan := SyncPlan{
+		Version:     syncPlanVersion,
+		ProjectID:   projectID,
+		Options:     options,
+		StateDigest: digest,
+	}
+	if err := deepCopy(config, &plan.Config); err != nil {
+		return nil, err
+	}
+	experienceUpdates, err := computeExperienceUpdates(config, *currentState, options)
+	if err != nil {
+		return nil, err
+	}
+	plan.Changes = describeExperienceUpdates(*experienceUpdates, *currentState)
+	return &plan, nil
+}
+
+// Apply a plan saved by PlanExperienceSync. Fails without making any changes if the database state
+// has changed since the plan was computed.
+func ApplyExperienceSyncPlan(client api.ClientWithResponsesInterface,
+	projectID uuid.UUID,
+	planPath string,
+) {
+	plan, err := loadSyncPlan(planPath)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if plan.ProjectID != projectID {
+		log.Fatalf("The plan was made for project %s, not %s", plan.ProjectID, projectID)
+	}
+	currentState, err := getCurrentDatabaseState(client, projectID)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	digest, err := digestDatabaseState(*currentState)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if digest != plan.StateDigest {
+		log.Fatalf("The experiences, tags, systems or test suites have changed since the plan was made. Run sync with --plan again to make a new plan.")
+	}
+	experienceUpdates, err := computeExperienceUpdates(&plan.Config, *currentState, plan.Options)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	fmt.Print(formatPlanChanges(describeExperienceUpdates(*experienceUpdates, *currentState)))
+	err = applyUpdates(client, projectID, *experienceUpdates)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+func saveSyncPlan(path string, plan *SyncPlan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+func loadSyncPlan(path string) (*SyncPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	var plan SyncPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	if plan.Version != syncPlanVersion {
+		return nil, fmt.Errorf("unsupported plan version %d in %s", plan.Version, path)
+	}
+	if err := NormalizeExperiences(plan.Config.Experiences); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// Compute a digest of everything in the database state that the updates depend on. Tags, systems
+// and experience sets are sorted so that the digest doesn't depend on the order the API listed them
+// in.
+func digestDatabaseState(state DatabaseState) (string, error) {
+	type sortedSet struct {
+		ID            uuid.UUID `json:"id"`
+		ExperienceIDs []string  `json:"experienceIDs"`
+	}
+	sortedIDs := func(ids []ExperienceID) []string {
+		out := make([]string, 0, len(ids))
+		for _, id := range ids {
+			out = append(out, id.String())
+		}
+		sort.Strings(out)
+		return out
+	}
+	setIDs := func(set map[ExperienceID]struct{}) []string {
+		ids := make([]ExperienceID, 0, len(set))
+		for id := range set {
+			ids = append(ids, id)
+		}
+		return sortedIDs(ids)
+	}
+
+	canonical := struct {
+		Experiences map[string]Experience `json:"experiences"`
+		Tags        map[string]sortedSet  `json:"tags"`
+		Systems     map[string]sortedSet  `json:"systems"`
+		TestSuites  map[string]sortedSet  `json:"testSuites"`
+	}{
+		Experiences: map[string]Experience{},
+		Tags:        map[string]sortedSet{},
+		Systems:     map[string]sortedSet{},
+		TestSuites:  map[string]sortedSet{},
+	}
+	for name, experience := range state.ExperiencesByName {
+		e := *experience
+		e.Tags = slices.Sorted(slices.Values(e.Tags))
+		e.Systems = slices.Sorted(slices.Values(e.Systems))
+		canonical.Experiences[name] = e
+	}
+	for name, set := range state.TagSetsByName {
+		canonical.Tags[name] = sortedSet{ID: set.TagID, ExperienceIDs: setIDs(set.ExperienceIDs)}
+	}
+	for name, set := range state.SystemSetsByName {
+		canonical.Systems[name] = sortedSet{ID: set.SystemID, ExperienceIDs: setIDs(set.ExperienceIDs)}
+	}
+	for name, id := range state.TestSuiteIDsByName {
+		canonical.TestSuites[name] = sortedSet{ID: id, ExperienceIDs: sortedIDs(state.TestSuiteExperienceIDsByName[name])}
+	}
+
+	data, err := json.Marshal(canonical)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode database state: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Describe the updates as lines of a plan, in a stable order: experiences, then tags, systems and
+// test suites, each sorted by name.
+func describeExperienceUpdates(updates ExperienceUpdates, currentState DatabaseState) []planChange {
+	changes := []planChange{}
+
+	for _, name := range slices.Sorted(maps.Keys(updates.MatchedExperiencesByNewName)) {
+		if change := describeExperienceMatch(updates.MatchedExperiencesByNewName[name]); change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(updates.TagUpdatesByName)) {
+		update := updates.TagUpdatesByName[name]
+		if update.Create {
+			changes = append(changes, newPlanChange(planCreateTag, "+ create tag %s", name))
+		}
+		if members := describeMembership(update.Additions, experienceNames(update.Removals)); members != "" {
+			changes = append(changes, newPlanChange(planMembership, "~ tag %s: %s", name, members))
+		}
+		if update.Delete {
+			changes = append(changes, newPlanChange(planDeleteTag, "- delete tag %s", name))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(updates.SystemUpdatesByName)) {
+		update := updates.SystemUpdatesByName[name]
+		if members := describeMembership(update.Additions, nil); members != "" {
+			changes = append(changes, newPlanChange(planMembership, "~ system %s: %s", name, members))
+		}
+	}
+
+	currentByID := byNameToByID(currentState.ExperiencesByName)
+	testSuiteUpdates := slices.Clone(updates.TestSuiteUpdates)
+	sort.Slice(testSuiteUpdates, func(i, j int) bool { return testSuiteUpdates[i].Name < testSuiteUpdates[j].Name })
+	for _, update := range testSuiteUpdates {
+		if update.Create {
+			changes = append(changes, newPlanChange(planCreateTestSuite, "+ create test suite %s", update.Name))
+		}
+		current := map[ExperienceID]bool{}
+		for _, id := range currentState.TestSuiteExperienceIDsByName[update.Name] {
+			current[id] = true
+		}
+		var additions []*Experience
+		for _, experience := range update.Experiences {
+			if experience.ExperienceID == nil || !current[*experience.ExperienceID] {
+				additions = append(additions, experience)
+			} else {
+				delete(current, *experience.ExperienceID)
+			}
+		}
+		var removals []string
+		for id := range current {
+			if experience, ok := currentByID[id]; ok {
+				removals = append(removals, experience.Name)
+			} else {
+				removals = append(removals, id.String())
+			}
+		}
+		if members := describeMembership(additions, removals); members != "" {
+			changes = append(changes, newPlanChange(planMembership, "~ test suite %s: %s", update.Name, members))
+		}
+	}
+	return changes
+}
+
+// Describe the change to a single experience, if any: a header line followed by one indented line
+// per changed field.
+func describeExperienceMatch(match ExperienceMatch) *planChange {
+	original, updated := match.Original, match.New
+	if original == nil {
+		change := newPlanChange(planCreateExperience, "+ create experience %s", updated.Name)
+		return &change
+	}
+	if updated.Archived {
+		if original.Archived {
+			return nil
+		}
+		change := newPlanChange(planArchiveExperience, "- archive experience %s", original.Name)
+		return &change
+	}
+
+	var fields []string
+	field := func(name string, before any, after any) {
+		if !reflect.DeepEqual(before, after) {
+			fields = append(fields, fmt.Sprintf("    %s: %s -> %s", name, formatPlanValue(before), formatPlanValue(after)))
+		}
+	}
+	field("description", original.Description, updated.Description)
+	field("locations", original.Locations, updated.Locations)
+	field("customFields", customFieldsOrEmpty(original.CustomFields), customFieldsOrEmpty(updated.CustomFields))
+	// These are left as they are when unset in the config. See updateSingleExperience.
+	if updated.ContainerTimeoutSeconds != nil {
+		field("containerTimeoutSeconds", original.ContainerTimeoutSeconds, updated.ContainerTimeoutSeconds)
+	}
+	if updated.Profile != nil {
+		field("profile", original.Profile, updated.Profile)
+	}
+	if updated.EnvironmentVariables != nil {
+		field("environmentVariables", original.EnvironmentVariables, updated.EnvironmentVariables)
+	}
+	if updated.CacheExempt != nil {
+		field("cacheExempt", original.CacheExempt, updated.CacheExempt)
+	}
+
+	var change planChange
+	switch {
+	case original.Archived && original.Name != updated.Name:
+		change = newPlanChange(planRestoreExperience, "+ restore experience %s as %s", original.Name, updated.Name)
+	case original.Archived:
+		change = newPlanChange(planRestoreExperience, "+ restore experience %s", updated.Name)
+	case original.Name != updated.Name:
+		change = newPlanChange(planRenameExperience, "~ rename experience %s -> %s", original.Name, updated.Name)
+	case len(fields) > 0:
+		change = newPlanChange(planUpdateExperience, "~ update experience %s", updated.Name)
+	default:
+		return nil
+	}
+	change.Lines = append(change.Lines, fields...)
+	return &change
+}
+
+// The lines of the changes of a plan, in order.
+func planChangeLines(changes []planChange) []string {
+	lines := []string{}
+	for _, change := range changes {
+		lines = append(lines, change.Lines...)
+	}
+	return lines
+}
+
+// Format the lines of a plan with a summary.
+func formatPlanChanges(changes []planChange) string {
+	if len(changes) == 0 {
+		return "No changes. The experiences match the config.\n"
+	}
+	counts := map[planChangeKind]int{}
+	for _, change := range changes {
+		counts[change.Kind]++
+	}
+
+	var b strings.Builder
+	for _, line := range planChangeLines(changes) {
+		fmt.Fprintln(&b, line)
+	}
+	fmt.Fprintf(&b, "\nPlan: %d to create, %d to update, %d to rename, %d to restore, %d to archive, %d tag, system and test suite membership changes.\n",
+		counts[planCreateExperience], counts[planUpdateExperience], counts[planRenameExperience], counts[planRestoreExperience], counts[planArchiveExperience], counts[planMembership])
+	if created, deleted := counts[planCreateTag]+counts[planCreateTestSuite], counts[planDeleteTag]; created > 0 || deleted > 0 {
+		fmt.Fprintf(&b, "Tags and test suites: %d to create, %d to delete.\n", created, deleted)
+	}
+	return b.String()
+}
+
+func describeMembership(additions []*Experience, removals []string) string {
+	var parts []string
+	for _, name := range experienceNames(additions) {
+		parts = append(parts, "+"+name)
+	}
+	sort.Strings(removals)
+	for _, name := range removals {
+		parts = append(parts, "-"+name)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func experienceNames(experiences []*Experience) []string {
+	names := make([]string, 0, len(experiences))
+	for _, experience := range experiences {
+		names = append(names, experience.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Unset and empty custom fields are the same to the API.
+func customFieldsOrEmpty(fields *[]api.CustomFieldDefinition) []api.CustomFieldDefinition {
+	if fields == nil || len(*fields) == 0 {
+		return []api.CustomFieldDefinition{}
+	}
+	return *fields
+}
+
+func formatPlanValue(value any) string {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "(unset)"
+		}
+		value = v.Elem().Interface()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func deepCopy(from any, to any) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return fmt.Errorf("failed to copy config: %w", err)
+	}
+	if err := json.Unmarshal(data, to); err != nil {
+		return fmt.Errorf("failed to copy config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	mockapiclient "github.com/resim-ai/api-client/api/mocks"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/yaml.v3"
+)
+
+var planCurrentStateData = `
+  - name: unchanged
+    experienceID: "1b0c8e84-4b0c-4f7e-9c7e-0f7e6d1c2a01"
+    description: Stays the same
+    locations: ["s3://bucket/unchanged"]
+    tags: [regression]
+  - name: to-update
+    experienceID: "1b0c8e84-4b0c-4f7e-9c7e-0f7e6d1c2a02"
+    description: Old description
+    locations: ["s3://bucket/to-update"]
+    profile: legs
+  - name: old-name
+    experienceID: "1b0c8e84-4b0c-4f7e-9c7e-0f7e6d1c2a03"
+    description: Renamed
+    locations: ["s3://bucket/renamed"]
+    tags: [regression]
+  - name: to-archive
+    experienceID: "1b0c8e84-4b0c-4f7e-9c7e-0f7e6d1c2a04"
+    description: Not in the config
+    locations: ["s3://bucket/to-archive"]
+  - name: to-restore
+    experienceID: "1b0c8e84-4b0c-4f7e-9c7e-0f7e6d1c2a05"
+    description: Archived
+    locations: ["s3://bucket/to-restore"]
+    archived: true
+`
+
+var planConfigData = `
+managedExperienceTags: [regression]
+managedTestSuites:
+  - name: Nightly
+    experiences: [unchanged, new-name, brand-new]
+experiences:
+  - name: unchanged
+    description: Stays the same
+    locations: ["s3://bucket/unchanged"]
+    tags: [regression]
+  - name: to-update
+    description: New description
+    locations: ["s3://bucket/to-update"]
+  - name: new-name
+    experienceID: "1b0c8e84-4b0c-4f7e-9c7e-0f7e6d1c2a03"
+    description: Renamed
+    locations: ["s3://bucket/renamed"]
+  - name: to-restore
+    description: Archived
+    locations: ["s3://bucket/to-restore"]
+  - name: brand-new
+    description: Created
+    locations: ["s3://bucket/brand-new"]
+    tags: [regression]
+    systems: [planner]
+`
+
+func planStateAndConfig(t *testing.T) (DatabaseState, ExperienceSyncConfig) {
+	currentState, config := loaderHelper(t, planCurrentStateData, planConfigData, []string{"regression"}, []string{"planner"})
+	currentState.TestSuiteIDsByName = map[string]TestSuiteID{"Nightly": uuid.New()}
+	currentState.TestSuiteExperienceIDsByName = map[string][]ExperienceID{
+		"Nightly": {
+			*currentState.ExperiencesByName["unchanged"].ExperienceID,
+			*currentState.ExperiencesByName["to-archive"].ExperienceID,
+		},
+	}
+	return currentState, config
+}
+
+func TestDescribeExperienceUpdates(t *testing.T) {
+	// SETUP
+	currentState, config := planStateAndConfig(t)
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
+	assert.NoError(t, err)
+
+	// ACTION
+	changes := describeExperienceUpdates(*experienceUpdates, currentState)
+
+	// VERIFICATION
+	assert.Equal(t, []string{
+		"+ create experience brand-new",
+		"~ rename experience old-name -> new-name",
+		"- archive experience to-archive",
+		"+ restore experience to-restore",
+		"~ update experience to-update",
+		`    description: "Old description" -> "New description"`,
+		"~ tag regression: +brand-new, -new-name",
+		"~ system planner: +brand-new",
+		"~ test suite Nightly: +brand-new, +new-name, -to-archive",
+	}, planChangeLines(changes))
+
+	summary := formatPlanChanges(changes)
+	assert.Contains(t, summary, "Plan: 1 to create, 1 to update, 1 to rename, 1 to restore, 1 to archive, 3 tag, system and test suite membership changes.")
+}
+
+func TestDescribeExperienceUpdatesNoArchive(t *testing.T) {
+	// SETUP
+	currentState, config := planStateAndConfig(t)
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{})
+	assert.NoError(t, err)
+
+	// ACTION
+	changes := describeExperienceUpdates(*experienceUpdates, currentState)
+
+	// VERIFICATION
+	for _, change := range changes {
+		assert.NotEqual(t, planArchiveExperience, change.Kind)
+	}
+}
+
+func TestDescribeNoChanges(t *testing.T) {
+	// SETUP
+	currentStateData := `
+  - name: unchanged
+    experienceID: "1b0c8e84-4b0c-4f7e-9c7e-0f7e6d1c2a01"
+    description: Stays the same
+    locations: ["s3://bucket/unchanged"]
+`
+	configData := `
+experiences:
+  - name: unchanged
+    description: Stays the same
+    locations: ["s3://bucket/unchanged"]
+`
+	currentState, config := loaderHelper(t, currentStateData, configData, nil, nil)
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
+	assert.NoError(t, err)
+
+	// ACTION
+	changes := describeExperienceUpdates(*experienceUpdates, currentState)
+
+	// VERIFICATION
+	assert.Empty(t, changes)
+	assert.Equal(t, "No changes. The experiences match the config.\n", formatPlanChanges(changes))
+}
+
+func TestDigestDatabaseState(t *testing.T) {
+	// SETUP
+	currentState, _ := planStateAndConfig(t)
+	digest, err := digestDatabaseState(currentState)
+	assert.NoError(t, err)
+
+	// ACTION
+	// The order experiences are listed in doesn't matter.
+	reordered, _ := planStateAndConfig(t)
+	reordered.TagSetsByName = currentState.TagSetsByName
+	reordered.SystemSetsByName = currentState.SystemSetsByName
+	reordered.TestSuiteIDsByName = currentState.TestSuiteIDsByName
+	reordered.TestSuiteExperienceIDsByName = map[string][]ExperienceID{
+		"Nightly": {
+			currentState.TestSuiteExperienceIDsByName["Nightly"][1],
+			currentState.TestSuiteExperienceIDsByName["Nightly"][0],
+		},
+	}
+	reorderedDigest, err := digestDatabaseState(reordered)
+	assert.NoError(t, err)
+
+	// Any change to an experience does.
+	currentState.ExperiencesByName["unchanged"].Description = "Changed since the plan"
+	changedDigest, err := digestDatabaseState(currentState)
+	assert.NoError(t, err)
+
+	// VERIFICATION
+	assert.Equal(t, digest, reorderedDigest)
+	assert.NotEqual(t, digest, changedDigest)
+}
+
+func TestSaveAndLoadSyncPlan(t *testing.T) {
+	// SETUP
+	currentState, config := planStateAndConfig(t)
+	digest, err := digestDatabaseState(currentState)
+	assert.NoError(t, err)
+	plan := SyncPlan{
+		Version:     syncPlanVersion,
+		ProjectID:   uuid.New(),
+		Options:     SyncOptions{ShouldArchive: true},
+		StateDigest: digest,
+	}
+	assert.NoError(t, deepCopy(&config, &plan.Config))
+	experienceUpdates, err := computeExperienceUpdates(&config, currentState, SyncOptions{ShouldArchive: true})
+	assert.NoError(t, err)
+	plan.Changes = describeExperienceUpdates(*experienceUpdates, currentState)
+	path := filepath.Join(t.TempDir(), "plan.json")
+
+	// ACTION
+	assert.NoError(t, saveSyncPlan(path, &plan))
+	loaded, err := loadSyncPlan(path)
+
+	// VERIFICATION
+	assert.NoError(t, err)
+	assert.Equal(t, plan.ProjectID, loaded.ProjectID)
+	assert.Equal(t, plan.StateDigest, loaded.StateDigest)
+	// The saved config is the one that was loaded, before computing the updates filled in the IDs
+	// of experiences matched by name.
+	assert.Nil(t, loaded.Config.Experiences[0].ExperienceID)
+
+	// Recomputing the updates from the saved config gives the same plan.
+	replanned, err := computeExperienceUpdates(&loaded.Config, currentState, loaded.Options)
+	assert.NoError(t, err)
+	assert.Equal(t, plan.Changes, describeExperienceUpdates(*replanned, currentState))
+}
+
+func TestLoadSyncPlanRejectsUnknownVersion(t *testing.T) {
+	// SETUP
+	path := filepath.Join(t.TempDir(), "plan.json")
+	assert.NoError(t, saveSyncPlan(path, &SyncPlan{Version: syncPlanVersion + 1}))
+
+	// ACTION
+	_, err := loadSyncPlan(path)
+
+	// VERIFICATION
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "unsupported plan version"))
+}
+
+func mockStateClient() *mockapiclient.ClientWithResponsesInterface {
+	var client mockapiclient.ClientWithResponsesInterface
+	client.On("ListExperienceTagsWithResponse", context.Background(), mockProjectID, mock.Anything).
+		Return(ListExperienceTagsWithResponseMock)
+	client.On("ListSystemsWithResponse", context.Background(), mockProjectID, mock.Anything).
+		Return(ListSystemsWithResponseMock)
+	client.On("ListTestSuitesWithResponse", context.Background(), mockProjectID, mock.Anything).
+		Return(ListTestSuitesWithResponseMock)
+	client.On("ListExperiencesWithResponse", context.Background(), mockProjectID, mock.Anything).
+		Return(ListExperiencesWithResponseMock)
+	client.On("ListExperiencesWithExperienceTagWithResponse", context.Background(), mockProjectID, mock.Anything, mock.Anything).
+		Return(ListExperiencesWithExperienceTagWithResponseMock)
+	client.On("ListExperiencesForSystemWithResponse", context.Background(), mockProjectID, mock.Anything, mock.Anything).
+		Return(ListExperiencesForSystemWithResponseMock)
+	return &client
+}
+
+// Write a config listing the unarchived experiences of the mock state, the way --clone would. The
+// config can't list those without locations, so the check must not archive them.
+func writeMockStateConfig(t *testing.T, edit func(config *ExperienceSyncConfig)) string {
+	config := ExperienceSyncConfig{}
+	for _, experience := range mockState.ExperiencesByName {
+		if !experience.Archived && len(experience.Locations) > 0 {
+			config.Experiences = append(config.Experiences, *experience)
+		}
+	}
+	edit(&config)
+	data, err := yaml.Marshal(config)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "experiences.yaml")
+	assert.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestCheckExperienceSyncInSync(t *testing.T) {
+	// SETUP
+	client := mockStateClient()
+	configPath := writeMockStateConfig(t, func(config *ExperienceSyncConfig) {})
+
+	// ACTION
+	inSync := CheckExperienceSync(client, mockProjectID, configPath, SyncOptions{})
+
+	// VERIFICATION
+	assert.True(t, inSync)
+}
+
+func TestCheckExperienceSyncDrifted(t *testing.T) {
+	// SETUP
+	client := mockStateClient()
+	configPath := writeMockStateConfig(t, func(config *ExperienceSyncConfig) {
+		config.Experiences[0].Description = "Edited in the config but not in the app"
+	})
+
+	// ACTION
+	inSync := CheckExperienceSync(client, mockProjectID, configPath, SyncOptions{})
+
+	// VERIFICATION
+	assert.False(t, inSync)
+	// Nothing is changed.
+	client.AssertNotCalled(t, "UpdateExperienceWithResponse", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	client.AssertNotCalled(t, "CreateExperienceWithResponse", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDescribeTagAndTestSuiteCreation(t *testing.T) {
+	// SETUP
+	experience := &Experience{Name: "tagged", ExperienceID: Ptr(uuid.New())}
+	updates := ExperienceUpdates{
+		MatchedExperiencesByNewName: map[string]ExperienceMatch{},
+		TagUpdatesByName: map[string]*TagUpdates{
+			"new-tag":    {Name: "new-tag", Additions: []*Experience{experience}, Create: true},
+			"unused-tag": {Name: "unused-tag", TagID: uuid.New(), Delete: true},
+		},
+		SystemUpdatesByName: map[string]*SystemUpdates{},
+		TestSuiteUpdates: []TestSuiteUpdate{{
+			Name:        "Nightly",
+			Experiences: []*Experience{experience},
+			Create:      true,
+		}},
+	}
+
+	// ACTION
+	changes := describeExperienceUpdates(updates, DatabaseState{})
+
+	// VERIFICATION
+	assert.Equal(t, []string{
+		"+ create tag new-tag",
+		"~ tag new-tag: +tagged",
+		"- delete tag unused-tag",
+		"+ create test suite Nightly",
+		"~ test suite Nightly: +tagged",
+	}, planChangeLines(changes))
+	summary := formatPlanChanges(changes)
+	assert.Contains(t, summary, "Plan: 0 to create, 0 to update, 0 to rename, 0 to restore, 0 to archive, 2 tag, system and test suite membership changes.")
+	assert.Contains(t, summary, "Tags and test suites: 2 to create, 1 to delete.")
+}
+
+func TestFormatPlanChangesCountsKinds(t *testing.T) {
+	// ACTION
+	// The summary counts the kinds of the changes, whatever their wording.
+	summary := formatPlanChanges([]planChange{
+		{Kind: planRenameExperience, Lines: []string{"renamed", "    with details"}},
+		{Kind: planMembership, Lines: []string{"membership"}},
+		{Kind: planCreateTestSuite, Lines: []string{"suite"}},
+	})
+
+	// VERIFICATION
+	assert.Equal(t, "renamed\n    with details\nmembership\nsuite\n\n"+
+		"Plan: 0 to create, 0 to update, 1 to rename, 0 to restore, 0 to archive, 1 tag, system and test suite membership changes.\n"+
+		"Tags and test suites: 1 to create, 0 to delete.\n", summary)
+}
@@ -11,11 +11,17 @@ type TagUpdates struct {
 	TagID     TagID
 	Additions []*Experience
 	Removals  []*Experience
+	// The tag doesn't exist and must be created before its additions. TagID is set once it is.
+	Create bool
+	// The tag is managed and no unarchived experience has it after the updates, so it should be
+	// deleted once they are made.
+	Delete bool
 }
 
 func getTagUpdates(matchedExperiencesByNewName map[string]ExperienceMatch,
 	currentTagSetsByName map[string]TagSet,
-	managedTags []string) (map[string]*TagUpdates, error) {
+	managedTags []string,
+	options SyncOptions) (map[string]*TagUpdates, error) {
 	updates := make(map[string]*TagUpdates)
 
 	for tag, set := range currentTagSetsByName {
@@ -26,24 +32,42 @@ func getTagUpdates(matchedExperiencesByNewName map[string]ExperienceMatch,
 			Removals:  []*Experience{},
 		}
 	}
-	for _, tag := range managedTags {
-		if _, exists := currentTagSetsByName[tag]; !exists {
-			return nil, fmt.Errorf("Managed tag doesn't exist: %s", tag)
+	// Plan to create the given tag if it doesn't exist and we're allowed to.
+	ensureExists := func(tag string) bool {
+		if _, exists := updates[tag]; exists {
+			return true
+		}
+		if !options.CreateMissing {
+			return false
 		}
+		updates[tag] = &TagUpdates{
+			Name:      tag,
+			Additions: []*Experience{},
+			Removals:  []*Experience{},
+			Create:    true,
+		}
+		return true
+	}
+	// A managed tag that doesn't exist is left alone unless an experience has it, which is an
+	// error below. This is what a sync with DeleteUnusedTags leaves behind.
+	for _, tag := range managedTags {
+		ensureExists(tag)
 	}
 
+	usedTags := make(map[string]struct{})
 	for _, match := range matchedExperiencesByNewName {
 		if match.New.Archived {
 			// Archived so we don't care
 			continue
 		}
 		for _, tag := range match.New.Tags {
-			tag_set, exists := currentTagSetsByName[tag]
-			if !exists {
+			usedTags[tag] = struct{}{}
+			if !ensureExists(tag) {
 				return nil, fmt.Errorf("Non-existent tag: %s", tag)
 			}
+			tag_set := currentTagSetsByName[tag]
 
-			if match.Original == nil {
+			if match.Original == nil || updates[tag].Create {
 				// Brand new. Always add!
 				updates[tag].Additions = append(updates[tag].Additions, match.New)
 				continue
@@ -65,5 +89,23 @@ func getTagUpdates(matchedExperiencesByNewName map[string]ExperienceMatch,
 			}
 		}
 	}
+
+	if options.DeleteUnusedTags {
+		// Every unarchived experience is matched, and lists all of its managed tags, so a
+		// managed tag none of them lists is unused.
+		for _, tag := range managedTags {
+			if _, used := usedTags[tag]; used {
+				continue
+			}
+			if _, exists := updates[tag]; !exists {
+				continue
+			}
+			if updates[tag].Create {
+				delete(updates, tag)
+			} else {
+				updates[tag].Delete = true
+			}
+		}
+	}
 	return updates, nil
 }
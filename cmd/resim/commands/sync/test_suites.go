@@ -2,6 +2,8 @@ package sync
 
 import (
 	"fmt"
+
+	"github.com/google/uuid"
 )
 
 // Struct encoding all the updates that need to be made for a single test suite.
@@ -9,25 +11,48 @@ type TestSuiteUpdate struct {
 	Name        string
 	TestSuiteID TestSuiteID
 	Experiences []*Experience
+	// The test suite doesn't exist and must be created with the following, rather than revised.
+	Create         bool
+	Description    string
+	SystemID       SystemID
+	MetricsBuildID *uuid.UUID
 }
 
 func getTestSuiteUpdates(matchedExperiencesByNewName map[string]ExperienceMatch,
 	testSuites []TestSuite,
 	testSuiteIDsByName map[string]TestSuiteID,
+	currentSystemSetsByName map[string]SystemSet,
+	createMissing bool,
 ) ([]TestSuiteUpdate, error) {
 	updates := []TestSuiteUpdate{}
 
 	for _, testSuite := range testSuites {
-		testSuiteID, exists := testSuiteIDsByName[testSuite.Name]
-		if !exists {
-			return nil, fmt.Errorf("Test suite not found: %s", testSuite.Name)
-		}
-
 		update := TestSuiteUpdate{
 			Name:        testSuite.Name,
-			TestSuiteID: testSuiteID,
 			Experiences: []*Experience{},
 		}
+		testSuiteID, exists := testSuiteIDsByName[testSuite.Name]
+		switch {
+		case exists:
+			update.TestSuiteID = testSuiteID
+		case !createMissing:
+			return nil, fmt.Errorf("Test suite not found: %s", testSuite.Name)
+		default:
+			if testSuite.System == "" {
+				return nil, fmt.Errorf("Test suite %s doesn't exist and has no system to create it with", testSuite.Name)
+			}
+			systemSet, exists := currentSystemSetsByName[testSuite.System]
+			if !exists {
+				return nil, fmt.Errorf("Non-existent system for test suite %s: %s", testSuite.Name, testSuite.System)
+			}
+			update.Create = true
+			update.Description = testSuite.Description
+			if update.Description == "" {
+				update.Description = testSuite.Name
+			}
+			update.SystemID = systemSet.SystemID
+			update.MetricsBuildID = testSuite.MetricsBuildID
+		}
 
 		for _, exp := range testSuite.Experiences {
 			match, exists := matchedExperiencesByNewName[exp]
@@ -0,0 +1,573 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	. "github.com/resim-ai/api-client/cmd/resim/commands/utils"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	testsCmd = &cobra.Command{
+		Use:     "tests",
+		Short:   "tests contains commands for inspecting the individual tests of a batch.",
+		Long:    ``,
+		Aliases: []string{"test"},
+	}
+
+	eventsTestsCmd = &cobra.Command{
+		Use:     "events",
+		Short:   "events contains commands for listing, showing and exporting the events of a test.",
+		Long:    ``,
+		Aliases: []string{"event"},
+	}
+
+	listEventsCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list - Lists the events of a test, or of every test in a batch",
+		Long: `list - Lists the events recorded by a test, or by every test in a batch if no test is given.
+
+Events can be filtered by tag, status and time window. --after and --before take either an
+RFC 3339 timestamp, which applies to events with ABSOLUTE timestamps, or a duration since the
+start of the test (e.g. 90s), which applies to events with RELATIVE timestamps. Events whose
+timestamps are of the other kind are excluded by that bound.`,
+		Run: listEvents,
+	}
+
+	getEventCmd = &cobra.Command{
+		Use:   "get",
+		Short: "get - Shows one event of a test together with its linked metrics",
+		Long:  ``,
+		Run:   getEvent,
+	}
+
+	exportEventsCmd = &cobra.Command{
+		Use:   "export",
+		Short: "export - Writes the events of a test, or of every test in a batch, as JSON Lines",
+		Long: `export - Writes one JSON object per event to stdout, each including the batch and test it belongs to.
+
+Accepts the same filters as "list", and is intended for piping into grep or jq.`,
+		Run: exportEvents,
+	}
+
+	tagsEventsCmd = &cobra.Command{
+		Use:   "tags",
+		Short: "tags - Lists the event tags used by a test",
+		Long:  ``,
+		Run:   listEventTags,
+	}
+)
+
+const (
+	eventProjectKey   = "project"
+	eventBatchIDKey   = "batch-id"
+	eventBatchNameKey = "batch-name"
+	eventTestIDKey    = "test-id" // User-facing is test ID, internal is job id
+	eventIDKey        = "event-id"
+	eventTagKey       = "tag"
+	eventStatusKey    = "status"
+	eventAfterKey     = "after"
+	eventBeforeKey    = "before"
+	eventOutputKey    = "output"
+)
+
+// eventNameWidth caps event and metric names in tables.
+const eventNameWidth = 60
+
+func addEventBatchFlags(cmd *cobra.Command) {
+	cmd.Flags().String(eventProjectKey, "", "The name or ID of the project the batch is associated with")
+	cmd.MarkFlagRequired(eventProjectKey)
+	cmd.Flags().String(eventBatchIDKey, "", "The ID of the batch the test belongs to.")
+	cmd.Flags().String(eventBatchNameKey, "", "The name of the batch the test belongs to (e.g. rejoicing-aquamarine-starfish). If the name is not unique, the most recent batch with that name is used.")
+	cmd.MarkFlagsMutuallyExclusive(eventBatchIDKey, eventBatchNameKey)
+	cmd.MarkFlagsOneRequired(eventBatchIDKey, eventBatchNameKey)
+	cmd.Flags().SetNormalizeFunc(aliasProjectNameFunc)
+}
+
+func addEventFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().String(eventTestIDKey, "", "(Optional) The ID of the test to list events for. If not provided, every test in the batch is included.")
+	cmd.Flags().StringSlice(eventTagKey, []string{}, "(Optional) Only include events with this tag. May be repeated or comma separated; events must carry every given tag.")
+	cmd.Flags().String(eventStatusKey, "", "(Optional) Comma-separated event statuses to include: PASSED, FAIL_BLOCK, FAIL_WARN, NO_STATUS_REPORTED, NOT_APPLICABLE or RAW")
+	cmd.Flags().String(eventAfterKey, "", "(Optional) Only include events at or after this time: an RFC 3339 timestamp, or a duration since the start of the test for RELATIVE events")
+	cmd.Flags().String(eventBeforeKey, "", "(Optional) Only include events before this time: an RFC 3339 timestamp, or a duration since the start of the test for RELATIVE events")
+}
+
+func init() {
+	addEventBatchFlags(listEventsCmd)
+	addEventFilterFlags(listEventsCmd)
+	listEventsCmd.Flags().String(eventOutputKey, batchOutputTable, "Output format: table or json")
+	eventsTestsCmd.AddCommand(listEventsCmd)
+
+	addEventBatchFlags(getEventCmd)
+	getEventCmd.Flags().String(eventTestIDKey, "", "The ID of the test the event belongs to")
+	getEventCmd.MarkFlagRequired(eventTestIDKey)
+	getEventCmd.Flags().String(eventIDKey, "", "The ID of the event to show")
+	getEventCmd.MarkFlagRequired(eventIDKey)
+	getEventCmd.Flags().String(eventOutputKey, batchOutputTable, "Output format: table or json")
+	eventsTestsCmd.AddCommand(getEventCmd)
+
+	addEventBatchFlags(exportEventsCmd)
+	addEventFilterFlags(exportEventsCmd)
+	eventsTestsCmd.AddCommand(exportEventsCmd)
+
+	addEventBatchFlags(tagsEventsCmd)
+	tagsEventsCmd.Flags().String(eventTestIDKey, "", "The ID of the test to list event tags for")
+	tagsEventsCmd.MarkFlagRequired(eventTestIDKey)
+	eventsTestsCmd.AddCommand(tagsEventsCmd)
+
+	testsCmd.AddCommand(eventsTestsCmd)
+	rootCmd.AddCommand(testsCmd)
+}
+
+// relativeEventEpoch is the zero point of RELATIVE event timestamps, which hold the time
+// since the start of the test.
+var relativeEventEpoch = time.Unix(0, 0).UTC()
+
+// eventTimeBound is one end of a time window: an absolute time for ABSOLUTE events, or
+// an offset since the start of the test for RELATIVE ones.
+type eventTimeBound struct {
+	at       time.Time
+	offset   time.Duration
+	relative bool
+}
+
+func parseEventTimeBound(raw string) (*eventTimeBound, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &eventTimeBound{at: t}, nil
+	}
+	if d, err := time.ParseDuration(raw); err == nil {
+		return &eventTimeBound{offset: d, relative: true}, nil
+	}
+	return nil, fmt.Errorf("invalid time %q: expected an RFC 3339 timestamp or a duration since the start of the test (e.g. 90s)", raw)
+}
+
+// compare returns -1, 0 or 1 as the event is before, at or after the bound. ok is false
+// if the event's timestamp is not of the bound's kind.
+func (b *eventTimeBound) compare(event api.Event) (int, bool) {
+	if b.relative != (event.TimestampType == api.RELATIVE) {
+		return 0, false
+	}
+	if b.relative {
+		offset := event.Timestamp.Sub(relativeEventEpoch)
+		switch {
+		case offset < b.offset:
+			return -1, true
+		case offset > b.offset:
+			return 1, true
+		}
+		return 0, true
+	}
+	return event.Timestamp.Compare(b.at), true
+}
+
+func parseEventStatuses(raw string) ([]api.MetricStatus, error) {
+	valid := []api.MetricStatus{
+		api.PASSED,
+		api.FAILBLOCK,
+		api.FAILWARN,
+		api.NOSTATUSREPORTED,
+		api.NOTAPPLICABLE,
+		api.RAW,
+	}
+	var statuses []api.MetricStatus
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.ToUpper(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		status := api.MetricStatus(part)
+		if !slices.Contains(valid, status) {
+			return nil, fmt.Errorf("invalid event status: %s", part)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// testEventFilter selects events by tag, status and time window. Tags are also sent to
+// the server to narrow the listing.
+type testEventFilter struct {
+	Tags     []string
+	Statuses []api.MetricStatus
+	After    *eventTimeBound
+	Before   *eventTimeBound
+}
+
+func (f testEventFilter) matches(event api.Event) bool {
+	for _, tag := range f.Tags {
+		if !slices.Contains(event.Tags, tag) {
+			return false
+		}
+	}
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, event.Status) {
+		return false
+	}
+	if f.After != nil {
+		if cmp, ok := f.After.compare(event); !ok || cmp < 0 {
+			return false
+		}
+	}
+	if f.Before != nil {
+		if cmp, ok := f.Before.compare(event); !ok || cmp >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func getEventFilterFlags() testEventFilter {
+	statuses, err := parseEventStatuses(viper.GetString(eventStatusKey))
+	if err != nil {
+		log.Fatal(err)
+	}
+	after, err := parseEventTimeBound(viper.GetString(eventAfterKey))
+	if err != nil {
+		log.Fatal(err)
+	}
+	before, err := parseEventTimeBound(viper.GetString(eventBeforeKey))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return testEventFilter{
+		Tags:     viper.GetStringSlice(eventTagKey),
+		Statuses: statuses,
+		After:    after,
+		Before:   before,
+	}
+}
+
+// testEventRecord is an event together with the test it was recorded by.
+type testEventRecord struct {
+	BatchID        uuid.UUID `json:"batchID"`
+	TestID         uuid.UUID `json:"testID"`
+	ExperienceName string    `json:"experienceName,omitempty"`
+	api.Event
+}
+
+func listEventsForTest(projectID uuid.UUID, batchID uuid.UUID, jobID uuid.UUID, tags []string) []api.Event {
+	var events []api.Event
+	var pageToken *string = nil
+	for {
+		params := &api.ListEventsForJobParams{
+			PageSize:  Ptr(100),
+			PageToken: pageToken,
+		}
+		if len(tags) > 0 {
+			params.EventTags = &tags
+		}
+		response, err := Client.ListEventsForJobWithResponse(context.Background(), projectID, batchID, jobID, params)
+		if err != nil {
+			log.Fatal("unable to list events:", err)
+		}
+		ValidateResponse(http.StatusOK, "unable to list events", response.HTTPResponse, response.Body)
+		if response.JSON200 == nil {
+			log.Fatal("empty response")
+		}
+		if response.JSON200.Events != nil {
+			events = append(events, *response.JSON200.Events...)
+		}
+		if response.JSON200.NextPageToken != nil && *response.JSON200.NextPageToken != "" {
+			pageToken = response.JSON200.NextPageToken
+		} else {
+			break
+		}
+	}
+	return events
+}
+
+// selectEventTests returns the tests to read events from: the one with rawTestID, or
+// every test in the batch if rawTestID is empty.
+func selectEventTests(jobs []api.Job, rawTestID string) []api.Job {
+	if rawTestID == "" {
+		return jobs
+	}
+	testID, err := uuid.Parse(rawTestID)
+	if err != nil {
+		log.Fatal("unable to parse test ID: ", err)
+	}
+	for _, job := range jobs {
+		if job.JobID != nil && *job.JobID == testID {
+			return []api.Job{job}
+		}
+	}
+	log.Fatalf("test %s not found in the batch", testID)
+	return nil
+}
+
+// collectTestEvents calls emit with every matching event of the given tests, in test
+// order, and returns how many were emitted.
+func collectTestEvents(projectID uuid.UUID, batchID uuid.UUID, jobs []api.Job, filter testEventFilter, emit func(testEventRecord)) int {
+	count := 0
+	for _, job := range jobs {
+		if job.JobID == nil {
+			continue
+		}
+		name := ""
+		if job.ExperienceName != nil {
+			name = *job.ExperienceName
+		}
+		for _, event := range listEventsForTest(projectID, batchID, *job.JobID, filter.Tags) {
+			if !filter.matches(event) {
+				continue
+			}
+			emit(testEventRecord{BatchID: batchID, TestID: *job.JobID, ExperienceName: name, Event: event})
+			count++
+		}
+	}
+	return count
+}
+
+// formatEventTimestamp renders ABSOLUTE timestamps as RFC 3339 and RELATIVE ones as an
+// offset from the start of the test.
+func formatEventTimestamp(event api.Event) string {
+	if event.TimestampType == api.RELATIVE {
+		return "+" + event.Timestamp.Sub(relativeEventEpoch).String()
+	}
+	return event.Timestamp.UTC().Format(time.RFC3339)
+}
+
+func formatEventTable(w io.Writer, records []testEventRecord) {
+	if len(records) == 0 {
+		fmt.Fprintln(w, "no events")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "EXPERIENCE\tEVENT ID\tNAME\tSTATUS\tTIMESTAMP\tTAGS")
+	for _, record := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.ExperienceName,
+			record.EventID,
+			truncate(record.Name, eventNameWidth),
+			record.Status,
+			formatEventTimestamp(record.Event),
+			strings.Join(record.Tags, ","),
+		)
+	}
+	tw.Flush()
+}
+
+func writeEventJSONLines(w io.Writer, record testEventRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.Fatal("unable to encode event:", err)
+	}
+	fmt.Fprintf(w, "%s\n", line)
+}
+
+func listEvents(ccmd *cobra.Command, args []string) {
+	projectID := getProjectID(Client, viper.GetString(eventProjectKey))
+	output := viper.GetString(eventOutputKey)
+	if output != batchOutputTable && output != batchOutputJSON {
+		log.Fatalf("Unsupported output: %s. Valid outputs are: %s, %s", output, batchOutputTable, batchOutputJSON)
+	}
+	filter := getEventFilterFlags()
+	batch := actualGetBatch(projectID, viper.GetString(eventBatchIDKey), viper.GetString(eventBatchNameKey))
+	jobs := selectEventTests(getAllJobs(projectID, *batch.BatchID), viper.GetString(eventTestIDKey))
+
+	records := []testEventRecord{}
+	collectTestEvents(projectID, *batch.BatchID, jobs, filter, func(record testEventRecord) {
+		records = append(records, record)
+	})
+	if output == batchOutputJSON {
+		OutputJson(records)
+	} else {
+		formatEventTable(os.Stdout, records)
+	}
+}
+
+func exportEvents(ccmd *cobra.Command, args []string) {
+	projectID := getProjectID(Client, viper.GetString(eventProjectKey))
+	filter := getEventFilterFlags()
+	batch := actualGetBatch(projectID, viper.GetString(eventBatchIDKey), viper.GetString(eventBatchNameKey))
+	jobs := selectEventTests(getAllJobs(projectID, *batch.BatchID), viper.GetString(eventTestIDKey))
+
+	count := collectTestEvents(projectID, *batch.BatchID, jobs, filter, func(record testEventRecord) {
+		writeEventJSONLines(os.Stdout, record)
+	})
+	infoLog("Exported %s\n", pluralize(count, "event"))
+}
+
+// eventLinkedMetrics returns the event metrics of a test that the event links to, in
+// the order the event lists them.
+func eventLinkedMetrics(projectID uuid.UUID, batchID uuid.UUID, jobID uuid.UUID, event api.Event) []api.JobMetric {
+	if len(event.MetricsIDs) == 0 {
+		return []api.JobMetric{}
+	}
+	byID := map[uuid.UUID]api.JobMetric{}
+	var pageToken *string = nil
+	for {
+		response, err := Client.ListEventMetricsForJobWithResponse(context.Background(), projectID, batchID, jobID, &api.ListEventMetricsForJobParams{
+			PageSize:  Ptr(100),
+			PageToken: pageToken,
+		})
+		if err != nil {
+			log.Fatal("unable to list event metrics:", err)
+		}
+		ValidateResponse(http.StatusOK, "unable to list event metrics", response.HTTPResponse, response.Body)
+		if response.JSON200 == nil {
+			log.Fatal("empty response")
+		}
+		if response.JSON200.Metrics != nil {
+			for _, metric := range *response.JSON200.Metrics {
+				if metric.MetricID != nil {
+					byID[*metric.MetricID] = metric
+				}
+			}
+		}
+		if response.JSON200.NextPageToken != nil && *response.JSON200.NextPageToken != "" {
+			pageToken = response.JSON200.NextPageToken
+		} else {
+			break
+		}
+	}
+
+	metrics := []api.JobMetric{}
+	for _, id := range event.MetricsIDs {
+		if metric, ok := byID[id]; ok {
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
+
+func stringOrDash[T ~string](value *T) string {
+	if value == nil {
+		return "-"
+	}
+	return string(*value)
+}
+
+func formatMetricValue(metric api.JobMetric) string {
+	if metric.Value == nil {
+		return "-"
+	}
+	value := strconv.FormatFloat(*metric.Value, 'g', -1, 64)
+	if metric.Unit != nil && *metric.Unit != "" {
+		value += " " + *metric.Unit
+	}
+	return value
+}
+
+func formatEventDetails(event api.Event, metrics []api.JobMetric) string {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Event ID:\t%s\n", event.EventID)
+	fmt.Fprintf(tw, "Name:\t%s\n", event.Name)
+	if event.Description != "" {
+		fmt.Fprintf(tw, "Description:\t%s\n", event.Description)
+	}
+	fmt.Fprintf(tw, "Status:\t%s\n", event.Status)
+	fmt.Fprintf(tw, "Timestamp:\t%s (%s)\n", formatEventTimestamp(event), event.TimestampType)
+	fmt.Fprintf(tw, "Tags:\t%s\n", strings.Join(event.Tags, ", "))
+	fmt.Fprintf(tw, "Created:\t%s\n", event.CreationTimestamp.UTC().Format(time.RFC3339))
+	tw.Flush()
+
+	b.WriteString("\n")
+	if len(metrics) == 0 {
+		b.WriteString("No linked metrics.\n")
+		return b.String()
+	}
+	tw = tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METRIC ID\tNAME\tTYPE\tSTATUS\tVALUE")
+	for _, metric := range metrics {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			metric.MetricID,
+			truncate(stringOrDash(metric.Name), eventNameWidth),
+			stringOrDash(metric.Type),
+			stringOrDash(metric.Status),
+			formatMetricValue(metric),
+		)
+	}
+	tw.Flush()
+	return b.String()
+}
+
+func getEvent(ccmd *cobra.Command, args []string) {
+	projectID := getProjectID(Client, viper.GetString(eventProjectKey))
+	output := viper.GetString(eventOutputKey)
+	if output != batchOutputTable && output != batchOutputJSON {
+		log.Fatalf("Unsupported output: %s. Valid outputs are: %s, %s", output, batchOutputTable, batchOutputJSON)
+	}
+	batch := actualGetBatch(projectID, viper.GetString(eventBatchIDKey), viper.GetString(eventBatchNameKey))
+	testID, err := uuid.Parse(viper.GetString(eventTestIDKey))
+	if err != nil {
+		log.Fatal("unable to parse test ID: ", err)
+	}
+	eventID, err := uuid.Parse(viper.GetString(eventIDKey))
+	if err != nil {
+		log.Fatal("unable to parse event ID: ", err)
+	}
+
+	response, err := Client.GetEventForJobWithResponse(context.Background(), projectID, *batch.BatchID, testID, eventID)
+	if err != nil {
+		log.Fatal("unable to get event:", err)
+	}
+	ValidateResponse(http.StatusOK, "unable to get event", response.HTTPResponse, response.Body)
+	if response.JSON200 == nil {
+		log.Fatal("empty response")
+	}
+	event := *response.JSON200
+	metrics := eventLinkedMetrics(projectID, *batch.BatchID, testID, event)
+
+	if output == batchOutputJSON {
+		OutputJson(struct {
+			api.Event
+			Metrics []api.JobMetric `json:"metrics"`
+		}{event, metrics})
+	} else {
+		fmt.Print(formatEventDetails(event, metrics))
+	}
+}
+
+func listEventTags(ccmd *cobra.Command, args []string) {
+	projectID := getProjectID(Client, viper.GetString(eventProjectKey))
+	batch := actualGetBatch(projectID, viper.GetString(eventBatchIDKey), viper.GetString(eventBatchNameKey))
+	testID, err := uuid.Parse(viper.GetString(eventTestIDKey))
+	if err != nil {
+		log.Fatal("unable to parse test ID: ", err)
+	}
+
+	var pageToken *string = nil
+	for {
+		response, err := Client.ListEventTagsForJobWithResponse(context.Background(), projectID, *batch.BatchID, testID, &api.ListEventTagsForJobParams{
+			PageSize:  Ptr(100),
+			PageToken: pageToken,
+		})
+		if err != nil {
+			log.Fatal("unable to list event tags:", err)
+		}
+		ValidateResponse(http.StatusOK, "unable to list event tags", response.HTTPResponse, response.Body)
+		if response.JSON200 == nil {
+			log.Fatal("empty response")
+		}
+		if response.JSON200.EventTags != nil {
+			for _, tag := range *response.JSON200.EventTags {
+				fmt.Println(tag)
+			}
+		}
+		if response.JSON200.NextPageToken != nil && *response.JSON200.NextPageToken != "" {
+			pageToken = response.JSON200.NextPageToken
+		} else {
+			break
+		}
+	}
+}
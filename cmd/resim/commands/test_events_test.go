@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/stretchr/testify/mock"
+)
+
+func relativeEvent(name string, offset time.Duration, status api.MetricStatus, tags ...string) api.Event {
+	return api.Event{
+		EventID:       uuid.New(),
+		Name:          name,
+		Status:        status,
+		Tags:          tags,
+		Timestamp:     relativeEventEpoch.Add(offset),
+		TimestampType: api.RELATIVE,
+	}
+}
+
+func (s *CommandsSuite) TestParseEventTimeBound() {
+	bound, err := parseEventTimeBound("")
+	s.NoError(err)
+	s.Nil(bound)
+
+	bound, err = parseEventTimeBound("2026-01-02T15:04:05Z")
+	s.NoError(err)
+	s.False(bound.relative)
+	s.Equal(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC), bound.at)
+
+	bound, err = parseEventTimeBound("90s")
+	s.NoError(err)
+	s.True(bound.relative)
+	s.Equal(90*time.Second, bound.offset)
+
+	_, err = parseEventTimeBound("later")
+	s.ErrorContains(err, `invalid time "later"`)
+}
+
+func (s *CommandsSuite) TestParseEventStatuses() {
+	statuses, err := parseEventStatuses("fail_block, PASSED")
+	s.NoError(err)
+	s.Equal([]api.MetricStatus{api.FAILBLOCK, api.PASSED}, statuses)
+
+	_, err = parseEventStatuses("FAILED")
+	s.ErrorContains(err, "invalid event status: FAILED")
+}
+
+func (s *CommandsSuite) TestTestEventFilterMatches() {
+	event := relativeEvent("hard-brake", 30*time.Second, api.FAILBLOCK, "safety", "braking")
+
+	s.True(testEventFilter{}.matches(event))
+	s.True(testEventFilter{Tags: []string{"safety", "braking"}}.matches(event))
+	s.False(testEventFilter{Tags: []string{"safety", "comfort"}}.matches(event))
+	s.True(testEventFilter{Statuses: []api.MetricStatus{api.FAILWARN, api.FAILBLOCK}}.matches(event))
+	s.False(testEventFilter{Statuses: []api.MetricStatus{api.PASSED}}.matches(event))
+
+	// --after is inclusive and --before exclusive.
+	s.True(testEventFilter{
+		After:  &eventTimeBound{offset: 30 * time.Second, relative: true},
+		Before: &eventTimeBound{offset: time.Minute, relative: true},
+	}.matches(event))
+	s.False(testEventFilter{Before: &eventTimeBound{offset: 30 * time.Second, relative: true}}.matches(event))
+	s.False(testEventFilter{After: &eventTimeBound{offset: 31 * time.Second, relative: true}}.matches(event))
+
+	// An absolute bound excludes relative events, and vice versa.
+	s.False(testEventFilter{After: &eventTimeBound{at: time.Unix(0, 0)}}.matches(event))
+	absolute := api.Event{Timestamp: time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC), TimestampType: api.ABSOLUTE}
+	s.True(testEventFilter{After: &eventTimeBound{at: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)}}.matches(absolute))
+	s.False(testEventFilter{After: &eventTimeBound{offset: time.Second, relative: true}}.matches(absolute))
+}
+
+func (s *CommandsSuite) TestCollectTestEvents() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	laneChange := api.Job{JobID: Ptr(uuid.New()), ExperienceName: Ptr("lane-change")}
+	merge := api.Job{JobID: Ptr(uuid.New()), ExperienceName: Ptr("merge")}
+
+	s.mockClient.On("ListEventsForJobWithResponse", matchContext, projectID, batchID, *laneChange.JobID, mock.MatchedBy(func(p *api.ListEventsForJobParams) bool {
+		return p.EventTags != nil && len(*p.EventTags) == 1 && (*p.EventTags)[0] == "safety" && p.PageToken == nil
+	})).Return(&api.ListEventsForJobResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.ListJobEventsOutput{
+			Events:        &[]api.Event{relativeEvent("hard-brake", time.Second, api.FAILBLOCK, "safety")},
+			NextPageToken: Ptr("page-2"),
+		},
+	}, nil).Once()
+	s.mockClient.On("ListEventsForJobWithResponse", matchContext, projectID, batchID, *laneChange.JobID, mock.MatchedBy(func(p *api.ListEventsForJobParams) bool {
+		return p.PageToken != nil && *p.PageToken == "page-2"
+	})).Return(&api.ListEventsForJobResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListJobEventsOutput{Events: &[]api.Event{relativeEvent("near-miss", 2*time.Second, api.PASSED, "safety")}},
+	}, nil).Once()
+	s.mockClient.On("ListEventsForJobWithResponse", matchContext, projectID, batchID, *merge.JobID, mock.Anything).Return(&api.ListEventsForJobResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListJobEventsOutput{Events: &[]api.Event{relativeEvent("cut-in", 3*time.Second, api.FAILBLOCK, "safety")}},
+	}, nil).Once()
+
+	var out bytes.Buffer
+	filter := testEventFilter{Tags: []string{"safety"}, Statuses: []api.MetricStatus{api.FAILBLOCK}}
+	count := collectTestEvents(projectID, batchID, []api.Job{laneChange, merge}, filter, func(record testEventRecord) {
+		writeEventJSONLines(&out, record)
+	})
+	s.Equal(2, count)
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	s.Require().Len(lines, 2)
+	var record map[string]any
+	s.Require().NoError(json.Unmarshal(lines[1], &record))
+	s.Equal("cut-in", record["name"])
+	s.Equal("merge", record["experienceName"])
+	s.Equal(merge.JobID.String(), record["testID"])
+	s.Equal(batchID.String(), record["batchID"])
+	s.Equal("FAIL_BLOCK", record["status"])
+}
+
+func (s *CommandsSuite) TestFormatEventTable() {
+	var out bytes.Buffer
+	formatEventTable(&out, []testEventRecord{{
+		ExperienceName: "lane-change",
+		Event:          relativeEvent("hard-brake", 90*time.Second, api.FAILBLOCK, "safety", "braking"),
+	}})
+	s.Contains(out.String(), "EXPERIENCE")
+	row := lineWith(out.String(), "hard-brake")
+	s.Contains(row, "lane-change")
+	s.Contains(row, "FAIL_BLOCK")
+	s.Contains(row, "+1m30s")
+	s.Contains(row, "safety,braking")
+
+	out.Reset()
+	formatEventTable(&out, nil)
+	s.Equal("no events\n", out.String())
+}
+
+func (s *CommandsSuite) TestEventLinkedMetrics() {
+	projectID := uuid.New()
+	batchID := uuid.New()
+	jobID := uuid.New()
+	first, second, unrelated := uuid.New(), uuid.New(), uuid.New()
+	event := relativeEvent("hard-brake", time.Second, api.FAILBLOCK)
+	event.MetricsIDs = []uuid.UUID{second, first}
+
+	s.mockClient.On("ListEventMetricsForJobWithResponse", matchContext, projectID, batchID, jobID, mock.Anything).Return(&api.ListEventMetricsForJobResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.ListJobMetricsOutput{Metrics: &[]api.JobMetric{
+			{MetricID: Ptr(first), Name: Ptr("deceleration"), Value: Ptr(7.5), Unit: Ptr("m/s²")},
+			{MetricID: Ptr(unrelated), Name: Ptr("comfort")},
+			{MetricID: Ptr(second), Name: Ptr("time-to-collision"), Status: Ptr(api.FAILBLOCK)},
+		}},
+	}, nil).Once()
+
+	metrics := eventLinkedMetrics(projectID, batchID, jobID, event)
+	s.Require().Len(metrics, 2)
+	s.Equal("time-to-collision", *metrics[0].Name)
+	s.Equal("deceleration", *metrics[1].Name)
+
+	details := formatEventDetails(event, metrics)
+	s.Contains(lineWith(details, "Name:"), "hard-brake")
+	s.Contains(lineWith(details, "Timestamp:"), "+1s (RELATIVE)")
+	s.Contains(lineWith(details, "deceleration"), "7.5 m/s²")
+	s.Contains(lineWith(details, "time-to-collision"), "FAIL_BLOCK")
+
+	// Events without metrics do not query the API.
+	s.Empty(eventLinkedMetrics(projectID, batchID, jobID, relativeEvent("quiet", 0, api.PASSED)))
+	s.mockClient.AssertNumberOfCalls(s.T(), "ListEventMetricsForJobWithResponse", 1)
+}
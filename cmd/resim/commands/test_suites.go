@@ -106,6 +106,7 @@ const (
 	testSuitesMetricsConfigPathKey      = "metrics-config-path"
 	testSuitesMetricsTemplatesPathKey   = "metrics-templates-path"
 	testSuiteBlueprintKey               = "blueprint"
+	testSuiteBuildsKey                  = "builds"
 )
 
 func init() {
@@ -202,7 +203,17 @@ func init() {
 	runTestSuiteCmd.Flags().String(testSuiteRevisionKey, "", "The specific revision of a test suite to run.")
 	// Build ID
 	runTestSuiteCmd.Flags().String(testSuiteBuildIDKey, "", "The ID of the build to use in this test suite run.")
-	runTestSuiteCmd.MarkFlagRequired(testSuiteBuildIDKey)
+	runTestSuiteCmd.Flags().String(testSuiteBuildsKey, "", "A comma-separated list of build IDs to run the test suite against, one batch per build. The batches are supervised together and a matrix of experience status per build is printed.")
+	runTestSuiteCmd.MarkFlagsOneRequired(testSuiteBuildIDKey, testSuiteBuildsKey)
+	runTestSuiteCmd.MarkFlagsMutuallyExclusive(testSuiteBuildIDKey, testSuiteBuildsKey)
+	runTestSuiteCmd.Flags().Int(batchMaxRerunAttemptsKey, 0, "With --builds, the maximum number of rerun attempts for failed tests in each batch")
+	runTestSuiteCmd.Flags().Float64(batchRerunMaxFailurePercentKey, 50, "With --builds, the maximum percentage of failed jobs in a batch before stopping reruns (1-100)")
+	runTestSuiteCmd.Flags().String(batchRerunOnStatesKey, "", "With --builds, states to trigger rerun on (e.g. Warning, Error, Blocker)")
+	runTestSuiteCmd.Flags().String(batchFailOnStatesKey, "", "(Optional) With --builds, comma-separated list of conflated states that should fail the command (WARNING, ERROR, BLOCKER). When unset, --rerun-on-states is used as the implicit fail filter.")
+	runTestSuiteCmd.Flags().String(batchWaitTimeoutKey, "1h", "With --builds, amount of time to wait for each batch to finish, expressed in Golang duration string.")
+	runTestSuiteCmd.Flags().String(batchWaitPollKey, "30s", "With --builds, interval between checking batch status, expressed in Golang duration string.")
+	runTestSuiteCmd.Flags().Float64(batchRequestsPerSecondKey, 10, "With --builds, the maximum number of API requests per second shared by the batches being supervised. 0 removes the limit.")
+	addRerunPolicyFlags(runTestSuiteCmd)
 	// Parameters
 	runTestSuiteCmd.Flags().StringSlice(testSuiteParameterKey, []string{}, "(Optional) Parameter overrides to pass to the build. Format: <parameter-name>=<parameter-value> or <parameter-name>:<parameter-value>. The equals sign (=) is recommended, especially if parameter names contain colons. Accepts repeated parameters or comma-separated parameters e.g. 'param1=value1,param2=value2'. If multiple = signs are used, the first one will be used to determine the key, and the rest will be part of the value.")
 	// Pool Labels
@@ -595,10 +606,7 @@ func runTestSuite(ccmd *cobra.Command, args []string) {
 		revision = Ptr(viper.GetInt32(testSuiteRevisionKey))
 	}
 	testSuite := actualGetTestSuite(projectID, viper.GetString(testSuiteKey), revision, false)
-	buildID, err := uuid.Parse(viper.GetString(testSuiteBuildIDKey))
-	if err != nil {
-		log.Fatal("failed to parse build ID: ", err)
-	}
+	buildIDs := getTestSuiteRunBuildIDs()
 
 	// Parse --parameter (if any provided)
 	parameters := api.BatchParameters{}
@@ -618,15 +626,17 @@ func runTestSuite(ccmd *cobra.Command, args []string) {
 	if viper.IsSet(testSuiteMetricsSetOverrideKey) {
 		effectiveMetricsSetName = NormalizeMetricsSetName(Ptr(viper.GetString(testSuiteMetricsSetOverrideKey)))
 		if HasMetricsSetName(effectiveMetricsSetName) {
-			build, err := Client.GetBuildWithResponse(context.Background(), projectID, buildID)
-			if err != nil {
-				log.Fatal("unable to retrieve build:", err)
-			}
-			if build.JSON200 == nil || build.JSON200.BranchID == uuid.Nil {
-				log.Fatal("build has no branch associated with it")
-			}
-			if err := validateMetricsSetExists(build.JSON200.BranchID, effectiveMetricsSetName); err != nil {
-				log.Fatal(err)
+			for _, buildID := range buildIDs {
+				build, err := Client.GetBuildWithResponse(context.Background(), projectID, buildID)
+				if err != nil {
+					log.Fatal("unable to retrieve build:", err)
+				}
+				if build.JSON200 == nil || build.JSON200.BranchID == uuid.Nil {
+					log.Fatal("build has no branch associated with it")
+				}
+				if err := validateMetricsSetExists(build.JSON200.BranchID, effectiveMetricsSetName); err != nil {
+					log.Fatal(err)
+				}
 			}
 		}
 	}
@@ -638,23 +648,42 @@ func runTestSuite(ccmd *cobra.Command, args []string) {
 		associatedAccount = viper.GetString(testSuiteAccountKey)
 	}
 
-	// Sync metrics2.0 config
+	// Sync metrics2.0 config, once per branch of the builds
 	if viper.GetBool(testSuiteSyncMetricsConfigKey) {
-		build, err := Client.GetBuildWithResponse(context.Background(), projectID, buildID)
-		if err != nil {
-			log.Fatal("unable to retrieve build:", err)
-		}
-		branchID := build.JSON200.BranchID
-		if branchID == uuid.Nil {
-			log.Fatal("build has no branch associated with it")
-		}
-		metricsConfigPaths := viper.GetStringSlice(testSuitesMetricsConfigPathKey)
-		metricsTemplatesPath := viper.GetString(testSuitesMetricsTemplatesPathKey)
-		if err := SyncMetricsConfig(projectID, branchID, metricsConfigPaths, metricsTemplatesPath, false); err != nil {
-			log.Fatalf("failed to sync metrics before batch: %v", err)
+		syncedBranches := map[uuid.UUID]bool{}
+		for _, buildID := range buildIDs {
+			build, err := Client.GetBuildWithResponse(context.Background(), projectID, buildID)
+			if err != nil {
+				log.Fatal("unable to retrieve build:", err)
+			}
+			branchID := build.JSON200.BranchID
+			if branchID == uuid.Nil {
+				log.Fatal("build has no branch associated with it")
+			}
+			if syncedBranches[branchID] {
+				continue
+			}
+			syncedBranches[branchID] = true
+			metricsConfigPaths := viper.GetStringSlice(testSuitesMetricsConfigPathKey)
+			metricsTemplatesPath := viper.GetString(testSuitesMetricsTemplatesPathKey)
+			if err := SyncMetricsConfig(projectID, branchID, metricsConfigPaths, metricsTemplatesPath, false); err != nil {
+				log.Fatalf("failed to sync metrics before batch: %v", err)
+			}
 		}
 	}
 
+	if !viper.IsSet(testSuiteBuildsKey) {
+		batch := createTestSuiteBatch(projectID, testSuite, buildIDs[0], parameters, poolLabels, associatedAccount, effectiveMetricsSetName)
+		reportTestSuiteBatch(batch, testSuiteGithub)
+		return
+	}
+	runTestSuiteMatrix(projectID, testSuite, buildIDs, parameters, poolLabels, associatedAccount, effectiveMetricsSetName, testSuiteGithub)
+}
+
+// createTestSuiteBatch runs the test suite against a single build. With a
+// metrics build or metrics set override, it runs the suite's experiences as an
+// adhoc batch instead.
+func createTestSuiteBatch(projectID uuid.UUID, testSuite *api.TestSuite, buildID uuid.UUID, parameters api.BatchParameters, poolLabels []api.PoolLabel, associatedAccount string, effectiveMetricsSetName *string) api.Batch {
 	var batch api.Batch
 	// If the user supplies an override, we run an adhoc batch:
 	if viper.IsSet(testSuiteMetricsBuildOverrideKey) || viper.IsSet(testSuiteMetricsSetOverrideKey) {
@@ -762,7 +791,11 @@ func runTestSuite(ccmd *cobra.Command, args []string) {
 		}
 		batch = *response.JSON201
 	}
+	return batch
+}
 
+// reportTestSuiteBatch prints a batch created by `test-suites run`.
+func reportTestSuiteBatch(batch api.Batch, testSuiteGithub bool) {
 	if !testSuiteGithub {
 		// Report the results back to the user
 		fmt.Println("Created batch for test suite successfully!")
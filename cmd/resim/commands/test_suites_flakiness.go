@@ -0,0 +1,261 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/cmd/resim/commands/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var flakinessTestSuiteCmd = &cobra.Command{
+	Use:   "flakiness",
+	Short: "flakiness - Ranks the experiences of a test suite by how flaky they are in recent batches",
+	Long: `Looks at the most recent finished batches of a test suite and ranks its experiences by two signals:
+
+  flip rate         how often a test's pass/fail result changed between two runs of the same build
+  rerun pass rate   how often a test only passed after being rerun, e.g. by batches supervise
+
+PASSED and WARNING tests count as passing, BLOCKER and ERROR tests as failing; other tests are ignored.
+An experience's score is the higher of the two rates. Use --tag to add an experience tag, such as
+flaky, to every experience in the report.`,
+	Run: testSuiteFlakiness,
+}
+
+const (
+	testSuiteBranchKey  = "branch"
+	testSuiteLastKey    = "last"
+	testSuiteOutputKey  = "output"
+	testSuiteTagKey     = "tag"
+	testSuiteMinRateKey = "min-rate"
+)
+
+func init() {
+	flakinessTestSuiteCmd.Flags().String(testSuiteProjectKey, "", "The name or ID of the project the test suite belongs to")
+	flakinessTestSuiteCmd.MarkFlagRequired(testSuiteProjectKey)
+	flakinessTestSuiteCmd.Flags().String(testSuiteKey, "", "The name or ID of the test suite to analyze")
+	flakinessTestSuiteCmd.MarkFlagRequired(testSuiteKey)
+	flakinessTestSuiteCmd.Flags().String(testSuiteBranchKey, "", "(Optional) Only analyze batches on this branch (name or ID)")
+	flakinessTestSuiteCmd.Flags().Int(testSuiteLastKey, 50, "The number of most recent finished batches to analyze")
+	flakinessTestSuiteCmd.Flags().Float64(testSuiteMinRateKey, 0, "Only report experiences whose score is at least this rate (0-1)")
+	flakinessTestSuiteCmd.Flags().String(testSuiteOutputKey, batchOutputTable, "Output format: table or json")
+	flakinessTestSuiteCmd.Flags().String(testSuiteTagKey, "", "(Optional) The name of an experience tag to add to the reported experiences. The tag is created if it does not exist.")
+	flakinessTestSuiteCmd.Flags().SetNormalizeFunc(aliasProjectNameFunc)
+	testSuiteCmd.AddCommand(flakinessTestSuiteCmd)
+}
+
+// experienceFlakiness is the flakiness of one experience across the analyzed
+// batches.
+type experienceFlakiness struct {
+	ExperienceID uuid.UUID `json:"experienceID"`
+	Name         string    `json:"name"`
+	Runs         int       `json:"runs"`
+	Failures     int       `json:"failures"`
+	// SameBuildPairs counts consecutive runs of the same build; Flips those whose
+	// results differ.
+	SameBuildPairs int     `json:"sameBuildPairs"`
+	Flips          int     `json:"flips"`
+	FlipRate       float64 `json:"flipRate"`
+	// RerunPasses counts runs that passed after the test was rerun.
+	Reruns        int     `json:"reruns"`
+	RerunPasses   int     `json:"rerunPasses"`
+	RerunPassRate float64 `json:"rerunPassRate"`
+	Score         float64 `json:"score"`
+}
+
+type flakinessReport struct {
+	TestSuiteID uuid.UUID             `json:"testSuiteID"`
+	BranchID    *uuid.UUID            `json:"branchID,omitempty"`
+	Batches     int                   `json:"batches"`
+	Experiences []experienceFlakiness `json:"experiences"`
+}
+
+func testSuiteFlakiness(ccmd *cobra.Command, args []string) {
+	projectID := getProjectID(Client, viper.GetString(testSuiteProjectKey))
+	output := viper.GetString(testSuiteOutputKey)
+	if output != batchOutputTable && output != batchOutputJSON {
+		log.Fatalf("Unsupported output: %s. Valid outputs are: %s, %s", output, batchOutputTable, batchOutputJSON)
+	}
+	last := viper.GetInt(testSuiteLastKey)
+	if last <= 0 {
+		log.Fatalf("--%s must be at least 1", testSuiteLastKey)
+	}
+	testSuite := actualGetTestSuite(projectID, viper.GetString(testSuiteKey), nil, false)
+
+	report := flakinessReport{TestSuiteID: testSuite.TestSuiteID}
+	filter := batchListFilter{
+		TestSuiteID: testSuite.TestSuiteID,
+		Statuses:    []api.BatchStatus{api.BatchStatusSUCCEEDED, api.BatchStatusERROR},
+	}
+	if viper.IsSet(testSuiteBranchKey) {
+		branchID := getBranchID(Client, projectID, viper.GetString(testSuiteBranchKey), true)
+		filter.BranchID = branchID
+		report.BranchID = &branchID
+	}
+
+	var batches []api.Batch
+	streamBatches(projectID, filter, last, func(batch api.Batch) {
+		batches = append(batches, batch)
+	})
+	jobsByBatch := map[uuid.UUID][]api.Job{}
+	for _, batch := range batches {
+		jobsByBatch[*batch.BatchID] = getAllJobs(projectID, *batch.BatchID)
+	}
+	report.Batches = len(batches)
+
+	minRate := viper.GetFloat64(testSuiteMinRateKey)
+	for _, experience := range computeFlakiness(batches, jobsByBatch) {
+		if experience.Score >= minRate {
+			report.Experiences = append(report.Experiences, experience)
+		}
+	}
+
+	if output == batchOutputJSON {
+		OutputJson(report)
+	} else {
+		fmt.Print(formatFlakinessReport(report, testSuite.Name))
+	}
+
+	if viper.IsSet(testSuiteTagKey) && len(report.Experiences) > 0 {
+		tagName := viper.GetString(testSuiteTagKey)
+		tagFlakyExperiences(projectID, tagName, report.Experiences)
+		if output == batchOutputTable {
+			fmt.Printf("\nTagged %s with %s\n", pluralize(len(report.Experiences), "experience"), tagName)
+		}
+	}
+}
+
+// computeFlakiness ranks the experiences of the given batches, most recent
+// first, by flakiness. Experiences that never flipped or passed on a rerun are
+// left out.
+func computeFlakiness(batches []api.Batch, jobsByBatch map[uuid.UUID][]api.Job) []experienceFlakiness {
+	type experienceState struct {
+		flakiness experienceFlakiness
+		// lastPassed is the most recent result of the experience per build.
+		lastPassed map[uuid.UUID]bool
+	}
+	states := map[uuid.UUID]*experienceState{}
+
+	for i := len(batches) - 1; i >= 0; i-- {
+		batch := batches[i]
+		var buildID uuid.UUID
+		if batch.BuildID != nil {
+			buildID = *batch.BuildID
+		}
+		for _, job := range jobsByBatch[*batch.BatchID] {
+			if job.ExperienceID == nil || job.ConflatedStatus == nil {
+				continue
+			}
+			var passed bool
+			switch *job.ConflatedStatus {
+			case api.ConflatedJobStatusPASSED, api.ConflatedJobStatusWARNING:
+				passed = true
+			case api.ConflatedJobStatusBLOCKER, api.ConflatedJobStatusERROR:
+				passed = false
+			default:
+				continue
+			}
+
+			state, ok := states[*job.ExperienceID]
+			if !ok {
+				state = &experienceState{
+					flakiness:  experienceFlakiness{ExperienceID: *job.ExperienceID, Name: job.ExperienceID.String()},
+					lastPassed: map[uuid.UUID]bool{},
+				}
+				states[*job.ExperienceID] = state
+			}
+			f := &state.flakiness
+			if job.ExperienceName != nil {
+				f.Name = *job.ExperienceName
+			}
+			f.Runs++
+			if !passed {
+				f.Failures++
+			}
+			if previous, ok := state.lastPassed[buildID]; ok {
+				f.SameBuildPairs++
+				if previous != passed {
+					f.Flips++
+				}
+			}
+			state.lastPassed[buildID] = passed
+			if job.RunCounter != nil && *job.RunCounter > 1 {
+				f.Reruns++
+				if passed {
+					f.RerunPasses++
+				}
+			}
+		}
+	}
+
+	var ranked []experienceFlakiness
+	for _, state := range states {
+		f := state.flakiness
+		if f.SameBuildPairs > 0 {
+			f.FlipRate = float64(f.Flips) / float64(f.SameBuildPairs)
+		}
+		f.RerunPassRate = float64(f.RerunPasses) / float64(f.Runs)
+		f.Score = max(f.FlipRate, f.RerunPassRate)
+		if f.Score > 0 {
+			ranked = append(ranked, f)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		if a, b := ranked[i].Flips+ranked[i].RerunPasses, ranked[j].Flips+ranked[j].RerunPasses; a != b {
+			return a > b
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	return ranked
+}
+
+func formatFlakinessReport(report flakinessReport, testSuiteName string) string {
+	var b strings.Builder
+	batches := fmt.Sprintf("%d batches", report.Batches)
+	if report.Batches == 1 {
+		batches = "1 batch"
+	}
+	fmt.Fprintf(&b, "Analyzed %s of test suite %s.\n", batches, testSuiteName)
+	if len(report.Experiences) == 0 {
+		b.WriteString("No flaky experiences found.\n")
+		return b.String()
+	}
+	b.WriteString("\n")
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EXPERIENCE\tSCORE\tRUNS\tFAILURES\tFLIPS\tFLIP RATE\tRERUN PASSES\tRERUN PASS RATE")
+	for _, f := range report.Experiences {
+		fmt.Fprintf(w, "%s\t%.2f\t%d\t%d\t%d/%d\t%.0f%%\t%d/%d\t%.0f%%\n",
+			f.Name, f.Score, f.Runs, f.Failures, f.Flips, f.SameBuildPairs, f.FlipRate*100, f.RerunPasses, f.Runs, f.RerunPassRate*100)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// tagFlakyExperiences adds the named tag, creating it if needed, to the
+// reported experiences.
+func tagFlakyExperiences(projectID uuid.UUID, tagName string, experiences []experienceFlakiness) {
+	tagID := getOrCreateExperienceTagID(Client, projectID, tagName)
+	experienceIDs := make([]uuid.UUID, len(experiences))
+	for i, experience := range experiences {
+		experienceIDs[i] = experience.ExperienceID
+	}
+	response, err := Client.AddTagsToExperiencesWithResponse(context.Background(), projectID, api.AddTagsToExperiencesInput{
+		ExperienceTagIDs: []api.ExperienceTagID{tagID},
+		Experiences:      &experienceIDs,
+	})
+	if err != nil {
+		log.Fatal("failed to tag experiences:", err)
+	}
+	ValidateResponse(http.StatusCreated, "failed to tag experiences", response.HTTPResponse, response.Body)
+}
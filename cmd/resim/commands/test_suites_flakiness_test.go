@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *CommandsSuite) TestComputeFlakiness() {
+	buildA, buildB := uuid.New(), uuid.New()
+	laneChange, merge, cutIn := uuid.New(), uuid.New(), uuid.New()
+	// Most recent first, as listed by the API.
+	batches := []api.Batch{
+		{BatchID: Ptr(uuid.New()), BuildID: Ptr(buildB)},
+		{BatchID: Ptr(uuid.New()), BuildID: Ptr(buildA)},
+		{BatchID: Ptr(uuid.New()), BuildID: Ptr(buildA)},
+		{BatchID: Ptr(uuid.New()), BuildID: Ptr(buildA)},
+	}
+	jobs := map[uuid.UUID][]api.Job{
+		*batches[3].BatchID: {
+			testJob("lane-change", api.ConflatedJobStatusPASSED, withExperienceID(laneChange)),
+			testJob("merge", api.ConflatedJobStatusBLOCKER, withExperienceID(merge)),
+			testJob("cut-in", api.ConflatedJobStatusPASSED, withExperienceID(cutIn)),
+		},
+		*batches[2].BatchID: {
+			testJob("lane-change", api.ConflatedJobStatusBLOCKER, withExperienceID(laneChange)),
+			testJob("merge", api.ConflatedJobStatusBLOCKER, withExperienceID(merge)),
+			testJob("cut-in", api.ConflatedJobStatusWARNING, withExperienceID(cutIn), withRunCounter(2)),
+		},
+		*batches[1].BatchID: {
+			testJob("lane-change", api.ConflatedJobStatusPASSED, withExperienceID(laneChange)),
+			testJob("merge", api.ConflatedJobStatusBLOCKER, withExperienceID(merge)),
+			testJob("cut-in", api.ConflatedJobStatusCANCELLED, withExperienceID(cutIn)),
+		},
+		// A different build: its result is not compared with build A's.
+		*batches[0].BatchID: {
+			testJob("merge", api.ConflatedJobStatusPASSED, withExperienceID(merge)),
+		},
+	}
+
+	ranked := computeFlakiness(batches, jobs)
+
+	// merge always failed on build A and never flipped, so it is not flaky.
+	s.Require().Len(ranked, 2)
+	s.Equal("lane-change", ranked[0].Name)
+	s.Equal(3, ranked[0].Runs)
+	s.Equal(2, ranked[0].Flips)
+	s.Equal(2, ranked[0].SameBuildPairs)
+	s.Equal(1.0, ranked[0].Score)
+	s.Equal("cut-in", ranked[1].Name)
+	s.Equal(2, ranked[1].Runs)
+	s.Equal(0, ranked[1].Flips)
+	s.Equal(1, ranked[1].RerunPasses)
+	s.Equal(0.5, ranked[1].RerunPassRate)
+	s.Equal(0.5, ranked[1].Score)
+}
+
+func (s *CommandsSuite) TestTestSuiteFlakinessTagsExperiences() {
+	viper.Reset()
+	projectID := uuid.New()
+	testSuiteID := uuid.New()
+	buildID := uuid.New()
+	tagID := uuid.New()
+	laneChange, merge := uuid.New(), uuid.New()
+	batches := []api.Batch{
+		{BatchID: Ptr(uuid.New()), BuildID: Ptr(buildID), TestSuiteID: Ptr(testSuiteID), Status: Ptr(api.BatchStatusSUCCEEDED)},
+		{BatchID: Ptr(uuid.New()), BuildID: Ptr(buildID), TestSuiteID: Ptr(testSuiteID), Status: Ptr(api.BatchStatusCANCELLED)},
+		{BatchID: Ptr(uuid.New()), BuildID: Ptr(buildID), TestSuiteID: Ptr(testSuiteID), Status: Ptr(api.BatchStatusERROR)},
+	}
+
+	viper.Set(testSuiteProjectKey, projectID.String())
+	viper.Set(testSuiteKey, testSuiteID.String())
+	viper.Set(testSuiteLastKey, 50)
+	viper.Set(testSuiteOutputKey, batchOutputTable)
+	viper.Set(testSuiteTagKey, "flaky")
+
+	s.mockClient.On("GetProjectWithResponse", matchContext, projectID).Return(&api.GetProjectResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.Project{ProjectID: projectID, Name: "test-project"},
+	}, nil)
+	s.mockClient.On("GetTestSuiteWithResponse", matchContext, projectID, testSuiteID).Return(&api.GetTestSuiteResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.TestSuite{TestSuiteID: testSuiteID, Name: "nightly"},
+	}, nil)
+	s.mockClient.On("ListBatchesForTestSuiteWithResponse", matchContext, projectID, testSuiteID, mock.Anything).Return(&api.ListBatchesForTestSuiteResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListBatchesOutput{Batches: &batches},
+	}, nil).Once()
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, *batches[0].BatchID, mock.Anything).Return(&api.ListJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.ListJobsOutput{Jobs: &[]api.Job{
+			testJob("lane-change", api.ConflatedJobStatusPASSED, withExperienceID(laneChange)),
+			testJob("merge", api.ConflatedJobStatusPASSED, withExperienceID(merge)),
+		}},
+	}, nil).Once()
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, *batches[2].BatchID, mock.Anything).Return(&api.ListJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.ListJobsOutput{Jobs: &[]api.Job{
+			testJob("lane-change", api.ConflatedJobStatusERROR, withExperienceID(laneChange)),
+			testJob("merge", api.ConflatedJobStatusPASSED, withExperienceID(merge)),
+		}},
+	}, nil).Once()
+	s.mockClient.On("ListExperienceTagsWithResponse", matchContext, projectID, mock.Anything).Return(&api.ListExperienceTagsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &api.ListExperienceTagsOutput{ExperienceTags: &[]api.ExperienceTag{{ExperienceTagID: tagID, Name: "flaky"}}},
+	}, nil).Once()
+	s.mockClient.On("AddTagsToExperiencesWithResponse", matchContext, projectID, mock.MatchedBy(func(body api.AddTagsToExperiencesInput) bool {
+		return assert.ObjectsAreEqual([]uuid.UUID{tagID}, body.ExperienceTagIDs) &&
+			assert.ObjectsAreEqual([]uuid.UUID{laneChange}, *body.Experiences)
+	})).Return(&api.AddTagsToExperiencesResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusCreated},
+	}, nil).Once()
+
+	output := captureStdout(s, func() { testSuiteFlakiness(nil, nil) })
+
+	s.Contains(output, "Analyzed 2 batches of test suite nightly.")
+	s.Equal([]string{"lane-change", "1.00", "2", "1", "1/1", "100%", "0/2", "0%"}, strings.Fields(lineWith(output, "lane-change")))
+	s.NotContains(output, "merge")
+	s.Contains(output, "Tagged 1 experience with flaky")
+}
@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	"github.com/spf13/viper"
+)
+
+// testSuiteMatrix is the status of every experience of a test suite run against
+// several builds, as printed by `test-suites run --builds`.
+type testSuiteMatrix struct {
+	Builds      []testSuiteMatrixBuild
+	Experiences []testSuiteMatrixExperience
+}
+
+type testSuiteMatrixBuild struct {
+	BuildID uuid.UUID
+	Batch   api.Batch
+	// Outcome is the final batch status, or TIMED_OUT when supervision gave up.
+	Outcome string
+}
+
+type testSuiteMatrixExperience struct {
+	Name string
+	// Statuses holds one status per build, in the order of the builds.
+	Statuses []string
+}
+
+// getTestSuiteRunBuildIDs returns the builds to run a test suite against, from
+// either --build-id or --builds.
+func getTestSuiteRunBuildIDs() []uuid.UUID {
+	if !viper.IsSet(testSuiteBuildsKey) {
+		buildID, err := uuid.Parse(viper.GetString(testSuiteBuildIDKey))
+		if err != nil {
+			log.Fatal("failed to parse build ID: ", err)
+		}
+		return []uuid.UUID{buildID}
+	}
+
+	var buildIDs []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	for _, raw := range strings.Split(viper.GetString(testSuiteBuildsKey), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		buildID, err := uuid.Parse(raw)
+		if err != nil {
+			log.Fatalf("failed to parse build ID %q: %v", raw, err)
+		}
+		if seen[buildID] {
+			log.Fatalf("build %s is listed more than once in --%s", buildID, testSuiteBuildsKey)
+		}
+		seen[buildID] = true
+		buildIDs = append(buildIDs, buildID)
+	}
+	if len(buildIDs) == 0 {
+		log.Fatalf("--%s must list at least one build ID", testSuiteBuildsKey)
+	}
+	return buildIDs
+}
+
+// getTestSuiteSuperviseParams reads the supervise flags of `test-suites run`.
+// The batches are supervised in parallel, so their requests share a limiter.
+func getTestSuiteSuperviseParams(projectID uuid.UUID) *SuperviseParams {
+	params, err := parseSuperviseFlags(projectID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	params.Limiter = newRequestLimiter(viper.GetFloat64(batchRequestsPerSecondKey))
+	return params
+}
+
+// runTestSuiteMatrix creates one batch per build, supervises the batches
+// concurrently, prints the experience by build matrix and exits with the
+// combined status of all batches.
+func runTestSuiteMatrix(projectID uuid.UUID, testSuite *api.TestSuite, buildIDs []uuid.UUID, parameters api.BatchParameters, poolLabels []api.PoolLabel, associatedAccount string, effectiveMetricsSetName *string, testSuiteGithub bool) {
+	params := getTestSuiteSuperviseParams(projectID)
+
+	batches := createTestSuiteMatrixBatches(projectID, testSuite, buildIDs, parameters, poolLabels, associatedAccount, effectiveMetricsSetName)
+	batchIDs := make([]uuid.UUID, len(batches))
+	for i, batch := range batches {
+		batchIDs[i] = *batch.BatchID
+	}
+	if testSuiteGithub {
+		ids := make([]string, len(batchIDs))
+		for i, batchID := range batchIDs {
+			ids[i] = batchID.String()
+		}
+		fmt.Printf("batch_ids=%s\n", strings.Join(ids, ","))
+	} else {
+		fmt.Printf("Created %d batches for test suite successfully!\n", len(batches))
+		for i, batch := range batches {
+			fmt.Printf("Build %s: batch %s (%s)\n", buildIDs[i], *batch.FriendlyName, batch.BatchID)
+		}
+	}
+
+	results := superviseBatchesInParallel(batchIDs, params)
+
+	// In GitHub mode stdout is reserved for the outputs, so the matrix goes to
+	// stderr alongside the supervision logs.
+	matrix := collectTestSuiteMatrix(projectID, buildIDs, batches, results)
+	if testSuiteGithub {
+		fmt.Fprint(os.Stderr, formatTestSuiteMatrix(matrix))
+	} else {
+		fmt.Print("\n" + formatTestSuiteMatrix(matrix))
+	}
+
+	failFilter := supervisorFailFilter(batchFailOnStatesKey, batchRerunOnStatesKey)
+	exitWithBatchStatus(results, exitCodeOptions{failOnStates: failFilter}, true)
+}
+
+// createTestSuiteMatrixBatches creates a batch of the test suite for each
+// build, in order.
+func createTestSuiteMatrixBatches(projectID uuid.UUID, testSuite *api.TestSuite, buildIDs []uuid.UUID, parameters api.BatchParameters, poolLabels []api.PoolLabel, associatedAccount string, effectiveMetricsSetName *string) []api.Batch {
+	batches := make([]api.Batch, len(buildIDs))
+	for i, buildID := range buildIDs {
+		batch := createTestSuiteBatch(projectID, testSuite, buildID, parameters, poolLabels, associatedAccount, effectiveMetricsSetName)
+		if batch.BatchID == nil {
+			log.Fatal("empty ID")
+		}
+		if batch.FriendlyName == nil {
+			log.Fatal("empty name")
+		}
+		batches[i] = batch
+	}
+	return batches
+}
+
+// collectTestSuiteMatrix lists the jobs of each build's final batch, falling
+// back to the submitted batch when supervision did not return one.
+func collectTestSuiteMatrix(projectID uuid.UUID, buildIDs []uuid.UUID, batches []api.Batch, results []*SuperviseResult) testSuiteMatrix {
+	matrix := testSuiteMatrix{}
+	rows := map[uuid.UUID]int{}
+	for i, buildID := range buildIDs {
+		build := testSuiteMatrixBuild{BuildID: buildID, Batch: batches[i]}
+		if result := results[i]; result != nil && result.Batch != nil {
+			build.Batch = *result.Batch
+		}
+		if result := results[i]; result != nil && result.Error != nil {
+			if _, ok := result.Error.(*TimeoutError); ok {
+				build.Outcome = "TIMED_OUT"
+			} else {
+				build.Outcome = "ERROR"
+			}
+		} else if build.Batch.Status != nil {
+			build.Outcome = string(*build.Batch.Status)
+		}
+		matrix.Builds = append(matrix.Builds, build)
+
+		for _, job := range getAllJobs(projectID, *build.Batch.BatchID) {
+			if job.ExperienceID == nil {
+				continue
+			}
+			row, ok := rows[*job.ExperienceID]
+			if !ok {
+				name := job.ExperienceID.String()
+				if job.ExperienceName != nil {
+					name = *job.ExperienceName
+				}
+				row = len(matrix.Experiences)
+				rows[*job.ExperienceID] = row
+				matrix.Experiences = append(matrix.Experiences, testSuiteMatrixExperience{
+					Name:     name,
+					Statuses: make([]string, len(buildIDs)),
+				})
+			}
+			matrix.Experiences[row].Statuses[i] = testSuiteMatrixJobStatus(job)
+		}
+	}
+	sort.SliceStable(matrix.Experiences, func(i, j int) bool {
+		return matrix.Experiences[i].Name < matrix.Experiences[j].Name
+	})
+	return matrix
+}
+
+// testSuiteMatrixJobStatus prefers the conflated status, which folds metrics
+// failures into the job status.
+func testSuiteMatrixJobStatus(job api.Job) string {
+	if job.ConflatedStatus != nil {
+		return string(*job.ConflatedStatus)
+	}
+	if job.JobStatus != nil {
+		return string(*job.JobStatus)
+	}
+	return "UNKNOWN"
+}
+
+// formatTestSuiteMatrix renders the builds of a matrix run and the status of
+// each experience per build. Builds are labelled by the first eight characters
+// of their ID; experiences missing from a build's batch show as "-".
+func formatTestSuiteMatrix(matrix testSuiteMatrix) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BUILD\tBUILD ID\tBATCH\tSTATUS")
+	for _, build := range matrix.Builds {
+		name := ""
+		if build.Batch.FriendlyName != nil {
+			name = *build.Batch.FriendlyName
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", testSuiteMatrixBuildLabel(build.BuildID), build.BuildID, name, build.Outcome)
+	}
+	w.Flush()
+	b.WriteString("\n")
+
+	if len(matrix.Experiences) == 0 {
+		b.WriteString("No jobs found.\n")
+		return b.String()
+	}
+	w = tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	header := []string{"EXPERIENCE"}
+	for _, build := range matrix.Builds {
+		header = append(header, testSuiteMatrixBuildLabel(build.BuildID))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, experience := range matrix.Experiences {
+		cells := []string{experience.Name}
+		for _, status := range experience.Statuses {
+			if status == "" {
+				status = "-"
+			}
+			cells = append(cells, status)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	w.Flush()
+	return b.String()
+}
+
+func testSuiteMatrixBuildLabel(buildID uuid.UUID) string {
+	return buildID.String()[:8]
+}
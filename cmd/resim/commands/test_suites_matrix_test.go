@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resim-ai/api-client/api"
+	. "github.com/resim-ai/api-client/ptr"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *CommandsSuite) TestGetTestSuiteRunBuildIDs() {
+	viper.Reset()
+	buildA, buildB := uuid.New(), uuid.New()
+
+	viper.Set(testSuiteBuildIDKey, buildA.String())
+	s.Equal([]uuid.UUID{buildA}, getTestSuiteRunBuildIDs())
+
+	viper.Reset()
+	viper.Set(testSuiteBuildsKey, buildA.String()+", "+buildB.String()+",")
+	s.Equal([]uuid.UUID{buildA, buildB}, getTestSuiteRunBuildIDs())
+}
+
+func (s *CommandsSuite) TestGetTestSuiteSuperviseParams() {
+	viper.Reset()
+	defer viper.Reset()
+	projectID := uuid.New()
+	viper.Set(batchMaxRerunAttemptsKey, 2)
+	viper.Set(batchRerunMaxFailurePercentKey, 40.0)
+	viper.Set(batchRerunOnStatesKey, "Error")
+	viper.Set(batchWaitTimeoutKey, "2h")
+	viper.Set(batchWaitPollKey, "10s")
+	viper.Set(batchRequestsPerSecondKey, 5.0)
+	viper.Set(batchRerunDelayKey, "1m")
+	viper.Set(batchMetricsOnlyRerunsKey, true)
+
+	params := getTestSuiteSuperviseParams(projectID)
+
+	// The same flags as batch supervise, including the rerun policy.
+	s.Equal(projectID, params.ProjectID)
+	s.Equal(2, params.MaxRerunAttempts)
+	s.Equal(40.0, params.RerunMaxFailurePercent)
+	s.Equal([]api.ConflatedJobStatus{api.ConflatedJobStatusERROR}, params.UndesiredConflatedStates)
+	s.Equal(2*time.Hour, params.Timeout)
+	s.Equal(10*time.Second, params.PollInterval)
+	s.Equal(time.Minute, params.RerunPolicy.Delay)
+	s.True(params.RerunPolicy.MetricsOnly)
+	s.NotNil(params.Limiter)
+}
+
+func (s *CommandsSuite) TestCreateTestSuiteMatrixBatches() {
+	viper.Reset()
+	projectID := uuid.New()
+	testSuite := &api.TestSuite{TestSuiteID: uuid.New(), TestSuiteRevision: 2}
+	buildIDs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	viper.Set(testSuitePriorityKey, 5)
+
+	var submitted []uuid.UUID
+	s.mockClient.On("CreateBatchForTestSuiteRevisionWithResponse", matchContext, projectID, testSuite.TestSuiteID, testSuite.TestSuiteRevision, mock.Anything).Run(func(args mock.Arguments) {
+		submitted = append(submitted, args.Get(4).(api.TestSuiteBatchInput).BuildID)
+	}).Return(&api.CreateBatchForTestSuiteRevisionResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusCreated},
+		JSON201: &api.Batch{
+			BatchID:      Ptr(uuid.New()),
+			FriendlyName: Ptr("matrix-batch"),
+			Status:       Ptr(api.BatchStatusSUBMITTED),
+		},
+	}, nil)
+
+	batches := createTestSuiteMatrixBatches(projectID, testSuite, buildIDs, api.BatchParameters{}, nil, "", nil)
+
+	s.Len(batches, 3)
+	s.Equal(buildIDs, submitted)
+	s.mockClient.AssertNotCalled(s.T(), "CreateBatchWithResponse", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *CommandsSuite) TestCollectTestSuiteMatrix() {
+	projectID := uuid.New()
+	buildA, buildB := uuid.New(), uuid.New()
+	laneChange, merge, cutIn := uuid.New(), uuid.New(), uuid.New()
+	submittedA := api.Batch{BatchID: Ptr(uuid.New()), FriendlyName: Ptr("batch-a"), Status: Ptr(api.BatchStatusSUBMITTED)}
+	submittedB := api.Batch{BatchID: Ptr(uuid.New()), FriendlyName: Ptr("batch-b"), Status: Ptr(api.BatchStatusSUBMITTED)}
+	finishedA := api.Batch{BatchID: submittedA.BatchID, FriendlyName: Ptr("batch-a"), Status: Ptr(api.BatchStatusSUCCEEDED)}
+
+	job := func(experienceID uuid.UUID, name string, status api.ConflatedJobStatus) api.Job {
+		return api.Job{ExperienceID: Ptr(experienceID), ExperienceName: Ptr(name), ConflatedStatus: Ptr(status)}
+	}
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, *submittedA.BatchID, mock.Anything).Return(&api.ListJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.ListJobsOutput{Jobs: &[]api.Job{
+			job(merge, "merge", api.ConflatedJobStatusPASSED),
+			job(laneChange, "lane-change", api.ConflatedJobStatusPASSED),
+		}},
+	}, nil).Once()
+	// The second build timed out, so its submitted batch is listed instead.
+	s.mockClient.On("ListJobsWithResponse", matchContext, projectID, *submittedB.BatchID, mock.Anything).Return(&api.ListJobsResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &api.ListJobsOutput{Jobs: &[]api.Job{
+			job(laneChange, "lane-change", api.ConflatedJobStatusBLOCKER),
+			job(cutIn, "cut-in", api.ConflatedJobStatusRUNNING),
+		}},
+	}, nil).Once()
+
+	matrix := collectTestSuiteMatrix(projectID, []uuid.UUID{buildA, buildB}, []api.Batch{submittedA, submittedB}, []*SuperviseResult{
+		{Batch: &finishedA},
+		{Error: &TimeoutError{message: "timed out"}},
+	})
+
+	s.Equal([]testSuiteMatrixExperience{
+		{Name: "cut-in", Statuses: []string{"", "RUNNING"}},
+		{Name: "lane-change", Statuses: []string{"PASSED", "BLOCKER"}},
+		{Name: "merge", Statuses: []string{"PASSED", ""}},
+	}, matrix.Experiences)
+	s.Equal("SUCCEEDED", matrix.Builds[0].Outcome)
+	s.Equal("TIMED_OUT", matrix.Builds[1].Outcome)
+
+	output := formatTestSuiteMatrix(matrix)
+	labelA, labelB := buildA.String()[:8], buildB.String()[:8]
+	s.Contains(lineWith(output, buildA.String()), "batch-a")
+	s.Contains(lineWith(output, buildB.String()), "TIMED_OUT")
+	s.Equal([]string{"EXPERIENCE", labelA, labelB}, strings.Fields(lineWith(output, "EXPERIENCE")))
+	s.Equal([]string{"cut-in", "-", "RUNNING"}, strings.Fields(lineWith(output, "cut-in")))
+	s.Equal([]string{"lane-change", "PASSED", "BLOCKER"}, strings.Fields(lineWith(output, "lane-change")))
+}
@@ -99,7 +99,9 @@ Exit codes:
   7 = BLOCKER (unresolved BLOCKER jobs in the fail filter)
   8 = WARNING (unresolved WARNING jobs in the fail filter)
 
-Multi-batch priority: 1 > 6 > 7 > 2 > 8 > 5 > 3 > 4 > 0.`,
+Multi-batch priority: 1 > 6 > 7 > 2 > 8 > 5 > 3 > 4 > 0.
+
+Pass --state-file to save rerun attempts as they are submitted; running the same command again after an interruption resumes each batch from its latest rerun batch.`,
 		Run: superviseWorkflowRun,
 	}
 )
@@ -130,6 +132,8 @@ const (
 	workflowWaitPollKey                = "poll-every"
 	workflowGithubKey                  = "github"
 	workflowRunSlackOutputKey          = "slack"
+	workflowJUnitKey                   = "junit"
+	workflowStateFileKey               = "state-file"
 )
 
 func init() {
@@ -233,6 +237,8 @@ func init() {
 	superviseWorkflowRunCmd.Flags().Bool(workflowQuietKey, false, "Suppress informational log lines (conflated status summaries and per-attempt rerun breakdowns).")
 	superviseWorkflowRunCmd.Flags().String(workflowWaitTimeoutKey, "1h", "Amount of time to wait for a workflow run to finish, expressed in Golang duration string.")
 	superviseWorkflowRunCmd.Flags().String(workflowWaitPollKey, "30s", "Interval between checking workflow run status, expressed in Golang duration string.")
+	superviseWorkflowRunCmd.Flags().String(workflowJUnitKey, "", junitFlagDescription+" Each batch of the run is a testsuite.")
+	superviseWorkflowRunCmd.Flags().String(workflowStateFileKey, "", stateFileFlagDescription+" Each batch of the run is resumed separately.")
 
 	rootCmd.AddCommand(workflowCmd)
 }
@@ -951,70 +957,6 @@ func actualGetWorkflow(projectID uuid.UUID, workflowKeyRaw string, expectArchive
 	return api.Workflow{}
 }
 
-// superviseWorkflowRunBatch supervises a single batch from a workflow run
-func superviseWorkflowRunBatch(projectID uuid.UUID, batchID uuid.UUID, params *SuperviseParams) *SuperviseResult {
-	// Create a new params struct with the batch ID
-	batchParams := &SuperviseParams{
-		ProjectID:                params.ProjectID,
-		MaxRerunAttempts:         params.MaxRerunAttempts,
-		RerunMaxFailurePercent:   params.RerunMaxFailurePercent,
-		UndesiredConflatedStates: params.UndesiredConflatedStates,
-		Timeout:                  params.Timeout,
-		PollInterval:             params.PollInterval,
-		BatchID:                  batchID.String(),
-		BatchName:                "",
-	}
-
-	// Supervise the batch by calling the underlying functions directly
-	var batch *api.Batch
-	for attempt := 0; attempt <= batchParams.MaxRerunAttempts; attempt++ {
-		var err error
-		batch, err = waitForBatchCompletion(batchParams.ProjectID, batchParams.BatchID, batchParams.BatchName, batchParams.Timeout, batchParams.PollInterval)
-
-		// Check timeout
-		if err != nil {
-			if timeoutErr, ok := err.(*TimeoutError); ok {
-				return &SuperviseResult{
-					Error: timeoutErr,
-				}
-			} else {
-				return &SuperviseResult{
-					Error: fmt.Errorf("error retrieving batch: %v", err),
-				}
-			}
-		}
-
-		infoLog("Batch %s completed with status: %s\n", batchID.String(), *batch.Status)
-		logConflatedSummary(batch)
-
-		// Check if rerun is required (includes max attempts check)
-		matchingJobIDs := getMatchingJobIDs(batch, batchParams, attempt)
-		if len(matchingJobIDs) == 0 {
-			return &SuperviseResult{
-				Batch: batch,
-			}
-		}
-
-		response, err := submitBatchRerun(batchParams.ProjectID, *batch.BatchID, matchingJobIDs, 30*time.Second, false, false)
-		if err != nil {
-			return &SuperviseResult{
-				Error: err,
-			}
-		}
-		newBatchID := response.JSON200.BatchID
-		infoLog("Submitted rerun batch: %s\n", newBatchID.String())
-
-		// Update batch ID for next iteration
-		batchParams.BatchID = newBatchID.String()
-		batchParams.BatchName = "" // Clear batch name since we're using ID now
-	}
-
-	// This should never happen, but we'll return the batch if we get here
-	return &SuperviseResult{
-		Batch: batch,
-	}
-}
-
 // WorkflowSuperviseResult contains aggregated results from supervising all batches in a workflow run
 type WorkflowSuperviseResult struct {
 	Results []*SuperviseResult
@@ -1054,6 +996,14 @@ func actualSuperviseWorkflowRun(projectID uuid.UUID, workflowID uuid.UUID, runID
 		}
 	}
 
+	return &WorkflowSuperviseResult{
+		Results: superviseBatchesInParallel(batchIDs, params),
+	}
+}
+
+// superviseBatchesInParallel supervises each batch concurrently and returns the
+// results in the order of batchIDs.
+func superviseBatchesInParallel(batchIDs []uuid.UUID, params *SuperviseParams) []*SuperviseResult {
 	infoLog("Supervising %d batches in parallel...\n", len(batchIDs))
 
 	// Supervise all batches in parallel
@@ -1065,8 +1015,12 @@ func actualSuperviseWorkflowRun(projectID uuid.UUID, workflowID uuid.UUID, runID
 		wg.Add(1)
 		go func(idx int, bid uuid.UUID) {
 			defer wg.Done()
-			infoLog("Supervising batch %d/%d: %s\n", idx+1, len(batchIDs), bid.String())
-			result := superviseWorkflowRunBatch(projectID, bid, params)
+			batchParams := *params
+			batchParams.BatchID = bid.String()
+			batchParams.BatchName = ""
+			batchParams.LogPrefix = supervisedBatchLogPrefix(bid)
+			batchParams.logf("Supervising batch %d/%d: %s\n", idx+1, len(batchIDs), bid.String())
+			result := superviseBatchLoop(&batchParams)
 			resultsMutex.Lock()
 			results[idx] = result
 			resultsMutex.Unlock()
@@ -1080,9 +1034,7 @@ func actualSuperviseWorkflowRun(projectID uuid.UUID, workflowID uuid.UUID, runID
 
 	wg.Wait()
 
-	return &WorkflowSuperviseResult{
-		Results: results,
-	}
+	return results
 }
 
 func superviseWorkflowRun(ccmd *cobra.Command, args []string) {
@@ -1120,6 +1072,11 @@ func superviseWorkflowRun(ccmd *cobra.Command, args []string) {
 		log.Fatalf("failed to parse timeout: %v", err)
 	}
 
+	stateFile, err := loadSuperviseStateFile(viper.GetString(workflowStateFileKey), projectID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	params := &SuperviseParams{
 		ProjectID:                projectID,
 		MaxRerunAttempts:         maxRerunAttempts,
@@ -1129,6 +1086,7 @@ func superviseWorkflowRun(ccmd *cobra.Command, args []string) {
 		PollInterval:             pollInterval,
 		BatchID:                  "", // Not used for workflow supervise
 		BatchName:                "", // Not used for workflow supervise
+		StateFile:                stateFile,
 	}
 
 	result := actualSuperviseWorkflowRun(projectID, wf.WorkflowID, runID, params)
@@ -1141,5 +1099,6 @@ func superviseWorkflowRun(ccmd *cobra.Command, args []string) {
 	// otherwise default to --rerun-on-states as the implicit fail filter (matches
 	// `batch supervise` behavior). Shared helper keeps this in lockstep with batch supervise.
 	failFilter := supervisorFailFilter(workflowFailOnStatesKey, workflowRerunOnStatesKey)
+	writeJUnitReportForResults(viper.GetString(workflowJUnitKey), wf.Name, result.Results)
 	exitWithBatchStatus(result.Results, exitCodeOptions{failOnStates: failFilter}, true)
 }
@@ -27,6 +27,7 @@ require (
 	github.com/vektah/gqlparser/v2 v2.5.19
 	github.com/vektra/mockery/v2 v2.53.4
 	golang.org/x/oauth2 v0.25.0
+	golang.org/x/term v0.29.0
 	k8s.io/api v0.32.2
 	k8s.io/apimachinery v0.32.2
 	k8s.io/kubectl v0.32.2
@@ -89,7 +90,6 @@ require (
 	go.uber.org/multierr v1.11.0 // indirect
 	golang.org/x/exp v0.0.0-20240112132812-db7319d0e0e3 // indirect
 	golang.org/x/sync v0.11.0 // indirect
-	golang.org/x/term v0.29.0 // indirect
 	golang.org/x/time v0.8.0 // indirect
 	gopkg.in/evanphx/json-patch.v4 v4.12.0 // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect